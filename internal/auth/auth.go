@@ -26,6 +26,7 @@ var Scopes = []string{
 	"Mail.Send",
 	"Calendars.Read",
 	"Calendars.ReadWrite",
+	"MailboxSettings.ReadWrite",
 	"offline_access",
 }
 
@@ -26,6 +26,7 @@ type Event struct {
 	ResponseStatus   string    `json:"response_status,omitempty"`
 	IsOnline         bool      `json:"is_online"`
 	OnlineMeetingURL string    `json:"online_meeting_url,omitempty"`
+	Categories       []string  `json:"categories,omitempty"`
 }
 
 // ListOptions configures event listing
@@ -52,7 +53,7 @@ func ListEvents(ctx context.Context, client *msgraph.GraphServiceClient, opts Li
 			EndDateTime:   &endStr,
 			Top:           &top,
 			Orderby:       []string{"start/dateTime"},
-			Select:        []string{"id", "subject", "start", "end", "location", "isAllDay", "organizer", "attendees", "webLink", "responseStatus", "isOnlineMeeting", "onlineMeetingUrl"},
+			Select:        []string{"id", "subject", "start", "end", "location", "isAllDay", "organizer", "attendees", "webLink", "responseStatus", "isOnlineMeeting", "onlineMeetingUrl", "categories"},
 		},
 	}
 
@@ -73,7 +74,7 @@ func ListEvents(ctx context.Context, client *msgraph.GraphServiceClient, opts Li
 func GetEvent(ctx context.Context, client *msgraph.GraphServiceClient, eventID string) (*Event, error) {
 	requestConfig := &users.ItemEventsEventItemRequestBuilderGetRequestConfiguration{
 		QueryParameters: &users.ItemEventsEventItemRequestBuilderGetQueryParameters{
-			Select: []string{"id", "subject", "start", "end", "location", "isAllDay", "organizer", "attendees", "body", "webLink", "responseStatus", "isOnlineMeeting", "onlineMeetingUrl"},
+			Select: []string{"id", "subject", "start", "end", "location", "isAllDay", "organizer", "attendees", "body", "webLink", "responseStatus", "isOnlineMeeting", "onlineMeetingUrl", "categories"},
 		},
 	}
 
@@ -271,6 +272,8 @@ func convertEvent(ev models.Eventable) Event {
 		}
 	}
 
+	event.Categories = ev.GetCategories()
+
 	if resp := ev.GetResponseStatus(); resp != nil {
 		if r := resp.GetResponse(); r != nil {
 			event.ResponseStatus = r.String()
@@ -0,0 +1,144 @@
+package categories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a declared master category list, as read from categories.yaml:
+//
+//	categories:
+//	  - name: Project X
+//	    color: blue
+//	  - name: Travel
+//	    color: dark green
+type File struct {
+	Categories []FileEntry `yaml:"categories"`
+}
+
+// FileEntry is one declared category
+type FileEntry struct {
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"`
+}
+
+// Change actions
+const (
+	ActionCreate   = "create"
+	ActionSetColor = "set-color"
+	ActionDelete   = "delete"
+)
+
+// Change is one step needed to make the master list match a file
+type Change struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	// Color is the target preset; OldColor the current one for set-color
+	Color    string `json:"color,omitempty"`
+	OldColor string `json:"old_color,omitempty"`
+}
+
+// LoadFile reads and validates a categories file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read categories file: %w", err)
+	}
+
+	return parseFile(data)
+}
+
+// parseFile parses a categories file, normalizing colours to presets
+func parseFile(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse categories file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range f.Categories {
+		name := strings.TrimSpace(entry.Name)
+		if name == "" {
+			return nil, fmt.Errorf("category %d has no name", i+1)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("category %q is declared twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		preset, err := ParseColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", name, err)
+		}
+
+		f.Categories[i] = FileEntry{Name: name, Color: preset}
+	}
+
+	return &f, nil
+}
+
+// Plan lists the changes that make current match the declared file.
+// Names are matched case-insensitively. Categories missing from the file
+// are deleted unless keepExtra is set.
+func Plan(current []Category, f *File, keepExtra bool) []Change {
+	var changes []Change
+
+	declared := make(map[string]bool)
+	for _, entry := range f.Categories {
+		declared[strings.ToLower(entry.Name)] = true
+
+		existing, ok := Find(current, entry.Name)
+		if !ok {
+			changes = append(changes, Change{Action: ActionCreate, Name: entry.Name, Color: entry.Color})
+			continue
+		}
+		if existing.Color != entry.Color {
+			changes = append(changes, Change{
+				Action:   ActionSetColor,
+				Name:     existing.DisplayName,
+				ID:       existing.ID,
+				Color:    entry.Color,
+				OldColor: existing.Color,
+			})
+		}
+	}
+
+	if !keepExtra {
+		for _, c := range current {
+			if !declared[strings.ToLower(c.DisplayName)] {
+				changes = append(changes, Change{Action: ActionDelete, Name: c.DisplayName, ID: c.ID, OldColor: c.Color})
+			}
+		}
+	}
+
+	return changes
+}
+
+// Apply carries out planned changes in order, stopping at the first error.
+// It returns the number of changes applied.
+func Apply(ctx context.Context, client *msgraph.GraphServiceClient, changes []Change) (int, error) {
+	for i, c := range changes {
+		var err error
+		switch c.Action {
+		case ActionCreate:
+			_, err = Create(ctx, client, c.Name, c.Color)
+		case ActionSetColor:
+			err = SetColor(ctx, client, c.ID, c.Color)
+		case ActionDelete:
+			err = Delete(ctx, client, c.ID)
+		default:
+			err = fmt.Errorf("unknown action: %s", c.Action)
+		}
+		if err != nil {
+			return i, fmt.Errorf("%s %q: %w", c.Action, c.Name, err)
+		}
+	}
+
+	return len(changes), nil
+}
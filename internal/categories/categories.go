@@ -0,0 +1,150 @@
+package categories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// Category is an entry in the mailbox's master category list
+type Category struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"name"`
+	// Color is the Graph preset, e.g. "preset7"; "none" has no colour
+	Color string `json:"color"`
+	// ColorName is the human-readable colour, e.g. "blue"
+	ColorName string `json:"color_name"`
+}
+
+// List retrieves the master category list
+func List(ctx context.Context, client *msgraph.GraphServiceClient) ([]Category, error) {
+	result, err := client.Me().Outlook().MasterCategories().Get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	categories := make([]Category, 0)
+	for _, c := range result.GetValue() {
+		categories = append(categories, convertCategory(c))
+	}
+
+	return categories, nil
+}
+
+// Find returns the category with the given name, matched case-insensitively
+func Find(categories []Category, name string) (*Category, bool) {
+	for i := range categories {
+		if strings.EqualFold(categories[i].DisplayName, name) {
+			return &categories[i], true
+		}
+	}
+	return nil, false
+}
+
+// Create adds a category to the master list. Color may be a preset or a
+// colour name.
+func Create(ctx context.Context, client *msgraph.GraphServiceClient, name, color string) (*Category, error) {
+	preset, err := ParseColor(color)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := colorValue(preset)
+	if err != nil {
+		return nil, err
+	}
+
+	c := models.NewOutlookCategory()
+	c.SetDisplayName(&name)
+	c.SetColor(value)
+
+	created, err := client.Me().Outlook().MasterCategories().Post(ctx, c, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+
+	category := convertCategory(created)
+	return &category, nil
+}
+
+// SetColor changes the colour of a category
+func SetColor(ctx context.Context, client *msgraph.GraphServiceClient, id, color string) error {
+	preset, err := ParseColor(color)
+	if err != nil {
+		return err
+	}
+
+	value, err := colorValue(preset)
+	if err != nil {
+		return err
+	}
+
+	c := models.NewOutlookCategory()
+	c.SetColor(value)
+
+	if _, err := client.Me().Outlook().MasterCategories().ByOutlookCategoryId(id).Patch(ctx, c, nil); err != nil {
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a category from the master list. Items tagged with it
+// keep the name but lose the colour.
+func Delete(ctx context.Context, client *msgraph.GraphServiceClient, id string) error {
+	if err := client.Me().Outlook().MasterCategories().ByOutlookCategoryId(id).Delete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	return nil
+}
+
+// Rename replaces a category with one of a new name and the same colour.
+// Graph does not allow changing a category's display name, so the old
+// entry is deleted after the new one is created.
+func Rename(ctx context.Context, client *msgraph.GraphServiceClient, c Category, newName string) (*Category, error) {
+	created, err := Create(ctx, client, newName, c.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Delete(ctx, client, c.ID); err != nil {
+		return created, err
+	}
+
+	return created, nil
+}
+
+// convertCategory converts a Graph API category to our Category type
+func convertCategory(c models.OutlookCategoryable) Category {
+	category := Category{
+		ID:          safeString(c.GetId()),
+		DisplayName: safeString(c.GetDisplayName()),
+		Color:       "none",
+	}
+
+	if color := c.GetColor(); color != nil {
+		category.Color = color.String()
+	}
+	category.ColorName = ColorName(category.Color)
+
+	return category
+}
+
+// colorValue converts a preset to its Graph enum
+func colorValue(preset string) (*models.CategoryColor, error) {
+	value, err := models.ParseCategoryColor(preset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid colour: %s", preset)
+	}
+	return value.(*models.CategoryColor), nil
+}
+
+func safeString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
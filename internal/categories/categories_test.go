@@ -0,0 +1,124 @@
+package categories
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "red", want: "preset0"},
+		{in: "Blue", want: "preset7"},
+		{in: "dark blue", want: "preset22"},
+		{in: "dark-green", want: "preset19"},
+		{in: "DarkGrey", want: "preset13"},
+		{in: "preset24", want: "preset24"},
+		{in: "none", want: "none"},
+		{in: "", want: "none"},
+		{in: "preset25", wantErr: true},
+		{in: "magenta", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseColor(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseColor(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseColor(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorName(t *testing.T) {
+	if got := ColorName("preset7"); got != "blue" {
+		t.Errorf("ColorName(preset7) = %q, want blue", got)
+	}
+	if got := ColorName("none"); got != "none" {
+		t.Errorf("ColorName(none) = %q, want none", got)
+	}
+	if got := Colorize("none", "x"); got != "x" {
+		t.Errorf("Colorize(none) = %q, want unchanged", got)
+	}
+	if got := Colorize("preset0", "x"); !strings.Contains(got, "x") || got == "x" {
+		t.Errorf("Colorize(preset0) = %q, want coloured text", got)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	t.Run("normalizes colours", func(t *testing.T) {
+		f, err := parseFile([]byte("categories:\n  - name: Project X\n    color: blue\n  - name: Travel\n"))
+		if err != nil {
+			t.Fatalf("parseFile() error = %v", err)
+		}
+		want := []FileEntry{{Name: "Project X", Color: "preset7"}, {Name: "Travel", Color: "none"}}
+		if !reflect.DeepEqual(f.Categories, want) {
+			t.Errorf("Categories = %v, want %v", f.Categories, want)
+		}
+	})
+
+	errorCases := map[string]string{
+		"missing name":   "categories:\n  - color: red\n",
+		"duplicate name": "categories:\n  - name: A\n  - name: a\n",
+		"bad colour":     "categories:\n  - name: A\n    color: magenta\n",
+		"invalid yaml":   "categories: [",
+	}
+	for name, input := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseFile([]byte(input)); err == nil {
+				t.Error("parseFile() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	current := []Category{
+		{ID: "1", DisplayName: "Project X", Color: "preset0"},
+		{ID: "2", DisplayName: "Travel", Color: "preset4"},
+		{ID: "3", DisplayName: "Old", Color: "preset1"},
+	}
+	f := &File{Categories: []FileEntry{
+		{Name: "project x", Color: "preset7"},
+		{Name: "Travel", Color: "preset4"},
+		{Name: "New", Color: "none"},
+	}}
+
+	t.Run("matches file exactly", func(t *testing.T) {
+		got := Plan(current, f, false)
+		want := []Change{
+			{Action: ActionSetColor, Name: "Project X", ID: "1", Color: "preset7", OldColor: "preset0"},
+			{Action: ActionCreate, Name: "New", Color: "none"},
+			{Action: ActionDelete, Name: "Old", ID: "3", OldColor: "preset1"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Plan() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("keeps extra categories", func(t *testing.T) {
+		for _, c := range Plan(current, f, true) {
+			if c.Action == ActionDelete {
+				t.Errorf("Plan(keepExtra) deletes %q", c.Name)
+			}
+		}
+	})
+
+	t.Run("no changes when in sync", func(t *testing.T) {
+		inSync := &File{Categories: []FileEntry{
+			{Name: "Project X", Color: "preset0"},
+			{Name: "Travel", Color: "preset4"},
+			{Name: "Old", Color: "preset1"},
+		}}
+		if got := Plan(current, inSync, false); len(got) != 0 {
+			t.Errorf("Plan() = %+v, want no changes", got)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package categories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// colorNames lists the Outlook colour names of preset0 to preset24
+var colorNames = []string{
+	"red", "orange", "brown", "yellow", "green", "teal", "olive", "blue",
+	"purple", "cranberry", "steel", "dark steel", "gray", "dark gray", "black",
+	"dark red", "dark orange", "dark brown", "dark yellow", "dark green",
+	"dark teal", "dark olive", "dark blue", "dark purple", "dark cranberry",
+}
+
+// ansiColors holds the closest 256-colour terminal code of each preset
+var ansiColors = []int{
+	196, 208, 130, 220, 34, 37, 100, 33,
+	93, 161, 109, 60, 245, 240, 238,
+	88, 166, 94, 136, 22,
+	23, 58, 18, 54, 89,
+}
+
+// ParseColor turns a colour name ("blue", "dark blue", "darkblue") or a
+// preset ("preset7") into a Graph preset. "none" removes the colour.
+func ParseColor(s string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	if key == "" || key == "none" {
+		return "none", nil
+	}
+
+	if n, ok := presetIndex(key); ok {
+		return fmt.Sprintf("preset%d", n), nil
+	}
+
+	key = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(key)
+	key = strings.ReplaceAll(key, "grey", "gray")
+	for i, name := range colorNames {
+		if key == strings.ReplaceAll(name, " ", "") {
+			return fmt.Sprintf("preset%d", i), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid colour: %s (use a name like blue or dark green, or preset0-preset24)", s)
+}
+
+// ColorName returns the colour name of a preset, or "none"
+func ColorName(preset string) string {
+	if n, ok := presetIndex(preset); ok {
+		return colorNames[n]
+	}
+	return "none"
+}
+
+// ColorNames returns every colour name in preset order
+func ColorNames() []string {
+	return append([]string{}, colorNames...)
+}
+
+// Colorize wraps s in the terminal colour of a preset. Text is returned
+// unchanged for "none" or unknown presets.
+func Colorize(preset, s string) string {
+	n, ok := presetIndex(preset)
+	if !ok {
+		return s
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", ansiColors[n], s)
+}
+
+// presetIndex returns the number of a "presetN" value
+func presetIndex(preset string) (int, bool) {
+	rest, ok := strings.CutPrefix(strings.ToLower(preset), "preset")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 0 || n >= len(colorNames) {
+		return 0, false
+	}
+	return n, true
+}
@@ -33,7 +33,7 @@ Before first login, you need to create an Azure app registration:
 1. Go to https://portal.azure.com → App registrations
 2. Create a new registration with "personal + work accounts" support
 3. Enable "Allow public client flows" in Authentication settings
-4. Add API permissions: User.Read, Mail.Read, Mail.ReadWrite, Mail.Send, Calendars.Read, Calendars.ReadWrite
+4. Add API permissions: User.Read, Mail.Read, Mail.ReadWrite, Mail.Send, Calendars.Read, Calendars.ReadWrite, MailboxSettings.ReadWrite
 5. Copy the Application (client) ID and use it with --client-id`,
 	RunE: runLogin,
 }
@@ -9,7 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/pp/octl/internal/calendar"
-	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/graph"
 	"github.com/pp/octl/internal/output"
 )
 
@@ -154,7 +154,7 @@ func runCalendarList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return printEvents(cmd, events)
+	return printEvents(ctx, cmd, client, events)
 }
 
 func runCalendarToday(cmd *cobra.Command, args []string) error {
@@ -185,7 +185,7 @@ func runCalendarToday(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	return printEvents(cmd, events)
+	return printEvents(ctx, cmd, client, events)
 }
 
 func runCalendarWeek(cmd *cobra.Command, args []string) error {
@@ -221,7 +221,7 @@ func runCalendarWeek(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	return printEvents(cmd, events)
+	return printEvents(ctx, cmd, client, events)
 }
 
 func runCalendarShow(cmd *cobra.Command, args []string) error {
@@ -277,7 +277,7 @@ func runCalendarShow(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 		body := event.Body
 		if event.BodyContentType == "html" {
-			body = mail.StripHTML(body)
+			body = renderHTML(body)
 		}
 		fmt.Println(body)
 	}
@@ -403,7 +403,7 @@ func runCalendarDelete(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func printEvents(cmd *cobra.Command, events []calendar.Event) error {
+func printEvents(ctx context.Context, cmd *cobra.Command, client *graph.Client, events []calendar.Event) error {
 	if len(events) == 0 {
 		fmt.Println("No events found")
 		return nil
@@ -414,7 +414,13 @@ func printEvents(cmd *cobra.Command, events []calendar.Event) error {
 		return output.New(format).Print(events)
 	}
 
-	table := output.NewTable("DATE", "TIME", "SUBJECT", "LOCATION")
+	tagged := false
+	for _, ev := range events {
+		tagged = tagged || len(ev.Categories) > 0
+	}
+	colors := tableCategoryColors(ctx, client, tagged)
+
+	table := output.NewTable("DATE", "TIME", "SUBJECT", "LOCATION", "CATEGORIES")
 	for _, ev := range events {
 		location := ev.Location
 		if location == "" && ev.IsOnline {
@@ -432,6 +438,7 @@ func printEvents(cmd *cobra.Command, events []calendar.Event) error {
 			ev.FormatTime(),
 			subject,
 			location,
+			formatCategories(ev.Categories, colors),
 		)
 	}
 
@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/categories"
+	"github.com/pp/octl/internal/graph"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// categories flags
+	categoryColor     string
+	categoryRetag     bool
+	categoryDeleteYes bool
+	categoryFile      string
+	categoryDryRun    bool
+	categoryKeepExtra bool
+	categoryApplyYes  bool
+)
+
+var colorHelp = "Colours: " + strings.Join(categories.ColorNames(), ", ") + ",\nnone, or a Graph preset such as preset7."
+
+var categoriesCmd = &cobra.Command{
+	Use:   "categories",
+	Short: "Manage Outlook categories",
+	Long: `Manage the master category list shared by mail, calendar, and contacts.
+
+Categories on items are matched by name; the master list gives each name
+a colour.
+
+Categories need the MailboxSettings.ReadWrite permission. If you logged in
+before octl asked for it, add it to the app registration and run
+"octl auth login" again.`,
+}
+
+var categoriesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List categories",
+	RunE:  runCategoriesList,
+}
+
+var categoriesCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a category",
+	Long: `Create a category in the master list.
+
+` + colorHelp,
+	Args: cobra.ExactArgs(1),
+	RunE: runCategoriesCreate,
+}
+
+var categoriesRenameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Short: "Rename a category",
+	Long: `Rename a category, keeping its colour.
+
+Graph cannot rename a category in place, so a new category is created and
+the old one deleted. Items keep the old name unless --retag is given,
+which updates every message tagged with it. Calendar events are not
+retagged.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCategoriesRename,
+}
+
+var categoriesDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a category",
+	Long: `Delete a category from the master list. Items tagged with it keep the
+name but lose the colour.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCategoriesDelete,
+}
+
+var categoriesSetColorCmd = &cobra.Command{
+	Use:   "set-color <name> <color>",
+	Short: "Change the colour of a category",
+	Long: `Change the colour of a category.
+
+` + colorHelp,
+	Args: cobra.ExactArgs(2),
+	RunE: runCategoriesSetColor,
+}
+
+var categoriesApplyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Make the master list match a file",
+	Long: `Make the master category list match a declared YAML file, creating,
+recolouring, and deleting categories as needed. Categories not in the file
+are deleted unless --keep-extra is given.
+
+File format:
+  categories:
+    - name: Project X
+      color: blue
+    - name: Travel
+      color: dark green
+
+` + colorHelp,
+	RunE: runCategoriesApply,
+}
+
+func init() {
+	rootCmd.AddCommand(categoriesCmd)
+	categoriesCmd.AddCommand(categoriesListCmd)
+	categoriesCmd.AddCommand(categoriesCreateCmd)
+	categoriesCmd.AddCommand(categoriesRenameCmd)
+	categoriesCmd.AddCommand(categoriesDeleteCmd)
+	categoriesCmd.AddCommand(categoriesSetColorCmd)
+	categoriesCmd.AddCommand(categoriesApplyCmd)
+
+	categoriesCreateCmd.Flags().StringVarP(&categoryColor, "color", "c", "none", "Category colour")
+
+	categoriesRenameCmd.Flags().BoolVar(&categoryRetag, "retag", false, "Update messages tagged with the old name")
+
+	categoriesDeleteCmd.Flags().BoolVarP(&categoryDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+
+	categoriesApplyCmd.Flags().StringVarP(&categoryFile, "file", "f", "", "Categories YAML file")
+	categoriesApplyCmd.Flags().BoolVar(&categoryDryRun, "dry-run", false, "Show the changes without applying them")
+	categoriesApplyCmd.Flags().BoolVar(&categoryKeepExtra, "keep-extra", false, "Keep categories that are not in the file")
+	categoriesApplyCmd.Flags().BoolVarP(&categoryApplyYes, "yes", "y", false, "Skip the confirmation prompt for deletions")
+	_ = categoriesApplyCmd.MarkFlagRequired("file")
+}
+
+func runCategoriesList(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	list, err := categories.List(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(list)
+	}
+
+	if len(list) == 0 {
+		fmt.Println("No categories found")
+		return nil
+	}
+
+	if format == "plain" {
+		table := output.NewTable("NAME", "COLOR", "PRESET", "ID")
+		for _, c := range list {
+			table.AddRow(c.DisplayName, c.ColorName, c.Color, c.ID)
+		}
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	color := output.ColorEnabled(os.Stdout)
+	table := output.NewTable("NAME", "COLOR")
+	for _, c := range list {
+		swatch := c.ColorName
+		if color {
+			swatch = categories.Colorize(c.Color, "● "+c.ColorName)
+		}
+		table.AddRow(c.DisplayName, swatch)
+	}
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runCategoriesCreate(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	category, err := categories.Create(ctx, client.Graph(), args[0], categoryColor)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(category)
+	}
+
+	fmt.Printf("Category created: %s (%s)\n", category.DisplayName, category.ColorName)
+	return nil
+}
+
+func runCategoriesRename(cmd *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+	if strings.EqualFold(oldName, newName) {
+		return fmt.Errorf("category names are case-insensitive; %q and %q are the same category", oldName, newName)
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	list, err := categories.List(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	existing, ok := categories.Find(list, oldName)
+	if !ok {
+		return fmt.Errorf("category not found: %s", oldName)
+	}
+	if _, taken := categories.Find(list, newName); taken {
+		return fmt.Errorf("category already exists: %s", newName)
+	}
+
+	if _, err := categories.Rename(ctx, client.Graph(), *existing, newName); err != nil {
+		return err
+	}
+	fmt.Printf("Category renamed: %s → %s\n", existing.DisplayName, newName)
+
+	if !categoryRetag {
+		return nil
+	}
+
+	return retagMessages(ctx, client, existing.DisplayName, newName)
+}
+
+// retagMessages replaces a category on every message tagged with it
+func retagMessages(ctx context.Context, client *graph.Client, oldName, newName string) error {
+	query, err := mail.Criteria{Category: oldName}.Compile()
+	if err != nil {
+		return err
+	}
+
+	opts := mail.ListOptions{Top: 100}
+	query.Apply(&opts)
+
+	messages, err := mail.ListAllMessages(ctx, client.Graph(), opts, 0)
+	if err != nil {
+		return err
+	}
+
+	result, err := mail.RunBulk(ctx, messageIDs(messages), mail.BulkOptions{Concurrency: 4}, func(ctx context.Context, id string) error {
+		_, err := mail.UpdateCategories(ctx, client.Graph(), id, []string{newName}, []string{oldName})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range result.Failed {
+		PrintError("%s: %s", f.ID, f.Error)
+	}
+	fmt.Printf("Retagged %d of %d message(s)\n", result.Succeeded, result.Total)
+
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d message(s) failed to retag", len(result.Failed))
+	}
+	return nil
+}
+
+func runCategoriesDelete(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	category, err := findCategory(ctx, client, args[0])
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if !categoryDeleteYes && !confirm(fmt.Sprintf("Delete category %q?", category.DisplayName)) {
+		return fmt.Errorf("aborted")
+	}
+
+	// The prompt may have outlasted the lookup's timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := categories.Delete(ctx, client.Graph(), category.ID); err != nil {
+		return err
+	}
+
+	fmt.Println("Category deleted")
+	return nil
+}
+
+func runCategoriesSetColor(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	category, err := findCategory(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := categories.SetColor(ctx, client.Graph(), category.ID, args[1]); err != nil {
+		return err
+	}
+
+	fmt.Println("Category colour updated")
+	return nil
+}
+
+func runCategoriesApply(cmd *cobra.Command, args []string) error {
+	file, err := categories.LoadFile(categoryFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	current, err := categories.List(ctx, client.Graph())
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	changes := categories.Plan(current, file, categoryKeepExtra)
+
+	format := GetOutputFormat()
+	if format == "json" && categoryDryRun {
+		return output.New(format).Print(changes)
+	}
+
+	if len(changes) == 0 {
+		if format == "json" {
+			return output.New(format).Print(changes)
+		}
+		fmt.Println("Categories already match")
+		return nil
+	}
+
+	if format != "json" {
+		printCategoryChanges(changes)
+	}
+	if categoryDryRun {
+		return nil
+	}
+
+	deletes := 0
+	for _, c := range changes {
+		if c.Action == categories.ActionDelete {
+			deletes++
+		}
+	}
+	if deletes > 0 && !categoryApplyYes && !confirm(fmt.Sprintf("Delete %d categories not in the file?", deletes)) {
+		return fmt.Errorf("aborted")
+	}
+
+	// Start the timeout after the prompt so a slow answer cannot cut the
+	// plan short
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	applied, err := categories.Apply(ctx, client.Graph(), changes)
+	if format == "json" {
+		if perr := output.New(format).Print(changes[:applied]); perr != nil {
+			return perr
+		}
+	} else {
+		fmt.Printf("Applied %d of %d change(s)\n", applied, len(changes))
+	}
+	return err
+}
+
+// printCategoryChanges prints a category plan as a diff
+func printCategoryChanges(changes []categories.Change) {
+	for _, c := range changes {
+		switch c.Action {
+		case categories.ActionCreate:
+			fmt.Printf("+ %s (%s)\n", c.Name, categories.ColorName(c.Color))
+		case categories.ActionSetColor:
+			fmt.Printf("~ %s (%s → %s)\n", c.Name, categories.ColorName(c.OldColor), categories.ColorName(c.Color))
+		case categories.ActionDelete:
+			fmt.Printf("- %s (%s)\n", c.Name, categories.ColorName(c.OldColor))
+		}
+	}
+}
+
+// findCategory looks up a category by name
+func findCategory(ctx context.Context, client *graph.Client, name string) (*categories.Category, error) {
+	list, err := categories.List(ctx, client.Graph())
+	if err != nil {
+		return nil, err
+	}
+
+	category, ok := categories.Find(list, name)
+	if !ok {
+		return nil, fmt.Errorf("category not found: %s", name)
+	}
+	return category, nil
+}
+
+// categoryColors maps lower-cased category names to their colour preset.
+// Colours are decoration only, so lookup errors yield an empty map.
+func categoryColors(ctx context.Context, client *graph.Client) map[string]string {
+	colors := make(map[string]string)
+
+	list, err := categories.List(ctx, client.Graph())
+	if err != nil {
+		return colors
+	}
+
+	for _, c := range list {
+		colors[strings.ToLower(c.DisplayName)] = c.Color
+	}
+	return colors
+}
+
+// formatCategories joins category names, coloured by the master list when
+// colors is non-nil
+func formatCategories(names []string, colors map[string]string) string {
+	if colors == nil {
+		return strings.Join(names, ", ")
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = categories.Colorize(colors[strings.ToLower(name)], name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tableCategoryColors loads category colours for table output when any
+// item is tagged and stdout is a terminal; otherwise it returns nil
+func tableCategoryColors(ctx context.Context, client *graph.Client, tagged bool) map[string]string {
+	if !tagged || GetOutputFormat() != "table" || !output.ColorEnabled(os.Stdout) {
+		return nil
+	}
+	return categoryColors(ctx, client)
+}
@@ -17,9 +17,14 @@ import (
 
 var (
 	// mail list flags
-	mailListCount  int32
-	mailListUnread bool
-	mailListFolder string
+	mailListCount int32
+
+	// mail read flags
+	mailReadHeaders bool
+	mailReadRaw     bool
+
+	// mail folders flags
+	mailFoldersTree bool
 
 	// mail send flags
 	mailTo      []string
@@ -28,6 +33,10 @@ var (
 	mailSubject string
 	mailBody    string
 	mailHTML    bool
+
+	mailImportance string
+	mailAt         string
+	mailTZ         string
 )
 
 var mailCmd = &cobra.Command{
@@ -39,31 +48,55 @@ var mailCmd = &cobra.Command{
 var mailListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List recent emails",
-	Long:  `List recent email messages from your inbox.`,
-	RunE:  runMailList,
+	Long: `List recent email messages, newest first.
+
+Filter flags are combined with AND. For example:
+  octl mail list --from jane@example.com --since 7d --has-attachments
+  octl mail list --folder "Inbox/Projects" --flagged --importance high
+  octl mail list --focused --unread`,
+	RunE: runMailList,
 }
 
 var mailReadCmd = &cobra.Command{
 	Use:   "read <message-id>",
 	Short: "Read an email message",
-	Long:  `Read the full content of an email message.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runMailRead,
+	Long: `Read the full content of an email message.
+
+--headers adds the internet message headers; "octl mail headers" shows a
+parsed view of them. --raw prints the original MIME message instead.
+
+Examples:
+  octl mail read <message-id>
+  octl mail read <message-id> --headers
+  octl mail read <message-id> --raw > message.eml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailRead,
 }
 
 var mailSearchCmd = &cobra.Command{
-	Use:   "search <query>",
+	Use:   "search [query]",
 	Short: "Search emails",
-	Long:  `Search email messages by keywords.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runMailSearch,
+	Long: `Search email messages by keywords and structured filters.
+
+The query is free text or KQL and can be combined with filter flags.
+Graph cannot mix full-text search with filters, so the flags are compiled
+to whichever form the combination allows.
+
+Examples:
+  octl mail search "quarterly report"
+  octl mail search --from "Jane Doe" --since 30d
+  octl mail search budget --to finance@example.com --has-attachments`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMailSearch,
 }
 
 var mailFoldersCmd = &cobra.Command{
 	Use:   "folders",
 	Short: "List mail folders",
-	Long:  `List all mail folders in your mailbox.`,
-	RunE:  runMailFolders,
+	Long: `List all mail folders in your mailbox.
+
+Use --tree to include child folders and show the folder hierarchy.`,
+	RunE: runMailFolders,
 }
 
 var mailSendCmd = &cobra.Command{
@@ -71,26 +104,42 @@ var mailSendCmd = &cobra.Command{
 	Short: "Send an email",
 	Long: `Send an email message.
 
-Example:
-  octl mail send --to user@example.com --subject "Hello" --body "Message body"`,
+` + composeHelp + `
+
+Examples:
+  octl mail send --to user@example.com --subject "Hello" --body "Message body"
+  octl mail send --to user@example.com --subject "Notes" --markdown --body-file notes.md
+  git log -5 --oneline | octl mail send --to team@example.com --subject "Changes" --body-file -
+  octl mail send --to user@example.com
+  octl mail send --to user@example.com --subject "Hello" --body "Hi" --at "2026-10-20T09:00" --tz Europe/Berlin
+  octl mail send --to '"Doe, Jane" <jane@example.com>' --reply-to team@example.com --sensitivity private
+  octl mail send --to user@example.com --subject "Report" --body "Attached" --attach report.pdf --attach data.csv
+
+Addresses may carry a display name, as in "Jane Doe <jane@example.com>";
+quote names that contain commas. Invalid addresses are rejected before
+anything is sent.
+
+--at schedules the message: Exchange holds it in the Outbox and sends it
+at that time, even if octl is not running. The time is read in --tz (an
+IANA or Windows zone name), or the local zone. List or cancel scheduled
+messages with "octl mail scheduled".`,
 	RunE: runMailSend,
 }
 
 var mailDraftCmd = &cobra.Command{
 	Use:   "draft",
-	Short: "Create a draft email",
-	Long:  `Create a draft email message.`,
-	RunE:  runMailDraft,
-}
-
-var mailMoveCmd = &cobra.Command{
-	Use:   "move <message-id> <folder>",
-	Short: "Move a message to a folder",
-	Long: `Move an email message to a different folder.
-
-Folder can be a folder ID or well-known name: inbox, drafts, sentitems, deleteditems, junkemail, archive`,
-	Args: cobra.ExactArgs(2),
-	RunE: runMailMove,
+	Short: "Create and manage draft emails",
+	Long: `Create a draft email message. The printed ID works with the draft
+edit, send, and delete subcommands; "octl mail drafts" lists drafts.
+
+` + composeHelp + `
+
+Examples:
+  octl mail draft --to user@example.com --subject "Proposal" --body-file proposal.md --markdown
+  octl mail draft edit <draft-id>
+  octl mail draft send <draft-id>`,
+	Args: cobra.NoArgs,
+	RunE: runMailDraft,
 }
 
 func init() {
@@ -101,32 +150,40 @@ func init() {
 	mailCmd.AddCommand(mailFoldersCmd)
 	mailCmd.AddCommand(mailSendCmd)
 	mailCmd.AddCommand(mailDraftCmd)
-	mailCmd.AddCommand(mailMoveCmd)
 
 	// mail list flags
 	mailListCmd.Flags().Int32VarP(&mailListCount, "count", "n", 25, "Number of messages to list")
-	mailListCmd.Flags().BoolVarP(&mailListUnread, "unread", "u", false, "Only show unread messages")
-	mailListCmd.Flags().StringVarP(&mailListFolder, "folder", "f", "", "Folder to list messages from")
+	bindQueryFlags(mailListCmd, &listQuery)
+
+	// mail read flags
+	mailReadCmd.Flags().BoolVar(&mailReadHeaders, "headers", false, "Include the internet message headers")
+	mailReadCmd.Flags().BoolVar(&mailReadRaw, "raw", false, "Print the raw MIME message")
+	mailReadCmd.MarkFlagsMutuallyExclusive("headers", "raw")
+
+	// mail folders flags
+	mailFoldersCmd.Flags().BoolVar(&mailFoldersTree, "tree", false, "Show the full folder hierarchy")
 
 	// mail search flags
 	mailSearchCmd.Flags().Int32VarP(&mailListCount, "count", "n", 25, "Maximum number of results")
+	bindQueryFlags(mailSearchCmd, &searchQuery)
 
 	// mail send flags
-	mailSendCmd.Flags().StringSliceVar(&mailTo, "to", nil, "Recipient email address(es)")
-	mailSendCmd.Flags().StringSliceVar(&mailCc, "cc", nil, "CC recipient(s)")
-	mailSendCmd.Flags().StringSliceVar(&mailBcc, "bcc", nil, "BCC recipient(s)")
+	bindAddressFlags(mailSendCmd)
 	mailSendCmd.Flags().StringVar(&mailSubject, "subject", "", "Email subject")
 	mailSendCmd.Flags().StringVar(&mailBody, "body", "", "Email body")
 	mailSendCmd.Flags().BoolVar(&mailHTML, "html", false, "Send body as HTML")
-	_ = mailSendCmd.MarkFlagRequired("to")
-	_ = mailSendCmd.MarkFlagRequired("subject")
-	_ = mailSendCmd.MarkFlagRequired("body")
+	bindOptionFlags(mailSendCmd)
+	mailSendCmd.Flags().StringVar(&mailAt, "at", "", "Schedule the message for this time (YYYY-MM-DDTHH:MM or RFC 3339)")
+	mailSendCmd.Flags().StringVar(&mailTZ, "tz", "", "Time zone for --at (default: local)")
+	bindComposeFlags(mailSendCmd)
 
 	// mail draft flags
-	mailDraftCmd.Flags().StringSliceVar(&mailTo, "to", nil, "Recipient email address(es)")
+	bindAddressFlags(mailDraftCmd)
 	mailDraftCmd.Flags().StringVar(&mailSubject, "subject", "", "Email subject")
 	mailDraftCmd.Flags().StringVar(&mailBody, "body", "", "Email body")
 	mailDraftCmd.Flags().BoolVar(&mailHTML, "html", false, "Body is HTML")
+	bindOptionFlags(mailDraftCmd)
+	bindComposeFlags(mailDraftCmd)
 }
 
 func getGraphClient() (*graph.Client, error) {
@@ -143,7 +200,68 @@ func getGraphClient() (*graph.Client, error) {
 	return graph.NewClient(authMgr.GetCredential())
 }
 
+// Queries filtered client-side read pages of clientSidePageSize messages and
+// stop after clientSidePageLimit pages
+const (
+	clientSidePageSize  = 100
+	clientSidePageLimit = 20
+)
+
+// queryMessages compiles criteria into a Graph query and lists the
+// matching messages in a folder (or the whole mailbox)
+func queryMessages(ctx context.Context, client *graph.Client, criteria mail.Criteria, folder string, top int32) ([]mail.Message, error) {
+	query, err := criteria.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Focused Inbox only sorts the Inbox
+	if criteria.Classification != "" && folder == "" {
+		folder = mail.FolderInbox
+	}
+
+	folderID, err := resolveFolder(ctx, client, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mail.ListOptions{
+		Top:      top,
+		FolderID: folderID,
+	}
+	query.Apply(&opts)
+
+	if !query.ClientSide() {
+		return mail.ListMessages(ctx, client.Graph(), opts)
+	}
+
+	// Part of the query is applied here, so read further pages until enough
+	// messages match
+	opts.Top = clientSidePageSize
+	return mail.ListMatchingMessages(ctx, client.Graph(), opts, query.Match, int(top), clientSidePageLimit)
+}
+
+// hasCategories reports whether any message is tagged with a category
+func hasCategories(messages []mail.Message) bool {
+	for _, m := range messages {
+		if len(m.Categories) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFolder resolves a folder ID, well-known name, or path to a folder ID
+func resolveFolder(ctx context.Context, client *graph.Client, ref string) (string, error) {
+	return mail.ResolveFolderID(ctx, client.Graph(), ref)
+}
+
 func runMailList(cmd *cobra.Command, args []string) error {
+	criteria, err := listQuery.criteria(cmd)
+	if err != nil {
+		return err
+	}
+
 	client, err := getGraphClient()
 	if err != nil {
 		return err
@@ -152,13 +270,7 @@ func runMailList(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	opts := mail.ListOptions{
-		Top:        mailListCount,
-		UnreadOnly: mailListUnread,
-		FolderID:   mailListFolder,
-	}
-
-	messages, err := mail.ListMessages(ctx, client.Graph(), opts)
+	messages, err := queryMessages(ctx, client, criteria, listQuery.folder, mailListCount)
 	if err != nil {
 		return err
 	}
@@ -173,7 +285,9 @@ func runMailList(cmd *cobra.Command, args []string) error {
 		return output.New(format).Print(messages)
 	}
 
-	table := output.NewTable("ID", "FROM", "SUBJECT", "DATE", "READ")
+	colors := tableCategoryColors(ctx, client, hasCategories(messages))
+
+	table := output.NewTable("ID", "FROM", "SUBJECT", "DATE", "READ", "CATEGORIES")
 	for _, msg := range messages {
 		read := " "
 		if msg.IsRead {
@@ -185,6 +299,7 @@ func runMailList(cmd *cobra.Command, args []string) error {
 			msg.FormatSubject(50),
 			msg.FormatDate(),
 			read,
+			formatCategories(msg.Categories, colors),
 		)
 	}
 
@@ -206,37 +321,76 @@ func runMailRead(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if mailReadRaw {
+		content, err := mail.GetMessageMIME(ctx, client.Graph(), messageID)
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(content)
+		return err
+	}
+
 	msg, err := mail.GetMessage(ctx, client.Graph(), messageID)
 	if err != nil {
 		return err
 	}
 
+	if mailReadHeaders {
+		if msg.Headers, err = mail.GetHeaders(ctx, client.Graph(), messageID); err != nil {
+			return err
+		}
+	}
+
 	format := GetOutputFormat()
 	if format == "json" {
 		return output.New(format).Print(msg)
 	}
 
 	// Print message details
-	fmt.Printf("From:    %s\n", msg.From)
-	fmt.Printf("To:      %s\n", strings.Join(msg.To, ", "))
-	fmt.Printf("Subject: %s\n", msg.Subject)
-	fmt.Printf("Date:    %s\n", msg.ReceivedAt.Format(time.RFC1123))
+	fmt.Printf("From:       %s\n", msg.From)
+	fmt.Printf("To:         %s\n", strings.Join(msg.To, ", "))
+	fmt.Printf("Subject:    %s\n", msg.Subject)
+	fmt.Printf("Date:       %s\n", msg.ReceivedAt.Format(time.RFC1123))
+	if msg.Importance != "" && msg.Importance != "normal" {
+		fmt.Printf("Importance: %s\n", msg.Importance)
+	}
+	if msg.Flag != nil {
+		fmt.Printf("Flag:       %s\n", formatFlag(msg.Flag))
+	}
+	if len(msg.Categories) > 0 {
+		fmt.Printf("Categories: %s\n", strings.Join(msg.Categories, ", "))
+	}
+	if mailReadHeaders {
+		fmt.Println()
+		fmt.Println("Headers:")
+		if len(msg.Headers) == 0 {
+			fmt.Println("  (none; drafts and some sent items have no internet headers)")
+		}
+		for _, h := range msg.Headers {
+			fmt.Printf("  %s: %s\n", h.Name, h.Value)
+		}
+	}
 	fmt.Println()
 	fmt.Println("---")
 	fmt.Println()
 
 	// Print body
-	body := msg.Body
-	if msg.BodyContentType == "html" {
-		body = mail.StripHTML(body)
-	}
-	fmt.Println(body)
+	fmt.Println(bodyText(msg))
 
 	return nil
 }
 
 func runMailSearch(cmd *cobra.Command, args []string) error {
-	query := args[0]
+	criteria, err := searchQuery.criteria(cmd)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		criteria.Text = args[0]
+	}
+	if criteria == (mail.Criteria{}) {
+		return fmt.Errorf("give a search query or at least one filter flag")
+	}
 
 	client, err := getGraphClient()
 	if err != nil {
@@ -246,7 +400,7 @@ func runMailSearch(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	messages, err := mail.SearchMessages(ctx, client.Graph(), query, mailListCount)
+	messages, err := queryMessages(ctx, client, criteria, searchQuery.folder, mailListCount)
 	if err != nil {
 		return err
 	}
@@ -261,13 +415,16 @@ func runMailSearch(cmd *cobra.Command, args []string) error {
 		return output.New(format).Print(messages)
 	}
 
-	table := output.NewTable("ID", "FROM", "SUBJECT", "DATE")
+	colors := tableCategoryColors(ctx, client, hasCategories(messages))
+
+	table := output.NewTable("ID", "FROM", "SUBJECT", "DATE", "CATEGORIES")
 	for _, msg := range messages {
 		table.AddRow(
 			msg.ID[:8]+"...",
 			msg.FormatFrom(30),
 			msg.FormatSubject(50),
 			msg.FormatDate(),
+			formatCategories(msg.Categories, colors),
 		)
 	}
 
@@ -287,6 +444,10 @@ func runMailFolders(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if mailFoldersTree {
+		return printFolderTree(ctx, cmd, client)
+	}
+
 	folders, err := mail.ListFolders(ctx, client.Graph())
 	if err != nil {
 		return err
@@ -314,89 +475,130 @@ func runMailFolders(cmd *cobra.Command, args []string) error {
 	return table.Render(cmd.OutOrStdout())
 }
 
-func runMailSend(cmd *cobra.Command, args []string) error {
-	client, err := getGraphClient()
+func printFolderTree(ctx context.Context, cmd *cobra.Command, client *graph.Client) error {
+	tree, err := mail.ListFolderTree(ctx, client.Graph())
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(tree)
+	}
 
-	bodyType := "text"
-	if mailHTML {
-		bodyType = "html"
+	if format == "plain" {
+		table := output.NewTable("ID", "PATH", "TOTAL", "UNREAD")
+		mail.WalkFolders(tree, func(f mail.Folder, depth int) {
+			table.AddRow(
+				f.ID,
+				f.Path,
+				fmt.Sprintf("%d", f.TotalItemCount),
+				fmt.Sprintf("%d", f.UnreadItemCount),
+			)
+		})
+		return output.New(format).Print(table.ToPlain())
 	}
 
-	opts := mail.SendOptions{
-		To:         mailTo,
-		Cc:         mailCc,
-		Bcc:        mailBcc,
-		Subject:    mailSubject,
-		Body:       mailBody,
-		BodyType:   bodyType,
-		SaveToSent: true,
+	table := output.NewTable("NAME", "TOTAL", "UNREAD")
+	mail.WalkFolders(tree, func(f mail.Folder, depth int) {
+		table.AddRow(
+			strings.Repeat("  ", depth)+f.DisplayName,
+			fmt.Sprintf("%d", f.TotalItemCount),
+			fmt.Sprintf("%d", f.UnreadItemCount),
+		)
+	})
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runMailSend(cmd *cobra.Command, args []string) error {
+	if mailTZ != "" && mailAt == "" {
+		return fmt.Errorf("--tz requires --at")
 	}
 
-	if err := mail.SendMessage(ctx, client.Graph(), opts); err != nil {
-		return err
+	var sendAt time.Time
+	if mailAt != "" {
+		var err error
+		if sendAt, err = parseSendTime(mailAt, mailTZ); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("Message sent successfully")
-	return nil
-}
+	opts, err := flagSendOptions()
+	if err != nil {
+		return err
+	}
+	opts.SaveToSent = true
 
-func runMailDraft(cmd *cobra.Command, args []string) error {
 	client, err := getGraphClient()
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	bodyType := "text"
-	if mailHTML {
-		bodyType = "html"
+	composed, err := composeMessage(cmd, &opts, true)
+	if err != nil {
+		return err
 	}
 
-	opts := mail.SendOptions{
-		To:       mailTo,
-		Subject:  mailSubject,
-		Body:     mailBody,
-		BodyType: bodyType,
+	// The editor may have been open for a while, so the timeout starts now
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout(opts))
+	defer cancel()
+
+	if !sendAt.IsZero() {
+		err = mail.ScheduleMessage(ctx, client.Graph(), opts, sendAt)
+		composed.Finish(err)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Message scheduled for %s", sendAt.Format("Mon Jan 2 15:04 MST"))
+		if sendAt.Location() != time.Local {
+			fmt.Printf(" (%s local)", sendAt.Local().Format("Mon Jan 2 15:04 MST"))
+		}
+		fmt.Println()
+		return nil
 	}
 
-	draft, err := mail.CreateDraft(ctx, client.Graph(), opts)
+	err = mail.SendMessage(ctx, client.Graph(), opts)
+	composed.Finish(err)
 	if err != nil {
 		return err
 	}
 
-	format := GetOutputFormat()
-	if format == "json" {
-		return output.New(format).Print(draft)
-	}
-
-	fmt.Printf("Draft created: %s\n", draft.ID)
+	fmt.Println("Message sent successfully")
 	return nil
 }
 
-func runMailMove(cmd *cobra.Command, args []string) error {
-	messageID := args[0]
-	folderID := args[1]
+func runMailDraft(cmd *cobra.Command, args []string) error {
+	opts, err := flagSendOptions()
+	if err != nil {
+		return err
+	}
 
 	client, err := getGraphClient()
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	composed, err := composeMessage(cmd, &opts, false)
+	if err != nil {
+		return err
+	}
+
+	// The editor may have been open for a while, so the timeout starts now
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout(opts))
 	defer cancel()
 
-	if err := mail.MoveMessage(ctx, client.Graph(), messageID, folderID); err != nil {
+	draft, err := mail.CreateDraft(ctx, client.Graph(), opts)
+	composed.Finish(err)
+	if err != nil {
 		return err
 	}
 
-	fmt.Println("Message moved successfully")
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(draft)
+	}
+
+	fmt.Printf("Draft created: %s\n", draft.ID)
 	return nil
 }
@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/graph"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// bulk action flags
+	bulkQuery       queryFlags
+	bulkDryRun      bool
+	bulkYes         bool
+	bulkLimit       int
+	bulkConcurrency int
+)
+
+// bulkAction describes one operation applied to many messages
+type bulkAction struct {
+	// verb and past describe the action in prompts and summaries
+	verb string
+	past string
+	// destructive actions always ask for confirmation
+	destructive bool
+	run         func(ctx context.Context, id string) error
+}
+
+const bulkSelectorHelp = `Messages can be given as IDs, read from stdin with "-" (one ID per line,
+or the JSON printed by "mail list --json"), or selected with the filter
+flags, e.g. --folder, --from and --before 30d for messages older than
+30 days. Use --dry-run to see what would be touched.`
+
+var mailMarkReadCmd = &cobra.Command{
+	Use:   "mark-read [message-id...]",
+	Short: "Mark messages as read",
+	Long: `Mark one or more messages as read.
+
+` + bulkSelectorHelp + `
+
+Examples:
+  octl mail mark-read <id1> <id2>
+  octl mail mark-read --folder "Inbox/Newsletters" --unread --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMailBulk(cmd, args, func(client *graph.Client) bulkAction {
+			return bulkAction{
+				verb: "mark as read",
+				past: "Marked as read",
+				run: func(ctx context.Context, id string) error {
+					return mail.MarkAsRead(ctx, client.Graph(), id, true)
+				},
+			}
+		})
+	},
+}
+
+var mailMarkUnreadCmd = &cobra.Command{
+	Use:   "mark-unread [message-id...]",
+	Short: "Mark messages as unread",
+	Long: `Mark one or more messages as unread.
+
+` + bulkSelectorHelp,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMailBulk(cmd, args, func(client *graph.Client) bulkAction {
+			return bulkAction{
+				verb: "mark as unread",
+				past: "Marked as unread",
+				run: func(ctx context.Context, id string) error {
+					return mail.MarkAsRead(ctx, client.Graph(), id, false)
+				},
+			}
+		})
+	},
+}
+
+var mailDeleteCmd = &cobra.Command{
+	Use:   "delete [message-id...]",
+	Short: "Delete messages",
+	Long: `Delete one or more messages. Deleted messages go to Deleted Items.
+
+` + bulkSelectorHelp + `
+
+Examples:
+  octl mail delete <message-id>
+  octl mail delete --folder inbox --from noreply@example.com --before 90d --dry-run
+  octl mail list --unread --json | octl mail delete - --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMailBulk(cmd, args, func(client *graph.Client) bulkAction {
+			return bulkAction{
+				verb:        "delete",
+				past:        "Deleted",
+				destructive: true,
+				run: func(ctx context.Context, id string) error {
+					return mail.DeleteMessage(ctx, client.Graph(), id)
+				},
+			}
+		})
+	},
+}
+
+var mailMoveCmd = &cobra.Command{
+	Use:   "move [message-id...] <folder>",
+	Short: "Move messages to a folder",
+	Long: `Move one or more email messages to a different folder. The destination
+folder is always the last argument.
+
+Folder can be a folder ID, a well-known name (inbox, drafts, sentitems,
+deleteditems, junkemail, archive), or a path of folder names such as
+"Inbox/Projects/Acme". Paths are matched case-insensitively.
+
+` + bulkSelectorHelp + `
+
+With filter flags, --folder selects the source folder.
+
+Examples:
+  octl mail move <message-id> archive
+  octl mail move <id1> <id2> "Inbox/Projects/Acme"
+  octl mail move archive --folder inbox --before 1y --yes`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest := args[len(args)-1]
+		args = args[:len(args)-1]
+
+		var folderID string
+		return runMailBulk(cmd, args, func(client *graph.Client) bulkAction {
+			return bulkAction{
+				verb: "move",
+				past: "Moved",
+				run: func(ctx context.Context, id string) error {
+					if folderID == "" {
+						return fmt.Errorf("destination folder not resolved")
+					}
+					return mail.MoveMessage(ctx, client.Graph(), id, folderID)
+				},
+			}
+		}, func(ctx context.Context, client *graph.Client) error {
+			id, err := resolveFolder(ctx, client, dest)
+			folderID = id
+			return err
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{mailMarkReadCmd, mailMarkUnreadCmd, mailDeleteCmd, mailMoveCmd} {
+		mailCmd.AddCommand(c)
+
+		bindQueryFlags(c, &bulkQuery)
+		c.Flags().BoolVar(&bulkDryRun, "dry-run", false, "List the messages that would be affected without changing them")
+		c.Flags().BoolVarP(&bulkYes, "yes", "y", false, "Skip the confirmation prompt")
+		c.Flags().IntVar(&bulkLimit, "limit", 0, "Maximum number of messages selected by filter flags (0 for all)")
+		c.Flags().IntVar(&bulkConcurrency, "concurrency", 4, "Maximum parallel requests")
+	}
+}
+
+// runMailBulk selects the target messages and applies an action to each,
+// printing a progress summary and per-message failures. Setup hooks run
+// before messages are selected, e.g. to resolve a destination folder.
+func runMailBulk(cmd *cobra.Command, args []string, newAction func(client *graph.Client) bulkAction, setup ...func(ctx context.Context, client *graph.Client) error) error {
+	criteria, err := bulkQuery.criteria(cmd)
+	if err != nil {
+		return err
+	}
+
+	hasSelector := criteria != (mail.Criteria{}) || bulkQuery.folder != ""
+	if len(args) == 0 && !hasSelector {
+		return fmt.Errorf("give message IDs, \"-\" to read them from stdin, or select messages with filter flags")
+	}
+
+	ids, fromStdin, err := bulkArgIDs(args)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, fn := range setup {
+		if err := fn(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	messages := make([]mail.Message, 0, len(ids))
+	for _, id := range ids {
+		messages = append(messages, mail.Message{ID: id})
+	}
+
+	if hasSelector {
+		selected, err := selectMessages(ctx, client, criteria)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, selected...)
+	}
+
+	messages = uniqueMessages(messages)
+	if len(messages) == 0 {
+		fmt.Println("No messages found")
+		return nil
+	}
+
+	action := newAction(client)
+	format := GetOutputFormat()
+
+	if bulkDryRun {
+		return printBulkDryRun(cmd, messages, action)
+	}
+
+	needsConfirm := action.destructive || len(messages) > 1
+	if needsConfirm && !bulkYes {
+		if fromStdin {
+			return fmt.Errorf("message IDs were read from stdin, so the prompt cannot be answered; pass --yes to confirm")
+		}
+		if !confirm(fmt.Sprintf("%s %d message(s)?", capitalize(action.verb), len(messages))) {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	opts := mail.BulkOptions{Concurrency: bulkConcurrency}
+	if format != "json" && len(messages) > 1 {
+		opts.Progress = func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d", done, total)
+			if done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+
+	result, runErr := mail.RunBulk(ctx, messageIDs(messages), opts, action.run)
+	if runErr != nil && opts.Progress != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if format == "json" {
+		if err := output.New(format).Print(result); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range result.Failed {
+			PrintError("%s: %s", f.ID, f.Error)
+		}
+		fmt.Printf("%s %d of %d message(s), %d failed\n", action.past, result.Succeeded, result.Total, len(result.Failed))
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d message(s) failed", len(result.Failed))
+	}
+	return nil
+}
+
+// bulkArgIDs collects message IDs from the arguments, reading stdin for "-"
+func bulkArgIDs(args []string) ([]string, bool, error) {
+	var ids []string
+	fromStdin := false
+	for _, arg := range args {
+		if arg != "-" {
+			ids = append(ids, arg)
+			continue
+		}
+		if fromStdin {
+			continue
+		}
+		fromStdin = true
+
+		stdinIDs, err := mail.ReadIDs(os.Stdin)
+		if err != nil {
+			return nil, false, err
+		}
+		ids = append(ids, stdinIDs...)
+	}
+	return ids, fromStdin, nil
+}
+
+// selectMessages lists the messages matching the bulk filter flags, up to
+// --limit of them
+func selectMessages(ctx context.Context, client *graph.Client, criteria mail.Criteria) ([]mail.Message, error) {
+	query, err := criteria.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	folderID, err := resolveFolder(ctx, client, bulkQuery.folder)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mail.ListOptions{
+		Top:      100,
+		FolderID: folderID,
+	}
+	query.Apply(&opts)
+
+	return mail.ListMatchingMessages(ctx, client.Graph(), opts, query.Match, bulkLimit, 0)
+}
+
+// printBulkDryRun lists the messages a bulk action would touch
+func printBulkDryRun(cmd *cobra.Command, messages []mail.Message, action bulkAction) error {
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(messages)
+	}
+
+	table := output.NewTable("ID", "FROM", "SUBJECT", "DATE")
+	for _, msg := range messages {
+		date := ""
+		if !msg.ReceivedAt.IsZero() {
+			date = msg.FormatDate()
+		}
+		table.AddRow(msg.ID, msg.FormatFrom(30), truncate(msg.Subject, 50), date)
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	if err := table.Render(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+	fmt.Printf("\nWould %s %d message(s)\n", action.verb, len(messages))
+	return nil
+}
+
+// uniqueMessages drops messages whose ID was already seen
+func uniqueMessages(messages []mail.Message) []mail.Message {
+	seen := make(map[string]bool, len(messages))
+	unique := make([]mail.Message, 0, len(messages))
+	for _, m := range messages {
+		if m.ID == "" || seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		unique = append(unique, m)
+	}
+	return unique
+}
+
+// messageIDs returns the IDs of messages
+func messageIDs(messages []mail.Message) []string {
+	ids := make([]string, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// capitalize upper-cases the first letter of s
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
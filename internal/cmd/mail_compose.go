@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/compose"
+	"github.com/pp/octl/internal/mail"
+)
+
+var (
+	// mail send and mail draft body flags
+	mailBodyFile string
+	mailMarkdown bool
+	mailAttach   []string
+
+	// mail send and mail draft option flags
+	mailReplyTo         []string
+	mailSensitivity     string
+	mailReadReceipt     bool
+	mailDeliveryReceipt bool
+)
+
+// composeHelp describes the body options shared by mail send and mail draft
+const composeHelp = `Without --body or --body-file, $VISUAL or $EDITOR opens with the
+recipients and subject as To/Cc/Bcc/Subject headers above the body; edit
+them there and save. An empty body aborts.
+
+--markdown renders the body as Markdown to HTML and drops any raw HTML
+in it. The message is sent as multipart/alternative, with the Markdown
+source as the plain-text part for mail clients that do not show HTML.
+
+--attach attaches a file and may be repeated. Past 3 MB in all, files are
+uploaded in chunks once the message is saved as a draft.`
+
+// bindComposeFlags registers the body source and attachment flags on a
+// command
+func bindComposeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&mailBodyFile, "body-file", "", "Read the body from a file (- for stdin)")
+	cmd.Flags().BoolVar(&mailMarkdown, "markdown", false, "Render the body from Markdown to HTML")
+	cmd.Flags().StringArrayVar(&mailAttach, "attach", nil, "Attach a file (repeatable)")
+	cmd.MarkFlagsMutuallyExclusive("body", "body-file")
+	cmd.MarkFlagsMutuallyExclusive("html", "markdown")
+}
+
+// bindAddressFlags registers the recipient flags on a command. Values may
+// hold several comma-separated addresses with display names, such as
+// "Doe, Jane" <jane@example.com>, which a string slice flag would split.
+func bindAddressFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&mailTo, "to", nil, "Recipient email address(es)")
+	cmd.Flags().StringArrayVar(&mailCc, "cc", nil, "CC recipient(s)")
+	cmd.Flags().StringArrayVar(&mailBcc, "bcc", nil, "BCC recipient(s)")
+	cmd.Flags().StringArrayVar(&mailReplyTo, "reply-to", nil, "Address(es) that replies should go to")
+}
+
+// bindOptionFlags registers the delivery option flags on a command
+func bindOptionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&mailImportance, "importance", "", "Importance: low, normal, or high")
+	cmd.Flags().StringVar(&mailSensitivity, "sensitivity", "", "Sensitivity: normal, personal, private, or confidential")
+	cmd.Flags().BoolVar(&mailReadReceipt, "request-read-receipt", false, "Ask recipients for a read receipt")
+	cmd.Flags().BoolVar(&mailDeliveryReceipt, "request-delivery-receipt", false, "Ask for a delivery receipt")
+}
+
+// addressArgs splits address flag values into single addresses
+func addressArgs(values []string) []string {
+	var addrs []string
+	for _, v := range values {
+		addrs = append(addrs, compose.SplitAddresses(v)...)
+	}
+	return addrs
+}
+
+// flagSendOptions builds send options from the send and draft flags and
+// checks them, so that mistakes are caught before signing in
+func flagSendOptions() (mail.SendOptions, error) {
+	bodyType := "text"
+	if mailHTML {
+		bodyType = "html"
+	}
+
+	opts := mail.SendOptions{
+		To:              addressArgs(mailTo),
+		Cc:              addressArgs(mailCc),
+		Bcc:             addressArgs(mailBcc),
+		ReplyTo:         addressArgs(mailReplyTo),
+		Subject:         mailSubject,
+		Body:            mailBody,
+		BodyType:        bodyType,
+		Importance:      mailImportance,
+		Sensitivity:     mailSensitivity,
+		ReadReceipt:     mailReadReceipt,
+		DeliveryReceipt: mailDeliveryReceipt,
+		Attachments:     mailAttach,
+	}
+	return opts, opts.Validate()
+}
+
+// sendTimeout is the request timeout for sending or saving opts, longer
+// when there are files to upload
+func sendTimeout(opts mail.SendOptions) time.Duration {
+	if len(opts.Attachments) > 0 {
+		return 5 * time.Minute
+	}
+	return 30 * time.Second
+}
+
+// composition is a message body gathered from flags, a file, or the
+// editor. An edited message stays on disk until Finish is called without
+// an error, so a failed send does not lose it.
+type composition struct {
+	path string
+}
+
+// Finish removes the edited message after success, or points the user to
+// it after a failure
+func (c *composition) Finish(err error) {
+	if c.path == "" {
+		return
+	}
+	if err == nil {
+		os.Remove(c.path)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Your message was kept in %s\n", c.path)
+}
+
+// composeMessage fills in the body of opts from --body, --body-file, or the
+// editor, and renders Markdown. The editor may also change the recipients
+// and subject. Sending requires recipients and a subject.
+func composeMessage(cmd *cobra.Command, opts *mail.SendOptions, sending bool) (*composition, error) {
+	c := &composition{}
+
+	switch {
+	case cmd.Flags().Changed("body"):
+	case mailBodyFile != "":
+		body, err := readBodyFile(mailBodyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Body = body
+	default:
+		if !isTerminal(os.Stdin) {
+			return nil, fmt.Errorf("no body given; use --body or --body-file (- for stdin)")
+		}
+
+		ext := ".eml"
+		if mailMarkdown {
+			ext = ".md"
+		}
+		text, path, err := compose.Edit(compose.Template(compose.Draft{
+			To:      opts.To,
+			Cc:      opts.Cc,
+			Bcc:     opts.Bcc,
+			Subject: opts.Subject,
+			Body:    opts.Body,
+		}), ext)
+		if err != nil {
+			return nil, err
+		}
+		c.path = path
+
+		draft, err := compose.Parse(text)
+		if err != nil {
+			c.Finish(err)
+			return nil, err
+		}
+		if draft.Body == "" {
+			os.Remove(path)
+			return nil, fmt.Errorf("empty message body, aborting")
+		}
+
+		opts.To, opts.Cc, opts.Bcc = draft.To, draft.Cc, draft.Bcc
+		opts.Subject = draft.Subject
+		opts.Body = draft.Body
+	}
+
+	if sending {
+		if len(opts.To)+len(opts.Cc)+len(opts.Bcc) == 0 {
+			err := fmt.Errorf("no recipients; use --to")
+			c.Finish(err)
+			return nil, err
+		}
+		if strings.TrimSpace(opts.Subject) == "" {
+			err := fmt.Errorf("no subject; use --subject")
+			c.Finish(err)
+			return nil, err
+		}
+	}
+
+	if mailMarkdown {
+		html, err := compose.Markdown(opts.Body)
+		if err != nil {
+			c.Finish(err)
+			return nil, err
+		}
+		opts.TextBody = opts.Body
+		opts.Body = html
+		opts.BodyType = "html"
+	}
+
+	return c, nil
+}
+
+// readBodyFile reads a message body from a file, or from stdin for "-"
+func readBodyFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %w", err)
+	}
+	return string(data), nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail drafts flags
+	draftsCount int32
+
+	// mail draft delete flags
+	draftDeleteYes bool
+)
+
+var mailDraftsCmd = &cobra.Command{
+	Use:   "drafts",
+	Short: "List draft emails",
+	Args:  cobra.NoArgs,
+	RunE:  runMailDrafts,
+}
+
+var mailDraftEditCmd = &cobra.Command{
+	Use:   "edit <draft-id>",
+	Short: "Edit a draft",
+	Long: `Change a draft's recipients, subject, or body.
+
+With no field flags, the draft opens in $VISUAL or $EDITOR in the same
+header format as "octl mail send"; an HTML body is shown as HTML. Field
+flags replace just those fields: --to, --cc, and --bcc replace the whole
+list, and --body or --body-file replace the body. --attach adds files to
+the draft's attachments.
+
+With --markdown the draft is recreated with a plain-text alternative
+part, keeping its file attachments, so it gets a new ID.
+
+Examples:
+  octl mail draft edit <draft-id>
+  octl mail draft edit <draft-id> --subject "Updated proposal" --cc boss@example.com
+  octl mail draft edit <draft-id> --body-file proposal.md --markdown
+  octl mail draft edit <draft-id> --attach budget.xlsx`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailDraftEdit,
+}
+
+var mailDraftSendCmd = &cobra.Command{
+	Use:   "send <draft-id>",
+	Short: "Send a draft",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMailDraftSend,
+}
+
+var mailDraftDeleteCmd = &cobra.Command{
+	Use:   "delete <draft-id>",
+	Short: "Delete a draft",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMailDraftDelete,
+}
+
+func init() {
+	mailCmd.AddCommand(mailDraftsCmd)
+	mailDraftCmd.AddCommand(mailDraftEditCmd)
+	mailDraftCmd.AddCommand(mailDraftSendCmd)
+	mailDraftCmd.AddCommand(mailDraftDeleteCmd)
+
+	mailDraftsCmd.Flags().Int32VarP(&draftsCount, "count", "n", 25, "Number of drafts to list")
+
+	mailDraftEditCmd.Flags().StringArrayVar(&mailTo, "to", nil, "Replace the recipients")
+	mailDraftEditCmd.Flags().StringArrayVar(&mailCc, "cc", nil, "Replace the CC recipients")
+	mailDraftEditCmd.Flags().StringArrayVar(&mailBcc, "bcc", nil, "Replace the BCC recipients")
+	mailDraftEditCmd.Flags().StringArrayVar(&mailReplyTo, "reply-to", nil, "Replace the reply-to addresses")
+	mailDraftEditCmd.Flags().StringVar(&mailSubject, "subject", "", "Replace the subject")
+	mailDraftEditCmd.Flags().StringVar(&mailBody, "body", "", "Replace the body")
+	mailDraftEditCmd.Flags().BoolVar(&mailHTML, "html", false, "Body is HTML")
+	bindOptionFlags(mailDraftEditCmd)
+	bindComposeFlags(mailDraftEditCmd)
+
+	mailDraftDeleteCmd.Flags().BoolVarP(&draftDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+func runMailDrafts(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	drafts, err := mail.ListMessages(ctx, client.Graph(), mail.ListOptions{
+		Top:      draftsCount,
+		FolderID: "drafts",
+	})
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(drafts)
+	}
+
+	if len(drafts) == 0 {
+		fmt.Println("No drafts")
+		return nil
+	}
+
+	table := output.NewTable("ID", "TO", "SUBJECT", "DATE")
+	for _, d := range drafts {
+		to := strings.Join(d.To, ", ")
+		if to == "" {
+			to = "(none)"
+		}
+		table.AddRow(d.ID, truncate(to, 30), d.FormatSubject(50), d.FormatDate())
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runMailDraftEdit(cmd *cobra.Command, args []string) error {
+	changes, err := flagSendOptions()
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	draft, err := mail.GetDraft(ctx, client.Graph(), args[0])
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	opts := draft.DraftOptions()
+	opts.Importance = changes.Importance
+	opts.Sensitivity = changes.Sensitivity
+
+	flags := cmd.Flags()
+	if flags.Changed("to") {
+		opts.To = changes.To
+	}
+	if flags.Changed("cc") {
+		opts.Cc = changes.Cc
+	}
+	if flags.Changed("bcc") {
+		opts.Bcc = changes.Bcc
+	}
+	if flags.Changed("reply-to") {
+		opts.ReplyTo = changes.ReplyTo
+	}
+	if flags.Changed("subject") {
+		opts.Subject = mailSubject
+	}
+	if flags.Changed("request-read-receipt") {
+		opts.ReadReceipt = mailReadReceipt
+	}
+	if flags.Changed("request-delivery-receipt") {
+		opts.DeliveryReceipt = mailDeliveryReceipt
+	}
+	opts.Attachments = changes.Attachments
+
+	newBody := flags.Changed("body") || flags.Changed("body-file")
+	if newBody {
+		opts.Body = mailBody
+		opts.BodyType = "text"
+		if mailHTML {
+			opts.BodyType = "html"
+		}
+	}
+
+	// Open the editor unless fields were given on the command line
+	editing := !newBody
+	for _, name := range []string{
+		"to", "cc", "bcc", "reply-to", "subject", "importance", "sensitivity",
+		"request-read-receipt", "request-delivery-receipt", "attach",
+	} {
+		if flags.Changed(name) {
+			editing = false
+		}
+	}
+
+	composed := &composition{}
+	if newBody || editing {
+		if composed, err = composeMessage(cmd, &opts, false); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), sendTimeout(opts))
+	defer cancel()
+
+	updated, err := mail.UpdateDraft(ctx, client.Graph(), args[0], opts)
+	composed.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(updated)
+	}
+
+	fmt.Printf("Draft updated: %s\n", updated.Subject)
+	// A Markdown body replaces the draft, which gives it a new ID
+	if updated.ID != args[0] {
+		fmt.Printf("New draft ID: %s\n", updated.ID)
+	}
+	return nil
+}
+
+func runMailDraftSend(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	draft, err := mail.GetDraft(ctx, client.Graph(), args[0])
+	if err != nil {
+		return err
+	}
+	if len(draft.To)+len(draft.Cc)+len(draft.Bcc) == 0 {
+		return fmt.Errorf("draft has no recipients; add some with 'octl mail draft edit %s --to ...'", args[0])
+	}
+
+	if err := mail.SendDraft(ctx, client.Graph(), args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Sent: %s\n", draft.Subject)
+	return nil
+}
+
+func runMailDraftDelete(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	draft, err := mail.GetDraft(ctx, client.Graph(), args[0])
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if !draftDeleteYes && !confirm(fmt.Sprintf("Delete draft %q?", draft.Subject)) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	// The prompt may have outlasted the lookup's timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mail.DeleteMessage(ctx, client.Graph(), args[0]); err != nil {
+		return err
+	}
+
+	fmt.Println("Draft deleted")
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/export"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail export flags
+	exportQuery       string
+	exportFolder      string
+	exportFormat      string
+	exportOutput      string
+	exportNameTmpl    string
+	exportConcurrency int
+	exportLimit       int
+)
+
+var mailExportCmd = &cobra.Command{
+	Use:   "export [message-id...]",
+	Short: "Export messages as .eml files or an mbox",
+	Long: `Export messages with their full MIME content, including all headers,
+alternative parts, and attachments.
+
+Messages can be given by ID or selected with --query and/or --folder.
+Each message is written to its own .eml file, or all messages are appended
+to a single mbox file with --format mbox.
+
+Bulk exports keep a checkpoint next to the output, so running the same
+export again after an interruption only downloads what is missing. An
+mbox is first cut back to the last message the checkpoint recorded, so a
+message half-written during a crash is not duplicated.
+
+File names for .eml files come from --name-template, a Go template with
+the fields .ID, .ShortID, .Subject, .From, .Date and .Time.
+
+Examples:
+  octl mail export <message-id> --output ./mail
+  octl mail export --folder "Inbox/Projects/Acme" --output ./acme
+  octl mail export --query "invoice" --format mbox --output invoices.mbox
+  octl mail export --folder archive --name-template "{{.From}}-{{.ShortID}}.eml"`,
+	RunE: runMailExport,
+}
+
+func init() {
+	mailCmd.AddCommand(mailExportCmd)
+
+	mailExportCmd.Flags().StringVarP(&exportQuery, "query", "q", "", "Export messages matching a search query")
+	mailExportCmd.Flags().StringVarP(&exportFolder, "folder", "f", "", "Export messages from a folder (ID, well-known name, or path)")
+	mailExportCmd.Flags().StringVar(&exportFormat, "format", export.FormatEML, "Output format: eml or mbox")
+	mailExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output directory (eml) or file (mbox)")
+	mailExportCmd.Flags().StringVar(&exportNameTmpl, "name-template", export.DefaultNameTemplate, "File name template for .eml files")
+	mailExportCmd.Flags().IntVar(&exportConcurrency, "concurrency", 4, "Maximum parallel downloads")
+	mailExportCmd.Flags().IntVar(&exportLimit, "limit", 0, "Maximum number of messages to export (0 for all)")
+}
+
+func runMailExport(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && exportQuery == "" && exportFolder == "" {
+		return fmt.Errorf("give message IDs or select messages with --query or --folder")
+	}
+
+	if exportOutput == "" {
+		if exportFormat == export.FormatMbox {
+			exportOutput = "export.mbox"
+		} else {
+			exportOutput = "."
+		}
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ids := args
+	if exportQuery != "" || exportFolder != "" {
+		folderID, err := resolveFolder(ctx, client, exportFolder)
+		if err != nil {
+			return err
+		}
+
+		opts := mail.ListOptions{
+			Top:      100,
+			FolderID: folderID,
+		}
+		if exportQuery != "" {
+			opts.Search = fmt.Sprintf("\"%s\"", exportQuery)
+		}
+
+		messages, err := mail.ListAllMessages(ctx, client.Graph(), opts, exportLimit)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		fmt.Println("No messages found")
+		return nil
+	}
+
+	fetch := func(ctx context.Context, id string) ([]byte, error) {
+		return mail.GetMessageMIME(ctx, client.Graph(), id)
+	}
+
+	opts := export.Options{
+		Format:       exportFormat,
+		Output:       exportOutput,
+		NameTemplate: exportNameTmpl,
+		Concurrency:  exportConcurrency,
+	}
+
+	result, runErr := export.Run(ctx, fetch, ids, opts)
+	if result == nil {
+		return runErr
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		if err := output.New(format).Print(result); err != nil {
+			return err
+		}
+		return runErr
+	}
+
+	for _, f := range result.Failed {
+		PrintError("%s: %s", f.ID, f.Error)
+	}
+	fmt.Printf("Exported %d, skipped %d, failed %d → %s\n", result.Exported, result.Skipped, len(result.Failed), result.Output)
+
+	if runErr != nil {
+		return runErr
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d message(s) failed to export; run again to retry", len(result.Failed))
+	}
+	return nil
+}
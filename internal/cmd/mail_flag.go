@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail flag flags
+	flagDue      string
+	flagClear    bool
+	flagComplete bool
+
+	// mail categorize flags
+	categorizeAdd    []string
+	categorizeRemove []string
+)
+
+var mailFlagCmd = &cobra.Command{
+	Use:   "flag <message-id>",
+	Short: "Flag a message for follow-up",
+	Long: `Flag a message for follow-up, mark the flag complete, or clear it.
+
+The due date accepts YYYY-MM-DD, RFC 3339, today, tomorrow, or a delay
+such as 3d or 2w. Dates without a time are due at 17:00.
+
+Examples:
+  octl mail flag <message-id>
+  octl mail flag <message-id> --due tomorrow
+  octl mail flag <message-id> --complete
+  octl mail flag <message-id> --clear`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailFlag,
+}
+
+var mailCategorizeCmd = &cobra.Command{
+	Use:   "categorize <message-id>",
+	Short: "Add or remove message categories",
+	Long: `Add or remove categories on a message. Category names are matched
+case-insensitively.
+
+Examples:
+  octl mail categorize <message-id> --add "Project X" --add Travel
+  octl mail categorize <message-id> --remove Travel`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailCategorize,
+}
+
+func init() {
+	mailCmd.AddCommand(mailFlagCmd)
+	mailCmd.AddCommand(mailCategorizeCmd)
+
+	mailFlagCmd.Flags().StringVar(&flagDue, "due", "", "Due date for the follow-up")
+	mailFlagCmd.Flags().BoolVar(&flagClear, "clear", false, "Remove the flag")
+	mailFlagCmd.Flags().BoolVar(&flagComplete, "complete", false, "Mark the flag complete")
+	mailFlagCmd.MarkFlagsMutuallyExclusive("clear", "complete", "due")
+
+	mailCategorizeCmd.Flags().StringSliceVar(&categorizeAdd, "add", nil, "Category to add (repeatable)")
+	mailCategorizeCmd.Flags().StringSliceVar(&categorizeRemove, "remove", nil, "Category to remove (repeatable)")
+	mailCategorizeCmd.MarkFlagsOneRequired("add", "remove")
+}
+
+func runMailFlag(cmd *cobra.Command, args []string) error {
+	status := mail.FlagFlagged
+	switch {
+	case flagClear:
+		status = mail.FlagNotFlagged
+	case flagComplete:
+		status = mail.FlagComplete
+	}
+
+	due, err := mail.ParseDueDate(flagDue, time.Now())
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mail.SetFlag(ctx, client.Graph(), args[0], status, due); err != nil {
+		return err
+	}
+
+	switch status {
+	case mail.FlagNotFlagged:
+		fmt.Println("Flag cleared")
+	case mail.FlagComplete:
+		fmt.Println("Flag marked complete")
+	default:
+		if due.IsZero() {
+			fmt.Println("Message flagged")
+		} else {
+			fmt.Printf("Message flagged, due %s\n", due.Local().Format("Mon Jan 2 15:04"))
+		}
+	}
+	return nil
+}
+
+func runMailCategorize(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	categories, err := mail.UpdateCategories(ctx, client.Graph(), args[0], categorizeAdd, categorizeRemove)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(categories)
+	}
+
+	if len(categories) == 0 {
+		fmt.Println("Categories: (none)")
+		return nil
+	}
+	fmt.Printf("Categories: %s\n", strings.Join(categories, ", "))
+	return nil
+}
+
+// formatFlag describes a follow-up flag for display
+func formatFlag(f *mail.Flag) string {
+	switch {
+	case f.Status == mail.FlagComplete && f.CompletedAt != nil:
+		return "complete " + f.CompletedAt.Local().Format("2006-01-02")
+	case f.DueAt != nil:
+		return f.Status + ", due " + f.DueAt.Local().Format("2006-01-02 15:04")
+	}
+	return f.Status
+}
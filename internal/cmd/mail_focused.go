@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail classify flags
+	classifyFocused bool
+	classifyOther   bool
+	classifyAlways  bool
+
+	// mail overrides delete flags
+	overrideDeleteYes bool
+)
+
+var mailClassifyCmd = &cobra.Command{
+	Use:   "classify <message-id>",
+	Short: "Move a message between the Focused and Other tabs",
+	Long: `Move a message to the Focused or Other tab of the Focused Inbox.
+
+--always also adds an override so that future mail from the sender is
+classified the same way; an existing override for the sender is updated.
+
+Examples:
+  octl mail classify <message-id> --other
+  octl mail classify <message-id> --focused --always`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailClassify,
+}
+
+var mailOverridesCmd = &cobra.Command{
+	Use:   "overrides",
+	Short: "Manage Focused Inbox sender overrides",
+	Long: `List and delete the senders whose mail always goes to the Focused or
+Other tab. Add overrides with "octl mail classify --always".`,
+}
+
+var mailOverridesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List Focused Inbox overrides",
+	Args:  cobra.NoArgs,
+	RunE:  runMailOverridesList,
+}
+
+var mailOverridesDeleteCmd = &cobra.Command{
+	Use:   "delete <override-id|address>",
+	Short: "Delete a Focused Inbox override",
+	Long: `Delete a Focused Inbox override by its ID or the sender's address.
+Mail from the sender is then classified automatically again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailOverridesDelete,
+}
+
+func init() {
+	mailCmd.AddCommand(mailClassifyCmd)
+	mailCmd.AddCommand(mailOverridesCmd)
+	mailOverridesCmd.AddCommand(mailOverridesListCmd)
+	mailOverridesCmd.AddCommand(mailOverridesDeleteCmd)
+
+	mailClassifyCmd.Flags().BoolVar(&classifyFocused, "focused", false, "Move the message to the Focused tab")
+	mailClassifyCmd.Flags().BoolVar(&classifyOther, "other", false, "Move the message to the Other tab")
+	mailClassifyCmd.Flags().BoolVar(&classifyAlways, "always", false, "Classify future mail from the sender the same way")
+	mailClassifyCmd.MarkFlagsOneRequired("focused", "other")
+	mailClassifyCmd.MarkFlagsMutuallyExclusive("focused", "other")
+
+	mailOverridesDeleteCmd.Flags().BoolVarP(&overrideDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+func runMailClassify(cmd *cobra.Command, args []string) error {
+	classification := mail.ClassificationFocused
+	if classifyOther {
+		classification = mail.ClassificationOther
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	msg, err := mail.ClassifyMessage(ctx, client.Graph(), args[0], classification)
+	if err != nil {
+		return err
+	}
+
+	var override *mail.Override
+	if classifyAlways {
+		sender, err := mail.ParseAddress(msg.From)
+		if err != nil {
+			return fmt.Errorf("message has no sender to add an override for")
+		}
+		if override, err = mail.SetOverride(ctx, client.Graph(), sender.Name, sender.Address, classification); err != nil {
+			return err
+		}
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(struct {
+			Message  *mail.Message  `json:"message"`
+			Override *mail.Override `json:"override,omitempty"`
+		}{msg, override})
+	}
+
+	fmt.Printf("Moved to %s: %s\n", classification, msg.FormatSubject(60))
+	if override != nil {
+		fmt.Printf("Future mail from %s will go to %s\n", override.Address, override.ClassifyAs)
+	}
+	return nil
+}
+
+func runMailOverridesList(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	overrides, err := mail.ListOverrides(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(overrides)
+	}
+
+	if len(overrides) == 0 {
+		fmt.Println("No Focused Inbox overrides")
+		return nil
+	}
+
+	table := output.NewTable("ID", "SENDER", "NAME", "CLASSIFY AS")
+	for _, o := range overrides {
+		table.AddRow(o.ID, o.Address, truncate(o.Name, 30), o.ClassifyAs)
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runMailOverridesDelete(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	id, sender := args[0], args[0]
+	if strings.Contains(args[0], "@") {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		overrides, err := mail.ListOverrides(ctx, client.Graph())
+		cancel()
+		if err != nil {
+			return err
+		}
+		override := mail.FindOverride(overrides, args[0])
+		if override == nil {
+			return fmt.Errorf("no override for %s", args[0])
+		}
+		id, sender = override.ID, override.Address
+	}
+
+	if !overrideDeleteYes && !confirm(fmt.Sprintf("Delete the override for %s?", sender)) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	// Start the timeout after the prompt so a slow answer cannot expire it
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mail.DeleteOverride(ctx, client.Graph(), id); err != nil {
+		return err
+	}
+
+	fmt.Println("Override deleted")
+	return nil
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail folder create flags
+	folderIfNotExists bool
+
+	// mail folder delete flags
+	folderDeleteYes bool
+)
+
+var mailFolderCmd = &cobra.Command{
+	Use:   "folder",
+	Short: "Manage mail folders",
+	Long: `Create, rename, move, and delete mail folders.
+
+Folders can be given as a folder ID, a well-known name (inbox, archive, ...),
+or a path of folder names such as "Inbox/Projects/Acme".`,
+}
+
+var mailFolderCreateCmd = &cobra.Command{
+	Use:   "create <path>",
+	Short: "Create a mail folder",
+	Long: `Create a mail folder, creating any missing parent folders.
+
+Examples:
+  octl mail folder create "Inbox/Projects/Acme"
+
+  # Succeed without changes if the folder already exists
+  octl mail folder create "Inbox/Projects/Acme" --if-not-exists`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailFolderCreate,
+}
+
+var mailFolderRenameCmd = &cobra.Command{
+	Use:   "rename <folder> <new-name>",
+	Short: "Rename a mail folder",
+	Long:  `Change the display name of a mail folder.`,
+	Args:  cobra.ExactArgs(2),
+	RunE:  runMailFolderRename,
+}
+
+var mailFolderMoveCmd = &cobra.Command{
+	Use:   "move <folder> <new-parent>",
+	Short: "Move a mail folder under a new parent",
+	Long: `Move a mail folder under a different parent folder.
+
+Use "/" as the new parent to move the folder to the top level.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runMailFolderMove,
+}
+
+var mailFolderDeleteCmd = &cobra.Command{
+	Use:   "delete <folder>",
+	Short: "Delete a mail folder",
+	Long:  `Delete a mail folder along with its messages and child folders.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMailFolderDelete,
+}
+
+func init() {
+	mailCmd.AddCommand(mailFolderCmd)
+	mailFolderCmd.AddCommand(mailFolderCreateCmd)
+	mailFolderCmd.AddCommand(mailFolderRenameCmd)
+	mailFolderCmd.AddCommand(mailFolderMoveCmd)
+	mailFolderCmd.AddCommand(mailFolderDeleteCmd)
+
+	// mail folder create flags
+	mailFolderCreateCmd.Flags().BoolVar(&folderIfNotExists, "if-not-exists", false, "Do nothing if the folder already exists")
+
+	// mail folder delete flags
+	mailFolderDeleteCmd.Flags().BoolVarP(&folderDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+func runMailFolderCreate(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	folder, created, err := mail.CreateFolderPath(ctx, client.Graph(), path, folderIfNotExists)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(folder)
+	}
+
+	if created {
+		fmt.Printf("Folder created: %s\n", path)
+	} else {
+		fmt.Printf("Folder already exists: %s\n", path)
+	}
+	fmt.Printf("ID: %s\n", folder.ID)
+	return nil
+}
+
+func runMailFolderRename(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	folderID, err := resolveFolder(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	folder, err := mail.RenameFolder(ctx, client.Graph(), folderID, args[1])
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(folder)
+	}
+
+	fmt.Printf("Folder renamed to: %s\n", folder.DisplayName)
+	return nil
+}
+
+func runMailFolderMove(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	folderID, err := resolveFolder(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	destinationID := mail.FolderRoot
+	if args[1] != "/" {
+		destinationID, err = resolveFolder(ctx, client, args[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	folder, err := mail.MoveFolder(ctx, client.Graph(), folderID, destinationID)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(folder)
+	}
+
+	fmt.Printf("Folder moved: %s\n", folder.DisplayName)
+	return nil
+}
+
+func runMailFolderDelete(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	folderID, err := resolveFolder(ctx, client, args[0])
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if mail.IsWellKnownFolder(folderID) {
+		return fmt.Errorf("refusing to delete well-known folder: %s", args[0])
+	}
+
+	if !folderDeleteYes && !confirm(fmt.Sprintf("Delete folder %q and everything in it?", args[0])) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	// The prompt may have outlasted the lookup's timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mail.DeleteFolder(ctx, client.Graph(), folderID); err != nil {
+		return err
+	}
+
+	fmt.Println("Folder deleted")
+	return nil
+}
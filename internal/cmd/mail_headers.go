@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var mailHeadersCmd = &cobra.Command{
+	Use:   "headers <message-id>",
+	Short: "Show a parsed view of a message's headers",
+	Long: `Show the headers that matter when tracing a delivery problem: the
+Received chain oldest first with the delay at each hop, Message-ID and
+threading headers, List-* headers, and the Exchange Online Protection
+anti-spam verdicts.
+
+Use "octl mail read --headers" for the full unparsed list.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailHeaders,
+}
+
+func init() {
+	mailCmd.AddCommand(mailHeadersCmd)
+}
+
+func runMailHeaders(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	headers, err := mail.GetHeaders(ctx, client.Graph(), args[0])
+	if err != nil {
+		return err
+	}
+	if len(headers) == 0 {
+		return fmt.Errorf("message has no internet headers (drafts and some sent items have none)")
+	}
+
+	summary := mail.SummarizeHeaders(headers)
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(summary)
+	}
+
+	fmt.Printf("Message-ID:  %s\n", orNone(summary.MessageID))
+	if summary.InReplyTo != "" {
+		fmt.Printf("In-Reply-To: %s\n", summary.InReplyTo)
+	}
+	for i, ref := range summary.References {
+		label := ""
+		if i == 0 {
+			label = "References:"
+		}
+		fmt.Printf("%-12s %s\n", label, ref)
+	}
+
+	fmt.Println()
+	fmt.Printf("Received chain (%d hops, %s in transit):\n", len(summary.Received), formatDelay(summary.Transit()))
+	table := output.NewTable("HOP", "TIME", "DELAY", "FROM", "BY", "WITH")
+	for i, hop := range summary.Received {
+		at, delay := "-", "-"
+		if !hop.At.IsZero() {
+			at = hop.At.Local().Format("2006-01-02 15:04:05")
+			if i > 0 {
+				delay = formatDelay(hop.Delay)
+			}
+		}
+		table.AddRow(fmt.Sprint(i+1), at, delay, orNone(hop.From), orNone(hop.By), hop.With)
+	}
+	if err := table.Render(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+
+	if len(summary.List) > 0 {
+		fmt.Println()
+		fmt.Println("List headers:")
+		for _, h := range summary.List {
+			fmt.Printf("  %s: %s\n", h.Name, h.Value)
+		}
+	}
+
+	if len(summary.AntiSpam) > 0 {
+		fmt.Println()
+		fmt.Println("Anti-spam:")
+		table := output.NewTable("KEY", "VALUE", "MEANING")
+		for _, v := range summary.AntiSpam {
+			table.AddRow(v.Key, v.Value, v.Meaning)
+		}
+		var b strings.Builder
+		if err := table.Render(&b); err != nil {
+			return err
+		}
+		fmt.Println(indent(b.String()))
+	}
+
+	return nil
+}
+
+// formatDelay shows a hop delay to the second, or below a second as "<1s"
+func formatDelay(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "-" + formatDelay(-d) + " (clock skew)"
+	case d < time.Second:
+		return "<1s"
+	}
+	return d.Round(time.Second).String()
+}
+
+// orNone shows an empty value as "(none)"
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
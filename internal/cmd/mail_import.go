@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail import flags
+	importFolder   string
+	importDryRun   bool
+	importMarkRead bool
+)
+
+var mailImportCmd = &cobra.Command{
+	Use:   "import <file|dir>...",
+	Short: "Import .eml files into a mail folder",
+	Long: `Import MIME messages (.eml files) into a mail folder.
+
+Directories are searched recursively for .eml files and Maildir messages.
+Read and flag state come from Status/X-Status headers or Maildir flags.
+
+Graph turns messages uploaded as raw MIME into drafts, so each file is
+converted instead: recipients, body, and attachments are taken from the
+MIME parts, and the message is created as received mail with its original
+received and sent dates and internet headers. Parts that do not convert
+cleanly, such as text in an unknown character set, are reported as
+warnings for that file.
+
+Use --dry-run to parse and validate the files without uploading anything.
+
+Examples:
+  octl mail import archive/*.eml --folder "Archive/Old Provider"
+  octl mail import ~/Maildir/old --folder "Archive/Old Provider" --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMailImport,
+}
+
+func init() {
+	mailCmd.AddCommand(mailImportCmd)
+
+	mailImportCmd.Flags().StringVarP(&importFolder, "folder", "f", "", "Destination folder (ID, well-known name, or path)")
+	mailImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Parse and validate files without uploading")
+	mailImportCmd.Flags().BoolVar(&importMarkRead, "mark-read", false, "Mark every imported message as read")
+	_ = mailImportCmd.MarkFlagRequired("folder")
+}
+
+// importResult is the per-file outcome of an import
+type importResult struct {
+	Path     string   `json:"path"`
+	Subject  string   `json:"subject,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func runMailImport(cmd *cobra.Command, args []string) error {
+	files, err := collectImportFiles(args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no .eml files found")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var folderID string
+	var importMessage func(item *mail.ImportItem) (*mail.Message, []string, error)
+	if !importDryRun {
+		client, err := getGraphClient()
+		if err != nil {
+			return err
+		}
+
+		folderID, err = resolveFolder(ctx, client, importFolder)
+		if err != nil {
+			return err
+		}
+
+		importMessage = func(item *mail.ImportItem) (*mail.Message, []string, error) {
+			return mail.ImportMessage(ctx, client.Graph(), folderID, item)
+		}
+	}
+
+	results := make([]importResult, 0, len(files))
+	failed := 0
+	for _, path := range files {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := importResult{Path: path}
+
+		item, err := mail.ParseImportFile(path)
+		if err == nil {
+			result.Subject = item.Subject
+			if importMarkRead {
+				item.IsRead = true
+			}
+
+			if importDryRun {
+				result.Status = "valid"
+			} else {
+				var msg *mail.Message
+				msg, result.Warnings, err = importMessage(item)
+				if err == nil {
+					result.ID = msg.ID
+					result.Status = "imported"
+				}
+			}
+		}
+
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			failed++
+		}
+
+		results = append(results, result)
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		if err := output.New(format).Print(results); err != nil {
+			return err
+		}
+	} else {
+		table := output.NewTable("STATUS", "FILE", "SUBJECT", "DETAIL")
+		for _, r := range results {
+			detail := r.Error
+			if detail == "" {
+				detail = strings.Join(r.Warnings, "; ")
+			}
+			table.AddRow(r.Status, r.Path, truncate(r.Subject, 40), detail)
+		}
+
+		if format == "plain" {
+			if err := output.New(format).Print(table.ToPlain()); err != nil {
+				return err
+			}
+		} else {
+			if err := table.Render(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			fmt.Printf("\n%d file(s), %d failed\n", len(results), failed)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) failed to import", failed)
+	}
+	return nil
+}
+
+// collectImportFiles expands directories into the message files they hold
+func collectImportFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			parent := filepath.Base(filepath.Dir(path))
+			if strings.EqualFold(filepath.Ext(path), ".eml") || parent == "cur" || parent == "new" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
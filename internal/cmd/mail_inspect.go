@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/inspect"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/mailbox"
+	"github.com/pp/octl/internal/output"
+)
+
+var mailInspectCmd = &cobra.Command{
+	Use:   "inspect <message-id>",
+	Short: "Check a message for signs of phishing",
+	Long: `Score how suspicious a message is. Everything is computed locally from
+the message's headers, body, and attachment list; no links are visited
+and nothing is sent to an outside service.
+
+Checks:
+  - SPF, DKIM, DMARC, and composite authentication verdicts from
+    Authentication-Results (or ARC headers for forwarded mail)
+  - a display name showing a different address or domain than the sender's
+  - Reply-To and Return-Path domains that differ from the From domain
+  - lookalike domains imitating yours or commonly impersonated brands
+  - links whose text names a different site than they lead to
+  - risky attachment types, double extensions, and mismatched types
+
+A score of 3 or more is medium risk and 6 or more is high.
+
+Examples:
+  octl mail inspect <message-id>
+  octl mail inspect <message-id> --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailInspect,
+}
+
+func init() {
+	mailCmd.AddCommand(mailInspectCmd)
+}
+
+func runMailInspect(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	msg, err := mail.GetMessage(ctx, client.Graph(), args[0])
+	if err != nil {
+		return err
+	}
+	headers, err := mail.GetHeaders(ctx, client.Graph(), args[0])
+	if err != nil {
+		return err
+	}
+
+	input := inspect.Message{
+		Headers:  headers,
+		Body:     msg.Body,
+		BodyType: msg.BodyContentType,
+	}
+	if msg.HasAttachments {
+		if input.Attachments, err = mail.ListAttachments(ctx, client.Graph(), args[0]); err != nil {
+			return err
+		}
+	}
+	// Lookalikes of the user's own domains are the most telling, but the
+	// check still runs against well-known brands without them
+	if profile, err := mailbox.GetProfile(ctx, client.Graph()); err == nil {
+		input.OwnDomains = profileDomains(profile)
+	}
+
+	report := inspect.Inspect(input)
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(report)
+	}
+
+	table := output.NewTable("CHECK", "RESULT", "SCORE", "DETAIL")
+	for _, f := range report.Findings {
+		score := ""
+		if f.Score > 0 {
+			score = fmt.Sprintf("+%d", f.Score)
+		}
+		table.AddRow(f.Check, f.Status, score, f.Detail)
+	}
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	fmt.Printf("Subject:     %s\n", msg.Subject)
+	fmt.Printf("From:        %s\n", orNone(report.From))
+	if len(report.ReplyTo) > 0 {
+		fmt.Printf("Reply-To:    %s\n", strings.Join(report.ReplyTo, ", "))
+	}
+	if report.ReturnPath != "" {
+		fmt.Printf("Return-Path: %s\n", report.ReturnPath)
+	}
+	fmt.Println()
+
+	if err := table.Render(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Risk: %s (score %d)\n", report.Risk, report.Score)
+	return nil
+}
+
+// profileDomains lists the domains of the user's addresses
+func profileDomains(p *mailbox.Profile) []string {
+	seen := map[string]bool{}
+	var domains []string
+	addrs := append([]string{p.Mail, p.UserPrincipalName}, p.ProxyAddresses...)
+	for _, addr := range addrs {
+		// Proxy addresses carry a type prefix such as "SMTP:"
+		if typ, rest, ok := strings.Cut(addr, ":"); ok && !strings.Contains(typ, "@") {
+			if !strings.EqualFold(typ, "smtp") {
+				continue
+			}
+			addr = rest
+		}
+		_, domain, ok := strings.Cut(addr, "@")
+		domain = strings.ToLower(domain)
+		// Tenant routing domains are not what senders imitate
+		if !ok || domain == "" || seen[domain] || strings.HasSuffix(domain, ".onmicrosoft.com") {
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+	return domains
+}
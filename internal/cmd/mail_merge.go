@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/config"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/merge"
+)
+
+var (
+	// mail merge flags
+	mergeTemplate string
+	mergeData     string
+	mergeDraft    bool
+	mergeDryRun   bool
+	mergeOut      string
+	mergeInterval time.Duration
+	mergeLog      string
+	mergeMarkdown bool
+	mergeYes      bool
+)
+
+var mailMergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Send personalised messages from a template",
+	Long: `Render a template once per recipient row and send the results.
+
+The template uses the editor's format: To, Cc, Bcc and Subject headers, a
+blank line, then the body. The whole file is a Go text/template executed
+with each row, so {{.name}} inserts the row's name column (use
+{{index . "first name"}} for names with spaces). Without a To header the
+row's email column is the recipient. Every row is rendered before anything
+is sent, and referencing a missing column is an error.
+
+Data is a .csv file with a header line, or a .json array of objects.
+Templates ending in .md, or any template with --markdown, are rendered to
+HTML, with the Markdown source kept as a plain-text alternative.
+
+Messages are sent --interval apart. Each send is recorded in a log in the
+data directory, keyed by the rendered message, so rerunning the same merge
+after a failure or crash sends only what is left. A message that was in
+flight when a run died is skipped with a warning rather than risk sending
+it twice; check Sent Items and remove its log entry to retry it. Drafts
+and sends are logged separately, so a --draft run followed by a real run
+sends every message.
+
+Examples:
+  octl mail merge --template status.md --data customers.csv --dry-run
+  octl mail merge --template status.md --data customers.csv --dry-run --out preview/
+  octl mail merge --template status.md --data customers.csv --draft
+  octl mail merge --template status.md --data customers.csv --interval 5s`,
+	RunE: runMailMerge,
+}
+
+func init() {
+	mailCmd.AddCommand(mailMergeCmd)
+
+	mailMergeCmd.Flags().StringVarP(&mergeTemplate, "template", "t", "", "Template file")
+	mailMergeCmd.Flags().StringVarP(&mergeData, "data", "d", "", "Recipient data (.csv or .json)")
+	mailMergeCmd.Flags().BoolVar(&mergeDraft, "draft", false, "Create drafts instead of sending")
+	mailMergeCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false, "Render the messages without sending")
+	mailMergeCmd.Flags().StringVar(&mergeOut, "out", "", "With --dry-run, write one file per message to this directory")
+	mailMergeCmd.Flags().DurationVar(&mergeInterval, "interval", 2*time.Second, "Minimum time between messages")
+	mailMergeCmd.Flags().StringVar(&mergeLog, "log", "", "Merge log path (default: in the data directory)")
+	mailMergeCmd.Flags().BoolVar(&mergeMarkdown, "markdown", false, "Render the body from Markdown to HTML")
+	mailMergeCmd.Flags().BoolVarP(&mergeYes, "yes", "y", false, "Skip the confirmation prompt")
+	_ = mailMergeCmd.MarkFlagRequired("template")
+	_ = mailMergeCmd.MarkFlagRequired("data")
+}
+
+func runMailMerge(cmd *cobra.Command, args []string) error {
+	if mergeOut != "" && !mergeDryRun {
+		return fmt.Errorf("--out requires --dry-run")
+	}
+
+	text, err := os.ReadFile(mergeTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := merge.ParseTemplate(filepath.Base(mergeTemplate), string(text))
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(mergeTemplate))
+	tmpl.Markdown = mergeMarkdown || ext == ".md" || ext == ".markdown"
+
+	rows, err := merge.LoadData(mergeData)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no rows in %s", mergeData)
+	}
+
+	msgs, err := tmpl.RenderAll(rows)
+	if err != nil {
+		return err
+	}
+
+	if mergeDryRun {
+		return printMergePreview(msgs)
+	}
+
+	logPath := mergeLog
+	if logPath == "" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		logPath = filepath.Join(dir, "merge", mergeLogName(mergeTemplate, mergeData))
+	}
+
+	log, err := merge.OpenLog(logPath)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	mode := merge.ModeSend
+	if mergeDraft {
+		mode = merge.ModeDraft
+	}
+	pending := 0
+	for _, msg := range msgs {
+		if status := log.Status(mode, msg.Key()); status == "" || status == merge.StatusFailed {
+			pending++
+		}
+	}
+	if pending == 0 {
+		fmt.Printf("All %d messages were already delivered (log: %s)\n", len(msgs), logPath)
+		return nil
+	}
+
+	action := "Send"
+	if mergeDraft {
+		action = "Create drafts for"
+	}
+	if !mergeYes && !confirm(fmt.Sprintf("%s %d of %d messages?", action, pending, len(msgs))) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := &merge.Merger{
+		Log:      log,
+		Draft:    mergeDraft,
+		Interval: mergeInterval,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "%s "+format+"\n", append([]any{time.Now().Format("15:04:05")}, args...)...)
+		},
+		Send: func(ctx context.Context, msg *merge.Message) (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+
+			opts := mail.SendOptions{
+				To:         msg.To,
+				Cc:         msg.Cc,
+				Bcc:        msg.Bcc,
+				Subject:    msg.Subject,
+				Body:       msg.Body,
+				BodyType:   msg.BodyType,
+				TextBody:   msg.TextBody,
+				SaveToSent: true,
+			}
+			if mergeDraft {
+				draft, err := mail.CreateDraft(ctx, client.Graph(), opts)
+				if err != nil {
+					return "", err
+				}
+				return draft.ID, nil
+			}
+			return "", mail.SendMessage(ctx, client.Graph(), opts)
+		},
+	}
+
+	result, err := m.Run(ctx, msgs)
+
+	verb := "Sent"
+	if mergeDraft {
+		verb = "Drafted"
+	}
+	fmt.Printf("%s %d, already done %d, failed %d", verb, result.Delivered, result.Skipped, result.Failed)
+	if result.Uncertain > 0 {
+		fmt.Printf(", interrupted earlier %d", result.Uncertain)
+	}
+	fmt.Printf(" (log: %s)\n", logPath)
+
+	if err != nil {
+		return err
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("%d messages failed; rerun the same command to retry them", result.Failed)
+	}
+	return nil
+}
+
+// printMergePreview writes rendered messages to stdout, or to one file per
+// message with --out
+func printMergePreview(msgs []*merge.Message) error {
+	if mergeOut == "" {
+		for i, msg := range msgs {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("### Row %d\n%s", msg.Row, msg)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(mergeOut, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, msg := range msgs {
+		path := filepath.Join(mergeOut, fmt.Sprintf("row-%03d.eml", msg.Row))
+		if err := os.WriteFile(path, []byte(msg.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write preview: %w", err)
+		}
+	}
+	fmt.Printf("Wrote %d messages to %s\n", len(msgs), mergeOut)
+	return nil
+}
+
+// mergeLogName names the default log after the template and data files,
+// so each merge resumes from its own log
+func mergeLogName(templatePath, dataPath string) string {
+	h := sha256.New()
+	for _, p := range []string{templatePath, dataPath} {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		fmt.Fprintf(h, "%s\x00", p)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:6]) + ".jsonl"
+}
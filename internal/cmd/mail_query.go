@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// queryFlags holds the structured search flags shared by mail list and
+// mail search
+type queryFlags struct {
+	from           string
+	to             string
+	subject        string
+	since          string
+	before         string
+	hasAttachments bool
+	importance     string
+	flagged        bool
+	category       string
+	unread         bool
+	focused        bool
+	other          bool
+	folder         string
+}
+
+var (
+	listQuery   queryFlags
+	searchQuery queryFlags
+)
+
+// bindQueryFlags registers the structured search flags on a command
+func bindQueryFlags(cmd *cobra.Command, q *queryFlags) {
+	cmd.Flags().StringVar(&q.from, "from", "", "Sender address or name")
+	cmd.Flags().StringVar(&q.to, "to", "", "Recipient address or name")
+	cmd.Flags().StringVar(&q.subject, "subject", "", "Subject contains text")
+	cmd.Flags().StringVar(&q.since, "since", "", "Received on or after (YYYY-MM-DD, RFC 3339, or age like 7d)")
+	cmd.Flags().StringVar(&q.before, "before", "", "Received before (YYYY-MM-DD, RFC 3339, or age like 7d)")
+	cmd.Flags().BoolVar(&q.hasAttachments, "has-attachments", false, "Only messages with (or, with =false, without) attachments")
+	cmd.Flags().StringVar(&q.importance, "importance", "", "Importance: low, normal, or high")
+	cmd.Flags().BoolVar(&q.flagged, "flagged", false, "Only flagged (or, with =false, unflagged) messages")
+	cmd.Flags().StringVar(&q.category, "category", "", "Messages with this category")
+	cmd.Flags().BoolVarP(&q.unread, "unread", "u", false, "Only show unread messages")
+	cmd.Flags().BoolVar(&q.focused, "focused", false, "Only messages in the Focused tab (searches the Inbox unless --folder is given)")
+	cmd.Flags().BoolVar(&q.other, "other", false, "Only messages in the Other tab (searches the Inbox unless --folder is given)")
+	cmd.Flags().StringVarP(&q.folder, "folder", "f", "", "Folder to search (ID, well-known name, or path)")
+	cmd.MarkFlagsMutuallyExclusive("focused", "other")
+}
+
+// criteria converts the flag values into query criteria
+func (q *queryFlags) criteria(cmd *cobra.Command) (mail.Criteria, error) {
+	now := time.Now()
+
+	since, err := mail.ParseDateArg(q.since, now)
+	if err != nil {
+		return mail.Criteria{}, fmt.Errorf("--since: %w", err)
+	}
+
+	before, err := mail.ParseDateArg(q.before, now)
+	if err != nil {
+		return mail.Criteria{}, fmt.Errorf("--before: %w", err)
+	}
+
+	c := mail.Criteria{
+		From:       q.from,
+		To:         q.to,
+		Subject:    q.subject,
+		Since:      since,
+		Before:     before,
+		Importance: q.importance,
+		Category:   q.category,
+		Unread:     q.unread,
+	}
+
+	// Tri-state flags only apply when given explicitly
+	if cmd.Flags().Changed("has-attachments") {
+		c.HasAttachments = &q.hasAttachments
+	}
+	if cmd.Flags().Changed("flagged") {
+		c.Flagged = &q.flagged
+	}
+
+	switch {
+	case q.focused:
+		c.Classification = mail.ClassificationFocused
+	case q.other:
+		c.Classification = mail.ClassificationOther
+	}
+
+	return c, nil
+}
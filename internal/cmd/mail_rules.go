@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/graph"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+	"github.com/pp/octl/internal/rules"
+)
+
+var (
+	// mail rules flags
+	rulesDeleteYes bool
+	rulesFile      string
+	rulesDryRun    bool
+	rulesKeepExtra bool
+	rulesApplyYes  bool
+)
+
+var mailRulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage server-side inbox rules",
+	Long: `Manage the inbox rules that the server runs on incoming mail.
+
+Rules can be kept in a YAML file and applied with "mail rules apply".`,
+}
+
+var mailRulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List inbox rules",
+	RunE:  runMailRulesList,
+}
+
+var mailRulesShowCmd = &cobra.Command{
+	Use:   "show <name|id>",
+	Short: "Show an inbox rule",
+	Long: `Show an inbox rule in the rules file format, ready to paste into
+rules.yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailRulesShow,
+}
+
+var mailRulesDeleteCmd = &cobra.Command{
+	Use:   "delete <name|id>",
+	Short: "Delete an inbox rule",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMailRulesDelete,
+}
+
+var mailRulesApplyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Make the inbox rules match a file",
+	Long: `Make the server-side inbox rules match a declared YAML file. Rules are
+matched by name; missing rules are created, changed rules replaced, and
+rules not in the file deleted unless --keep-extra is given.
+
+Conditions (all must match): from, subject_contains, has_attachments.
+Actions: move_to (folder path), categories, mark_read, forward_to.
+Sequence defaults to the rule's position in the file; stop: true stops
+processing later rules.
+
+File format:
+  rules:
+    - name: Newsletters
+      conditions:
+        from: [news@example.com]
+        subject_contains: [newsletter]
+      actions:
+        move_to: Inbox/Newsletters
+        mark_read: true
+    - name: Invoices
+      stop: true
+      conditions:
+        has_attachments: true
+        subject_contains: [invoice]
+      actions:
+        categories: [Finance]
+        forward_to: [accounts@example.com]`,
+	RunE: runMailRulesApply,
+}
+
+func init() {
+	mailCmd.AddCommand(mailRulesCmd)
+	mailRulesCmd.AddCommand(mailRulesListCmd)
+	mailRulesCmd.AddCommand(mailRulesShowCmd)
+	mailRulesCmd.AddCommand(mailRulesDeleteCmd)
+	mailRulesCmd.AddCommand(mailRulesApplyCmd)
+
+	mailRulesDeleteCmd.Flags().BoolVarP(&rulesDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+
+	mailRulesApplyCmd.Flags().StringVarP(&rulesFile, "file", "f", "", "Rules YAML file")
+	mailRulesApplyCmd.Flags().BoolVar(&rulesDryRun, "dry-run", false, "Show the changes without applying them")
+	mailRulesApplyCmd.Flags().BoolVar(&rulesKeepExtra, "keep-extra", false, "Keep rules that are not in the file")
+	mailRulesApplyCmd.Flags().BoolVarP(&rulesApplyYes, "yes", "y", false, "Skip the confirmation prompt for deletions")
+	_ = mailRulesApplyCmd.MarkFlagRequired("file")
+}
+
+func runMailRulesList(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	list, err := rules.List(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(list)
+	}
+
+	if len(list) == 0 {
+		fmt.Println("No rules found")
+		return nil
+	}
+
+	paths := folderPathsByID(ctx, client)
+
+	table := output.NewTable("SEQ", "NAME", "ENABLED", "CONDITIONS", "ACTIONS")
+	for _, r := range list {
+		enabled := "no"
+		if r.Enabled {
+			enabled = "yes"
+		}
+		table.AddRow(
+			fmt.Sprintf("%d", r.Sequence),
+			r.Name,
+			enabled,
+			truncate(describeConditions(r), 40),
+			truncate(describeActions(r, paths), 50),
+		)
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runMailRulesShow(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rule, err := findRule(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(rule)
+	}
+
+	paths := folderPathsByID(ctx, client)
+	data, err := rules.MarshalFile([]rules.FileRule{rules.ToFileRule(*rule, folderPathFunc(paths))})
+	if err != nil {
+		return fmt.Errorf("failed to format rule: %w", err)
+	}
+
+	fmt.Printf("# id: %s\n", rule.ID)
+	for _, u := range rule.Unsupported {
+		fmt.Printf("# not managed by octl: %s\n", u)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+func runMailRulesDelete(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	rule, err := findRule(ctx, client, args[0])
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	if !rulesDeleteYes && !confirm(fmt.Sprintf("Delete rule %q?", rule.Name)) {
+		return fmt.Errorf("aborted")
+	}
+
+	// The prompt may have outlasted the lookup's timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := rules.Delete(ctx, client.Graph(), rule.ID); err != nil {
+		return err
+	}
+
+	fmt.Println("Rule deleted")
+	return nil
+}
+
+func runMailRulesApply(cmd *cobra.Command, args []string) error {
+	file, err := rules.LoadFile(rulesFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	declared, err := file.Resolve(func(ref string) (string, error) {
+		return resolveRuleFolder(ctx, client, ref)
+	})
+	if err != nil {
+		return err
+	}
+
+	current, err := rules.List(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	changes := rules.Plan(current, declared, rulesKeepExtra)
+
+	format := GetOutputFormat()
+	if format == "json" && rulesDryRun {
+		return output.New(format).Print(changes)
+	}
+
+	if len(changes) == 0 {
+		if format == "json" {
+			return output.New(format).Print(changes)
+		}
+		fmt.Println("Rules already match")
+		return nil
+	}
+
+	if format != "json" {
+		printRuleChanges(changes, folderPathsByID(ctx, client))
+	}
+	if rulesDryRun {
+		return nil
+	}
+
+	deletes := 0
+	for _, c := range changes {
+		if c.Action == rules.ActionDelete {
+			deletes++
+		}
+	}
+	if deletes > 0 && !rulesApplyYes && !confirm(fmt.Sprintf("Delete %d rule(s) not in the file?", deletes)) {
+		return fmt.Errorf("aborted")
+	}
+
+	// Start a new timeout after the prompt so a slow answer cannot leave
+	// the rules half-replaced
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	applied, err := rules.Apply(ctx, client.Graph(), changes)
+	if format == "json" {
+		if perr := output.New(format).Print(changes[:applied]); perr != nil {
+			return perr
+		}
+	} else {
+		fmt.Printf("Applied %d of %d change(s)\n", applied, len(changes))
+	}
+	return err
+}
+
+// printRuleChanges prints a rules plan as a diff
+func printRuleChanges(changes []rules.Change, paths map[string]string) {
+	for _, c := range changes {
+		switch c.Action {
+		case rules.ActionCreate:
+			fmt.Printf("+ %s\n", c.Rule.Name)
+			fmt.Printf("    if %s\n", describeConditions(c.Rule))
+			fmt.Printf("    then %s\n", describeActions(c.Rule, paths))
+		case rules.ActionReplace:
+			fmt.Printf("~ %s (%s)\n", c.Rule.Name, strings.Join(c.Fields, ", "))
+			fmt.Printf("  - if %s then %s\n", describeConditions(*c.Current), describeActions(*c.Current, paths))
+			fmt.Printf("  + if %s then %s\n", describeConditions(c.Rule), describeActions(c.Rule, paths))
+		case rules.ActionDelete:
+			fmt.Printf("- %s\n", c.Rule.Name)
+		}
+	}
+}
+
+// describeConditions summarizes the conditions of a rule
+func describeConditions(r rules.Rule) string {
+	var parts []string
+	if len(r.Conditions.From) > 0 {
+		parts = append(parts, "from "+strings.Join(r.Conditions.From, " or "))
+	}
+	if len(r.Conditions.SubjectContains) > 0 {
+		parts = append(parts, fmt.Sprintf("subject contains %q", strings.Join(r.Conditions.SubjectContains, `" or "`)))
+	}
+	if r.Conditions.HasAttachments != nil {
+		if *r.Conditions.HasAttachments {
+			parts = append(parts, "has attachments")
+		} else {
+			parts = append(parts, "no attachments")
+		}
+	}
+	if len(r.Unsupported) > 0 {
+		parts = append(parts, "(+ unmanaged settings)")
+	}
+	if len(parts) == 0 {
+		return "all messages"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeActions summarizes the actions of a rule
+func describeActions(r rules.Rule, paths map[string]string) string {
+	var parts []string
+	if r.Actions.MoveToFolder != "" {
+		parts = append(parts, "move to "+folderPathFunc(paths)(r.Actions.MoveToFolder))
+	}
+	if len(r.Actions.Categories) > 0 {
+		parts = append(parts, "categorize "+strings.Join(r.Actions.Categories, ", "))
+	}
+	if r.Actions.MarkRead {
+		parts = append(parts, "mark read")
+	}
+	if len(r.Actions.ForwardTo) > 0 {
+		parts = append(parts, "forward to "+strings.Join(r.Actions.ForwardTo, ", "))
+	}
+	if r.StopProcessing {
+		parts = append(parts, "stop")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findRule looks up an inbox rule by name or ID
+func findRule(ctx context.Context, client *graph.Client, ref string) (*rules.Rule, error) {
+	list, err := rules.List(ctx, client.Graph())
+	if err != nil {
+		return nil, err
+	}
+
+	rule, ok := rules.Find(list, ref)
+	if !ok {
+		return nil, fmt.Errorf("rule not found: %s", ref)
+	}
+	return rule, nil
+}
+
+// resolveRuleFolder resolves a folder reference to the real folder ID that
+// the server stores in rules, looking up well-known names
+func resolveRuleFolder(ctx context.Context, client *graph.Client, ref string) (string, error) {
+	id, err := resolveFolder(ctx, client, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if mail.IsWellKnownFolder(id) {
+		folder, err := mail.GetFolder(ctx, client.Graph(), id)
+		if err != nil {
+			return "", err
+		}
+		return folder.ID, nil
+	}
+
+	return id, nil
+}
+
+// folderPathsByID maps folder IDs to their paths. Paths are only used for
+// display, so lookup errors yield an empty map.
+func folderPathsByID(ctx context.Context, client *graph.Client) map[string]string {
+	paths := make(map[string]string)
+
+	tree, err := mail.ListFolderTree(ctx, client.Graph())
+	if err != nil {
+		return paths
+	}
+
+	mail.WalkFolders(tree, func(f mail.Folder, depth int) {
+		paths[f.ID] = f.Path
+	})
+	return paths
+}
+
+// folderPathFunc returns a lookup from folder ID to path that falls back to
+// the ID itself
+func folderPathFunc(paths map[string]string) func(id string) string {
+	return func(id string) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		return id
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/mailbox"
+	"github.com/pp/octl/internal/output"
+)
+
+var mailScheduledCmd = &cobra.Command{
+	Use:   "scheduled",
+	Short: "Manage scheduled messages",
+	Long: `List and cancel messages sent with "octl mail send --at", which wait in
+the Outbox until their send time.`,
+}
+
+var mailScheduledListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled messages",
+	Args:  cobra.NoArgs,
+	RunE:  runMailScheduledList,
+}
+
+var mailScheduledCancelCmd = &cobra.Command{
+	Use:   "cancel <message-id>",
+	Short: "Cancel a scheduled message",
+	Long: `Cancel a scheduled message by moving it from the Outbox back to Drafts,
+where it can be edited, sent, or deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailScheduledCancel,
+}
+
+func init() {
+	mailCmd.AddCommand(mailScheduledCmd)
+	mailScheduledCmd.AddCommand(mailScheduledListCmd)
+	mailScheduledCmd.AddCommand(mailScheduledCancelCmd)
+}
+
+func runMailScheduledList(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	scheduled, err := mail.ListScheduled(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(scheduled)
+	}
+
+	if len(scheduled) == 0 {
+		fmt.Println("No scheduled messages")
+		return nil
+	}
+
+	table := output.NewTable("ID", "SEND AT", "TO", "SUBJECT")
+	for _, s := range scheduled {
+		table.AddRow(
+			s.ID,
+			s.SendAt.Local().Format("2006-01-02 15:04"),
+			truncate(strings.Join(s.To, ", "), 30),
+			truncate(s.Subject, 50),
+		)
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runMailScheduledCancel(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mail.CancelScheduled(ctx, client.Graph(), args[0]); err != nil {
+		return err
+	}
+
+	fmt.Println("Scheduled message cancelled and moved to Drafts")
+	return nil
+}
+
+// parseSendTime parses --at in the --tz zone, or the local zone, and
+// checks that it is in the future
+func parseSendTime(at, zone string) (time.Time, error) {
+	loc := time.Local
+	if zone != "" {
+		var err error
+		if loc, err = mailbox.LoadLocation(zone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	t, err := mailbox.ParseWindowTime(at, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !t.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("--at %s is in the past", t.Format("2006-01-02 15:04 MST"))
+	}
+	return t, nil
+}
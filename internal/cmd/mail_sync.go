@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/config"
+	"github.com/pp/octl/internal/graph"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/mirror"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail sync flags
+	syncFolders     []string
+	syncDir         string
+	syncBodies      bool
+	syncAttachments bool
+	syncFull        bool
+)
+
+var mailSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror mail folders to a local Maildir",
+	Long: `Mirror mail folders to a local Maildir for offline reading and searching.
+
+The first run downloads every message in the selected folders. Later runs
+use Graph delta queries to fetch only what changed, including deletions
+and messages moved between folders. A changed message is stored again,
+so with --attachments even a read-state change downloads it anew. If
+Graph expires a folder's saved delta, that folder is re-read in full.
+
+By default only headers and a body preview are stored. Use --bodies to
+store full message bodies, or --attachments to store the raw MIME message
+with attachments included.
+
+The mirror lives in ~/.local/share/octl/mail unless --dir is given.
+
+Examples:
+  octl mail sync
+  octl mail sync --folders inbox,"Inbox/Projects" --bodies
+  octl mail sync --folders archive --attachments --dir ~/Mail/outlook`,
+	RunE: runMailSync,
+}
+
+func init() {
+	mailCmd.AddCommand(mailSyncCmd)
+
+	mailSyncCmd.Flags().StringSliceVar(&syncFolders, "folders", []string{mail.FolderInbox}, "Folders to mirror (IDs, well-known names, or paths)")
+	mailSyncCmd.Flags().StringVar(&syncDir, "dir", "", "Mirror directory (default ~/.local/share/octl/mail)")
+	mailSyncCmd.Flags().BoolVar(&syncBodies, "bodies", false, "Store full message bodies")
+	mailSyncCmd.Flags().BoolVar(&syncAttachments, "attachments", false, "Store raw MIME messages including attachments")
+	mailSyncCmd.Flags().BoolVar(&syncFull, "full", false, "Ignore saved sync state and re-read every folder")
+}
+
+func runMailSync(cmd *cobra.Command, args []string) error {
+	root := syncDir
+	if root == "" {
+		dataDir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		root = filepath.Join(dataDir, "mail")
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	folders, err := syncFolderRefs(ctx, client, syncFolders)
+	if err != nil {
+		return err
+	}
+
+	opts := mirror.Options{
+		Root:        root,
+		Bodies:      syncBodies,
+		Attachments: syncAttachments,
+		Full:        syncFull,
+	}
+
+	results, syncErr := mirror.Sync(ctx, &mirror.GraphSource{Client: client.Graph()}, opts, folders)
+
+	format := GetOutputFormat()
+	if format == "json" {
+		if err := output.New(format).Print(results); err != nil {
+			return err
+		}
+		return syncErr
+	}
+
+	table := output.NewTable("FOLDER", "ADDED", "UPDATED", "REMOVED")
+	for _, r := range results {
+		table.AddRow(
+			r.Folder,
+			fmt.Sprintf("%d", r.Added),
+			fmt.Sprintf("%d", r.Updated),
+			fmt.Sprintf("%d", r.Removed),
+		)
+	}
+
+	if format == "plain" {
+		if err := output.New(format).Print(table.ToPlain()); err != nil {
+			return err
+		}
+		return syncErr
+	}
+
+	if err := table.Render(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+	if syncErr == nil {
+		fmt.Printf("\nMirror: %s\n", root)
+	}
+	return syncErr
+}
+
+// syncFolderRefs resolves folder references to IDs and display paths
+func syncFolderRefs(ctx context.Context, client *graph.Client, refs []string) ([]mirror.FolderRef, error) {
+	folders := make([]mirror.FolderRef, 0, len(refs))
+	for _, ref := range refs {
+		// Well-known names are aliases, so look up the real ID and name
+		if mail.IsWellKnownFolder(ref) {
+			f, err := mail.GetFolder(ctx, client.Graph(), strings.ToLower(ref))
+			if err != nil {
+				return nil, err
+			}
+			folders = append(folders, mirror.FolderRef{ID: f.ID, Path: f.DisplayName})
+			continue
+		}
+
+		id, err := resolveFolder(ctx, client, ref)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, mirror.FolderRef{ID: id, Path: ref})
+	}
+	return folders, nil
+}
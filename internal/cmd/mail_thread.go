@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// mail thread flags
+	threadShowQuoted bool
+)
+
+var mailThreadCmd = &cobra.Command{
+	Use:   "thread <message-id>",
+	Short: "Read a whole conversation",
+	Long: `Show every message in the conversation of a message as one transcript,
+oldest first. Messages are collected from all folders, including Sent Items.
+
+Quoted history in replies is collapsed; use --show-quoted to keep it.
+With --json the ordered list of messages is printed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMailThread,
+}
+
+func init() {
+	mailCmd.AddCommand(mailThreadCmd)
+
+	mailThreadCmd.Flags().BoolVar(&threadShowQuoted, "show-quoted", false, "Show quoted history in replies")
+}
+
+func runMailThread(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	messages, err := mail.GetThread(ctx, client.Graph(), args[0])
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(messages)
+	}
+
+	if len(messages) == 0 {
+		fmt.Println("No messages found")
+		return nil
+	}
+
+	fmt.Printf("Thread: %s (%d message(s))\n", messages[0].FormatSubject(70), len(messages))
+
+	for i, msg := range messages {
+		fmt.Println()
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Printf("[%d/%d] From: %s\n", i+1, len(messages), msg.From)
+		fmt.Printf("       Date: %s\n", msg.ReceivedAt.Local().Format(time.RFC1123))
+		if len(msg.To) > 0 {
+			fmt.Printf("       To:   %s\n", strings.Join(msg.To, ", "))
+		}
+		fmt.Println()
+
+		body := strings.TrimSpace(bodyText(&msg))
+		if !threadShowQuoted {
+			body, _ = mail.CollapseQuoted(body)
+		}
+		fmt.Println(body)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/config"
+	"github.com/pp/octl/internal/output"
+	"github.com/pp/octl/internal/triage"
+)
+
+var (
+	// mail triage flags
+	triageRules  string
+	triageFolder string
+	triageDryRun bool
+	triageLimit  int
+	triageLog    string
+)
+
+var mailTriageCmd = &cobra.Command{
+	Use:   "triage",
+	Short: "Run local triage rules on a folder",
+	Long: `Run local triage rules on the messages in a folder.
+
+Local rules can express what server rules cannot, such as message age,
+read state, or regular expressions on the body. Rules are read from
+triage.yaml in the config directory unless --rules is given.
+
+Match fields (all must match): from, to, subject, body (substring, or
+/regex/ and /regex/i), older_than and newer_than (e.g. 30d), is_read,
+has_attachments, flagged, importance, categories, classification.
+Actions: run, categorize, mark_read, then move or delete. Commands get the
+message as JSON on stdin and in OCTL_MESSAGE_ID, OCTL_SUBJECT, OCTL_FROM
+and OCTL_RECEIVED_AT.
+
+Triage is idempotent: actions already in effect are skipped and each
+command runs once per rule and message. Every action is appended to
+triage.log in the data directory.
+
+Example triage.yaml:
+  rules:
+    - name: Old newsletters
+      match:
+        from: news@example.com
+        older_than: 30d
+        is_read: true
+      actions:
+        move: Archive/Newsletters
+    - name: Build failures
+      match:
+        subject: /build (failed|broken)/i
+      actions:
+        categorize: [CI]
+        run: notify-send "$OCTL_SUBJECT"`,
+	RunE: runMailTriage,
+}
+
+func init() {
+	mailCmd.AddCommand(mailTriageCmd)
+
+	mailTriageCmd.Flags().StringVarP(&triageRules, "rules", "r", "", "Triage rules file (default: triage.yaml in the config directory)")
+	mailTriageCmd.Flags().StringVarP(&triageFolder, "folder", "f", "inbox", "Folder to triage (ID, well-known name, or path)")
+	mailTriageCmd.Flags().BoolVar(&triageDryRun, "dry-run", false, "Show the actions without taking them")
+	mailTriageCmd.Flags().IntVar(&triageLimit, "limit", 500, "Maximum number of messages to examine, newest first (0 for all)")
+	mailTriageCmd.Flags().StringVar(&triageLog, "log", "", "Action log file (default: triage.log in the data directory)")
+}
+
+func runMailTriage(cmd *cobra.Command, args []string) error {
+	rulesPath := triageRules
+	if rulesPath == "" {
+		dir, err := config.ConfigDir()
+		if err != nil {
+			return err
+		}
+		rulesPath = filepath.Join(dir, "triage.yaml")
+	}
+
+	file, err := triage.LoadFile(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	logPath := triageLog
+	if logPath == "" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		logPath = filepath.Join(dir, "triage.log")
+	}
+
+	log, err := triage.OpenLog(logPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	engine := &triage.Engine{
+		Rules:   file.Rules,
+		Mailbox: &triage.GraphMailbox{Client: client.Graph()},
+		Run:     triage.ShellRunner,
+		Log:     log,
+		DryRun:  triageDryRun,
+	}
+
+	entries, runErr := engine.Triage(ctx, triageFolder, triageLimit)
+
+	format := GetOutputFormat()
+	if format == "json" {
+		if err := output.New(format).Print(entries); err != nil {
+			return err
+		}
+		return runErr
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Nothing to do")
+		return runErr
+	}
+
+	failed := 0
+	table := output.NewTable("MESSAGE", "SUBJECT", "RULE", "ACTION", "RESULT")
+	for _, e := range entries {
+		result := "done"
+		switch {
+		case e.Error != "":
+			result = "failed: " + e.Error
+			failed++
+		case e.DryRun:
+			result = "would run"
+		}
+
+		action := e.Action
+		if e.Detail != "" {
+			action += " " + e.Detail
+		}
+
+		table.AddRow(truncate(e.MessageID, 11), truncate(e.Subject, 40), e.Rule, truncate(action, 40), result)
+	}
+
+	if format == "plain" {
+		if err := output.New(format).Print(table.ToPlain()); err != nil {
+			return err
+		}
+	} else if err := table.Render(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d action(s) failed", failed)
+	}
+	return nil
+}
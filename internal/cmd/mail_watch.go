@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/config"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/triage"
+	"github.com/pp/octl/internal/watch"
+)
+
+var (
+	// mail watch flags
+	watchFolder   string
+	watchQuery    string
+	watchExec     string
+	watchInterval time.Duration
+	watchState    string
+	watchBody     bool
+)
+
+var mailWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream new messages as JSON lines",
+	Long: `Watch a folder and print every newly arrived message as one JSON line.
+
+The folder is polled with a delta query, so each poll only fetches
+changes. Progress is kept in a state file in the data directory, so a
+restart picks up where the last run stopped without replaying old mail;
+the first run only reports mail that arrives after it starts.
+
+--query matches the subject, sender, and preview case-insensitively, as
+text or as a /regular expression/. --exec runs a command for every
+message with the message JSON on stdin and OCTL_MESSAGE_ID, OCTL_SUBJECT,
+OCTL_FROM and OCTL_RECEIVED_AT set. Errors are retried with backoff;
+Ctrl+C stops the watch.
+
+Examples:
+  octl mail watch
+  octl mail watch --folder "Inbox/Alerts" --query "/^\[PAGE\]/"
+  octl mail watch --query "build failed" --exec 'notify-send "$OCTL_SUBJECT"'`,
+	RunE: runMailWatch,
+}
+
+func init() {
+	mailCmd.AddCommand(mailWatchCmd)
+
+	mailWatchCmd.Flags().StringVarP(&watchFolder, "folder", "f", "inbox", "Folder to watch (ID, well-known name, or path)")
+	mailWatchCmd.Flags().StringVarP(&watchQuery, "query", "q", "", "Only messages matching this text or /regex/")
+	mailWatchCmd.Flags().StringVar(&watchExec, "exec", "", "Command to run for each message")
+	mailWatchCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "Time between polls")
+	mailWatchCmd.Flags().StringVar(&watchState, "state", "", "State file (default: one per folder in the data directory)")
+	mailWatchCmd.Flags().BoolVar(&watchBody, "body", false, "Include message bodies")
+}
+
+func runMailWatch(cmd *cobra.Command, args []string) error {
+	if watchInterval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+
+	filter, err := watch.ParseQuery(watchQuery)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	folderID, err := resolveFolder(ctx, client, watchFolder)
+	if err != nil {
+		return err
+	}
+
+	statePath := watchState
+	if statePath == "" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256([]byte(folderID))
+		statePath = filepath.Join(dir, "watch", hex.EncodeToString(sum[:6])+".json")
+	}
+
+	state, err := watch.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	logf := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "%s "+format+"\n", append([]any{time.Now().Format("15:04:05")}, args...)...)
+	}
+
+	w := &watch.Watcher{
+		Source:      &watch.GraphSource{Client: client.Graph()},
+		FolderID:    folderID,
+		State:       state,
+		StatePath:   statePath,
+		Interval:    watchInterval,
+		MaxBackoff:  10 * time.Minute,
+		IncludeBody: watchBody,
+		Filter:      filter,
+		Logf:        logf,
+		Emit: func(ctx context.Context, msg mail.Message) error {
+			if err := encoder.Encode(msg); err != nil {
+				return fmt.Errorf("failed to write message: %w", err)
+			}
+			if watchExec != "" {
+				// A failing hook should not stop the watch
+				if err := triage.ShellRunner(ctx, watchExec, msg); err != nil {
+					logf("exec for %s: %v", msg.ID, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	fmt.Fprintf(os.Stderr, "Watching %s (Ctrl+C to stop)\n", watchFolder)
+	return w.Run(ctx)
+}
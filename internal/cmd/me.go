@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/mailbox"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// settings set flags
+	settingsTimeZone  string
+	settingsWorkDays  string
+	settingsWorkStart string
+	settingsWorkEnd   string
+)
+
+var meCmd = &cobra.Command{
+	Use:   "me",
+	Short: "Show your profile and mailbox settings",
+	RunE:  runMe,
+}
+
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Manage mailbox settings",
+}
+
+var settingsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Change the time zone and working hours",
+	Long: `Change the mailbox time zone and working hours. Settings that are not
+given are kept.
+
+Time zones are Windows names such as "Pacific Standard Time" or IANA names
+such as Europe/Berlin. Working hours follow a time zone change when they
+were in the old zone. Days accept names, ranges such as mon-fri, and
+weekdays.
+
+Examples:
+  octl settings set --timezone "W. Europe Standard Time"
+  octl settings set --work-days mon-thu --work-start 08:00 --work-end 18:00`,
+	RunE: runSettingsSet,
+}
+
+func init() {
+	rootCmd.AddCommand(meCmd)
+	rootCmd.AddCommand(settingsCmd)
+	settingsCmd.AddCommand(settingsSetCmd)
+
+	settingsSetCmd.Flags().StringVar(&settingsTimeZone, "timezone", "", "Mailbox time zone")
+	settingsSetCmd.Flags().StringVar(&settingsWorkDays, "work-days", "", "Working days, e.g. mon-fri")
+	settingsSetCmd.Flags().StringVar(&settingsWorkStart, "work-start", "", "Start of the working day (HH:MM)")
+	settingsSetCmd.Flags().StringVar(&settingsWorkEnd, "work-end", "", "End of the working day (HH:MM)")
+	settingsSetCmd.MarkFlagsOneRequired("timezone", "work-days", "work-start", "work-end")
+}
+
+func runMe(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	profile, err := mailbox.GetProfile(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	settings, err := mailbox.GetSettings(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(struct {
+			Profile  *mailbox.Profile  `json:"profile"`
+			Settings *mailbox.Settings `json:"mailbox_settings"`
+		}{profile, settings})
+	}
+
+	fmt.Printf("Name:          %s\n", profile.DisplayName)
+	fmt.Printf("UPN:           %s\n", profile.UserPrincipalName)
+	if profile.Mail != "" {
+		fmt.Printf("Mail:          %s\n", profile.Mail)
+	}
+	if len(profile.ProxyAddresses) > 0 {
+		fmt.Printf("Addresses:     %s\n", strings.Join(profile.ProxyAddresses, "\n               "))
+	}
+	fmt.Println()
+	printSettings(settings)
+	return nil
+}
+
+func runSettingsSet(cmd *cobra.Command, args []string) error {
+	update := mailbox.SettingsUpdate{
+		TimeZone: strings.TrimSpace(settingsTimeZone),
+	}
+
+	if settingsWorkDays != "" {
+		days, err := mailbox.ParseDays(settingsWorkDays)
+		if err != nil {
+			return err
+		}
+		update.Days = days
+	}
+	for _, clock := range []struct {
+		value  string
+		target *string
+	}{{settingsWorkStart, &update.Start}, {settingsWorkEnd, &update.End}} {
+		if clock.value == "" {
+			continue
+		}
+		t, err := mailbox.ParseClock(clock.value)
+		if err != nil {
+			return err
+		}
+		*clock.target = t.Format("15:04")
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	settings, err := mailbox.UpdateSettings(ctx, client.Graph(), update)
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(settings)
+	}
+
+	fmt.Println("Mailbox settings updated")
+	fmt.Println()
+	printSettings(settings)
+	return nil
+}
+
+// printSettings prints the mailbox settings
+func printSettings(s *mailbox.Settings) {
+	zone := s.TimeZone
+	if zone == "" {
+		zone = "(not set)"
+	} else if loc, err := mailbox.LoadLocation(zone); err == nil && loc.String() != zone {
+		zone = fmt.Sprintf("%s (%s)", zone, loc)
+	}
+	fmt.Printf("Time zone:     %s\n", zone)
+
+	if s.DateFormat != "" || s.TimeFormat != "" {
+		fmt.Printf("Date format:   %s %s\n", s.DateFormat, s.TimeFormat)
+	}
+
+	if s.Language != "" {
+		language := s.Language
+		if s.LanguageName != "" {
+			language = fmt.Sprintf("%s (%s)", s.LanguageName, s.Language)
+		}
+		fmt.Printf("Language:      %s\n", language)
+	}
+
+	if wh := s.WorkingHours; wh != nil {
+		hours := fmt.Sprintf("%s %s-%s", mailbox.FormatDays(wh.Days), wh.Start, wh.End)
+		if wh.TimeZone != "" && wh.TimeZone != s.TimeZone {
+			hours += " (" + wh.TimeZone + ")"
+		}
+		fmt.Printf("Working hours: %s\n", hours)
+	}
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/htmltext"
+	"github.com/pp/octl/internal/mailbox"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// oof on flags
+	oofMessageFile  string
+	oofExternalFile string
+	oofFrom         string
+	oofUntil        string
+	oofAudience     string
+)
+
+var oofCmd = &cobra.Command{
+	Use:   "oof",
+	Short: "Manage automatic replies (out of office)",
+}
+
+var oofStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show automatic replies",
+	RunE:  runOOFStatus,
+}
+
+var oofOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Turn automatic replies on",
+	Long: `Turn automatic replies on, either until turned off or for a scheduled
+window when --from and --until are given.
+
+Messages are read from files; plain text is converted to HTML and HTML is
+sent as is. Messages that are not given keep their current text.
+
+Times without an offset are taken to be in the mailbox time zone, and
+times with one are converted into it.
+
+Examples:
+  octl oof on --message-file internal.txt
+  octl oof on --message-file internal.txt --external-file ext.txt --audience contacts
+  octl oof on --message-file internal.txt --from 2026-12-22 --until 2027-01-05T09:00`,
+	RunE: runOOFOn,
+}
+
+var oofOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Turn automatic replies off",
+	RunE:  runOOFOff,
+}
+
+func init() {
+	rootCmd.AddCommand(oofCmd)
+	oofCmd.AddCommand(oofStatusCmd)
+	oofCmd.AddCommand(oofOnCmd)
+	oofCmd.AddCommand(oofOffCmd)
+
+	oofOnCmd.Flags().StringVar(&oofMessageFile, "message-file", "", "File with the reply for people in your organization")
+	oofOnCmd.Flags().StringVar(&oofExternalFile, "external-file", "", "File with the reply for external senders")
+	oofOnCmd.Flags().StringVar(&oofFrom, "from", "", "Start of the scheduled window")
+	oofOnCmd.Flags().StringVar(&oofUntil, "until", "", "End of the scheduled window")
+	oofOnCmd.Flags().StringVar(&oofAudience, "audience", "", "External senders to reply to: none, contacts, or all (default all with --external-file)")
+	oofOnCmd.MarkFlagsRequiredTogether("from", "until")
+}
+
+func runOOFStatus(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	replies, err := mailbox.GetAutoReplies(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	return printAutoReplies(replies)
+}
+
+func runOOFOn(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	current, err := mailbox.GetAutoReplies(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	replies := mailbox.AutoReplies{
+		Audience:        current.Audience,
+		InternalMessage: current.InternalMessage,
+		ExternalMessage: current.ExternalMessage,
+	}
+
+	if oofMessageFile != "" {
+		if replies.InternalMessage, err = readReplyFile(oofMessageFile); err != nil {
+			return err
+		}
+	}
+	if oofExternalFile != "" {
+		if replies.ExternalMessage, err = readReplyFile(oofExternalFile); err != nil {
+			return err
+		}
+		if replies.Audience == mailbox.AudienceNone {
+			replies.Audience = mailbox.AudienceAll
+		}
+	}
+	if cmd.Flags().Changed("audience") {
+		if replies.Audience, err = mailbox.ParseAudience(oofAudience); err != nil {
+			return err
+		}
+	}
+
+	if replies.InternalMessage == "" {
+		return fmt.Errorf("no reply message set; use --message-file")
+	}
+
+	if oofFrom != "" {
+		loc, err := mailbox.LoadLocation(current.TimeZone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: mailbox time zone %q is not known here, using local time\n", current.TimeZone)
+			loc = time.Local
+		}
+
+		start, err := mailbox.ParseWindowTime(oofFrom, loc)
+		if err != nil {
+			return err
+		}
+		end, err := mailbox.ParseWindowTime(oofUntil, loc)
+		if err != nil {
+			return err
+		}
+		replies.Start, replies.End = &start, &end
+	}
+
+	updated, err := mailbox.SetAutoReplies(ctx, client.Graph(), replies, current.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	return printAutoReplies(updated)
+}
+
+func runOOFOff(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := mailbox.DisableAutoReplies(ctx, client.Graph()); err != nil {
+		return err
+	}
+
+	fmt.Println("Automatic replies turned off")
+	return nil
+}
+
+// readReplyFile reads an automatic reply message and converts it to HTML
+func readReplyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+
+	message := mailbox.MessageHTML(string(data))
+	if message == "" {
+		return "", fmt.Errorf("message file is empty: %s", path)
+	}
+	return message, nil
+}
+
+// printAutoReplies prints the automatic replies setting
+func printAutoReplies(r *mailbox.AutoReplies) error {
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(r)
+	}
+
+	switch r.Status {
+	case mailbox.StatusAlwaysEnabled:
+		fmt.Println("Status:    on")
+	case mailbox.StatusScheduled:
+		fmt.Println("Status:    scheduled")
+		if r.Start != nil && r.End != nil {
+			fmt.Printf("From:      %s\n", r.Start.Format("Mon Jan 2, 2006 15:04"))
+			fmt.Printf("Until:     %s\n", r.End.Format("Mon Jan 2, 2006 15:04"))
+		}
+	default:
+		fmt.Println("Status:    off")
+	}
+	if r.TimeZone != "" {
+		fmt.Printf("Time zone: %s\n", r.TimeZone)
+	}
+	fmt.Printf("Audience:  %s\n", describeAudience(r.Audience))
+
+	if r.InternalMessage != "" {
+		fmt.Println()
+		fmt.Println("Internal reply:")
+		fmt.Println(indent(htmltext.Render(r.InternalMessage, htmltext.Options{})))
+	}
+	if r.ExternalMessage != "" && r.Audience != mailbox.AudienceNone {
+		fmt.Println()
+		fmt.Println("External reply:")
+		fmt.Println(indent(htmltext.Render(r.ExternalMessage, htmltext.Options{})))
+	}
+
+	return nil
+}
+
+// describeAudience describes who receives automatic replies
+func describeAudience(audience string) string {
+	switch audience {
+	case mailbox.AudienceContacts:
+		return "organization and external contacts"
+	case mailbox.AudienceAll:
+		return "everyone"
+	}
+	return "organization only"
+}
+
+// indent indents each line of s by two spaces
+func indent(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
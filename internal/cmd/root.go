@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/htmltext"
+	"github.com/pp/octl/internal/mail"
+	"github.com/pp/octl/internal/output"
 )
 
 var (
@@ -53,3 +59,41 @@ func GetOutputFormat() string {
 func PrintError(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
 }
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// truncate shortens s to at most maxLen characters for table display
+func truncate(s string, maxLen int) string {
+	if len(s) > maxLen {
+		return s[:maxLen-3] + "..."
+	}
+	return s
+}
+
+// bodyText returns the message body as plain text
+func bodyText(msg *mail.Message) string {
+	if msg.BodyContentType == "html" {
+		return renderHTML(msg.Body)
+	}
+	return msg.Body
+}
+
+// renderHTML renders an HTML body for stdout, wrapped to the terminal
+// width and with terminal hyperlinks when colours are enabled
+func renderHTML(s string) string {
+	return htmltext.Render(s, htmltext.Options{
+		Width:      output.TerminalWidth(os.Stdout),
+		Hyperlinks: output.ColorEnabled(os.Stdout),
+	})
+}
@@ -0,0 +1,433 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pp/octl/internal/config"
+	"github.com/pp/octl/internal/notify"
+	"github.com/pp/octl/internal/output"
+)
+
+var (
+	// subscribe flags
+	subscribeResource    string
+	subscribeFolder      string
+	subscribeURL         string
+	subscribeChangeTypes string
+	subscribeExpires     time.Duration
+	subscribeRenewAll    bool
+	subscribeDeleteYes   bool
+
+	// listen flags
+	listenAddr    string
+	listenPath    string
+	listenExec    string
+	listenNoFetch bool
+	listenNoRenew bool
+)
+
+// renewWindow is how close to expiry listen renews a subscription
+const renewWindow = 24 * time.Hour
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe",
+	Short: "Manage Graph change-notification subscriptions",
+	Long: `Manage Graph change-notification subscriptions for messages and events.
+
+Graph posts notifications to a public HTTPS URL, which octl listen can
+serve (behind a tunnel or reverse proxy). Each subscription created here
+gets a random client state that listen uses to verify notifications; it
+is kept in subscriptions.json in the config directory.`,
+}
+
+var subscribeCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a subscription",
+	Long: `Create a subscription. Graph validates the notification URL before it
+answers, so octl listen must already be reachable at --url.
+
+Resources: messages (optionally limited with --folder), events, or a raw
+Graph resource path. Subscriptions last at most 7 days; octl listen
+renews them automatically.
+
+Examples:
+  octl subscribe create --resource messages --folder inbox --url https://example.ngrok.app/
+  octl subscribe create --resource events --url https://hooks.example.com/octl --change-types created`,
+	RunE: runSubscribeCreate,
+}
+
+var subscribeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List subscriptions",
+	RunE:  runSubscribeList,
+}
+
+var subscribeRenewCmd = &cobra.Command{
+	Use:   "renew [subscription-id...]",
+	Short: "Renew subscriptions",
+	RunE:  runSubscribeRenew,
+}
+
+var subscribeDeleteCmd = &cobra.Command{
+	Use:   "delete <subscription-id...>",
+	Short: "Delete subscriptions",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runSubscribeDelete,
+}
+
+var listenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Receive change notifications",
+	Long: `Run an HTTP receiver for Graph change notifications.
+
+The receiver answers Graph's validation handshake, drops notifications
+whose client state does not match a subscription created with octl
+subscribe, fetches each changed message or event, and prints one JSON line
+per change. --exec runs a command per change with the JSON on stdin and
+OCTL_CHANGE_TYPE, OCTL_KIND and OCTL_RESOURCE_ID set. Subscriptions are
+renewed while listen runs.
+
+Test locally with a synthetic notification:
+  curl -X POST localhost:8080/ -d '{"value":[{"subscriptionId":"<id>",
+    "clientState":"<state>","changeType":"created",
+    "resource":"Users/<user>/Messages/<message-id>",
+    "resourceData":{"id":"<message-id>"}}]}'
+
+Examples:
+  octl listen --addr :8080
+  octl listen --addr 127.0.0.1:8080 --exec ./on-change.sh`,
+	RunE: runListen,
+}
+
+func init() {
+	rootCmd.AddCommand(subscribeCmd)
+	rootCmd.AddCommand(listenCmd)
+	subscribeCmd.AddCommand(subscribeCreateCmd)
+	subscribeCmd.AddCommand(subscribeListCmd)
+	subscribeCmd.AddCommand(subscribeRenewCmd)
+	subscribeCmd.AddCommand(subscribeDeleteCmd)
+
+	subscribeCreateCmd.Flags().StringVarP(&subscribeResource, "resource", "r", "messages", "Resource: messages, events, or a Graph resource path")
+	subscribeCreateCmd.Flags().StringVarP(&subscribeFolder, "folder", "f", "", "Limit messages to a folder (ID, well-known name, or path)")
+	subscribeCreateCmd.Flags().StringVar(&subscribeURL, "url", "", "Public HTTPS notification URL")
+	subscribeCreateCmd.Flags().StringVar(&subscribeChangeTypes, "change-types", notify.DefaultChangeTypes, "Changes to report")
+	subscribeCreateCmd.Flags().DurationVar(&subscribeExpires, "expires", 72*time.Hour, "Subscription lifetime (at most 7 days)")
+	_ = subscribeCreateCmd.MarkFlagRequired("url")
+
+	subscribeRenewCmd.Flags().BoolVar(&subscribeRenewAll, "all", false, "Renew every subscription created with octl")
+	subscribeRenewCmd.Flags().DurationVar(&subscribeExpires, "expires", 72*time.Hour, "New lifetime from now (at most 7 days)")
+
+	subscribeDeleteCmd.Flags().BoolVarP(&subscribeDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
+
+	listenCmd.Flags().StringVar(&listenAddr, "addr", ":8080", "Address to listen on")
+	listenCmd.Flags().StringVar(&listenPath, "path", "/", "URL path to serve")
+	listenCmd.Flags().StringVar(&listenExec, "exec", "", "Command to run for each change")
+	listenCmd.Flags().BoolVar(&listenNoFetch, "no-fetch", false, "Emit changes without fetching the resources")
+	listenCmd.Flags().BoolVar(&listenNoRenew, "no-renew", false, "Do not renew subscriptions automatically")
+	listenCmd.Flags().DurationVar(&subscribeExpires, "expires", 72*time.Hour, "Lifetime given to renewed subscriptions")
+}
+
+// openSubscriptionStore opens the local subscription store
+func openSubscriptionStore() (*notify.Store, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return notify.OpenStore(filepath.Join(dir, "subscriptions.json"))
+}
+
+func runSubscribeCreate(cmd *cobra.Command, args []string) error {
+	if !strings.HasPrefix(subscribeURL, "https://") {
+		return fmt.Errorf("--url must be an https:// URL that Graph can reach")
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	folderID := ""
+	if subscribeFolder != "" {
+		if folderID, err = resolveFolder(ctx, client, subscribeFolder); err != nil {
+			return err
+		}
+	}
+
+	resource, err := notify.ResourcePath(subscribeResource, folderID)
+	if err != nil {
+		return err
+	}
+
+	state, err := notify.NewClientState()
+	if err != nil {
+		return err
+	}
+
+	store, err := openSubscriptionStore()
+	if err != nil {
+		return err
+	}
+
+	sub, err := notify.CreateSubscription(ctx, client.Graph(), notify.Subscription{
+		Resource:        resource,
+		ChangeType:      subscribeChangeTypes,
+		NotificationURL: subscribeURL,
+		ExpiresAt:       notify.ExpiryFor(time.Now(), subscribeExpires),
+		ClientState:     state,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := store.Put(*sub); err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		sub.ClientState = ""
+		return output.New(format).Print(sub)
+	}
+
+	fmt.Printf("Subscription created: %s\n", sub.ID)
+	fmt.Printf("Resource: %s (%s)\n", sub.Resource, sub.ChangeType)
+	fmt.Printf("Expires:  %s\n", sub.ExpiresAt.Local().Format("Mon Jan 2 15:04"))
+	return nil
+}
+
+func runSubscribeList(cmd *cobra.Command, args []string) error {
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	subs, err := notify.ListSubscriptions(ctx, client.Graph())
+	if err != nil {
+		return err
+	}
+
+	store, err := openSubscriptionStore()
+	if err != nil {
+		return err
+	}
+
+	format := GetOutputFormat()
+	if format == "json" {
+		return output.New(format).Print(subs)
+	}
+
+	if len(subs) == 0 {
+		fmt.Println("No subscriptions found")
+		return nil
+	}
+
+	table := output.NewTable("ID", "RESOURCE", "CHANGES", "EXPIRES", "URL", "VERIFIED")
+	for _, s := range subs {
+		verified := "no"
+		if local, ok := store.Get(s.ID); ok && local.ClientState != "" {
+			verified = "yes"
+		}
+		table.AddRow(s.ID, s.Resource, s.ChangeType, s.ExpiresAt.Local().Format("Jan 2 15:04"), s.NotificationURL, verified)
+	}
+
+	if format == "plain" {
+		return output.New(format).Print(table.ToPlain())
+	}
+	return table.Render(cmd.OutOrStdout())
+}
+
+func runSubscribeRenew(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !subscribeRenewAll {
+		return fmt.Errorf("give subscription IDs or --all")
+	}
+
+	store, err := openSubscriptionStore()
+	if err != nil {
+		return err
+	}
+
+	ids := args
+	if subscribeRenewAll {
+		ids = nil
+		for _, s := range store.List() {
+			ids = append(ids, s.ID)
+		}
+		if len(ids) == 0 {
+			fmt.Println("No subscriptions to renew")
+			return nil
+		}
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	expires := notify.ExpiryFor(time.Now(), subscribeExpires)
+	var errs []error
+	for _, id := range ids {
+		sub, err := notify.RenewSubscription(ctx, client.Graph(), id, expires)
+		if err != nil {
+			PrintError("%s: %v", id, err)
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := store.Get(id); ok {
+			if err := store.Put(*sub); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Renewed %s until %s\n", id, sub.ExpiresAt.Local().Format("Mon Jan 2 15:04"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d renewals failed", len(errs), len(ids))
+	}
+	return nil
+}
+
+func runSubscribeDelete(cmd *cobra.Command, args []string) error {
+	if !subscribeDeleteYes && !confirm(fmt.Sprintf("Delete %d subscription(s)?", len(args))) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	store, err := openSubscriptionStore()
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	failed := 0
+	for _, id := range args {
+		if err := notify.DeleteSubscription(ctx, client.Graph(), id); err != nil {
+			PrintError("%s: %v", id, err)
+			failed++
+			continue
+		}
+		if err := store.Remove(id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s\n", id)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deletions failed", failed, len(args))
+	}
+	return nil
+}
+
+func runListen(cmd *cobra.Command, args []string) error {
+	store, err := openSubscriptionStore()
+	if err != nil {
+		return err
+	}
+
+	client, err := getGraphClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logf := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "%s "+format+"\n", append([]any{time.Now().Format("15:04:05")}, args...)...)
+	}
+
+	var fetcher notify.Fetcher
+	if !listenNoFetch {
+		fetcher = &notify.GraphFetcher{Client: client.Graph()}
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	receiver := notify.NewReceiver(store, fetcher, func(ctx context.Context, ev notify.Event) error {
+		if err := encoder.Encode(ev); err != nil {
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+		if listenExec != "" {
+			if err := notify.ShellHook(ctx, listenExec, ev); err != nil {
+				logf("exec for %s: %v", ev.ID, err)
+			}
+		}
+		return nil
+	})
+	receiver.Logf = logf
+
+	go receiver.Run(ctx)
+
+	if !listenNoRenew {
+		go renewLoop(ctx, &notify.GraphRenewer{Client: client.Graph()}, store, logf)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(listenPath, receiver)
+	server := &http.Server{
+		Addr:              listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on %s%s (Ctrl+C to stop)\n", listenAddr, listenPath)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	return nil
+}
+
+// renewLoop renews subscriptions nearing expiry until ctx is cancelled
+func renewLoop(ctx context.Context, renewer notify.Renewer, store *notify.Store, logf func(string, ...any)) {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		renewed, err := notify.RenewDue(ctx, renewer, store, time.Now(), renewWindow, subscribeExpires)
+		for _, s := range renewed {
+			logf("renewed %s until %s", s.ID, s.ExpiresAt.Local().Format("Mon Jan 2 15:04"))
+		}
+		if err != nil && ctx.Err() == nil {
+			logf("renewal failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTemplateRoundTrip(t *testing.T) {
+	d := Draft{
+		To:      []string{"a@example.com", `"Doe, Jane" <jane@example.com>`},
+		Cc:      []string{"c@example.com"},
+		Subject: "Quarterly report",
+		Body:    "Hi,\n\nNumbers attached.\n",
+	}
+
+	got, err := Parse(Template(d))
+	if err != nil {
+		t.Fatalf("Parse(Template()) error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, d) {
+		t.Errorf("round trip = %+v, want %+v", *got, d)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Draft
+		wantErr bool
+	}{
+		{
+			name:  "folded headers and case-insensitive names",
+			input: "to: a@example.com,\n  b@example.com\nSUBJECT: Long\n\tsubject\n\nBody\n\n\n",
+			want:  Draft{To: []string{"a@example.com", "b@example.com"}, Subject: "Long subject", Body: "Body\n"},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "To: a@example.com\r\nSubject: Hi\r\n\r\nLine one\r\nLine two\r\n",
+			want:  Draft{To: []string{"a@example.com"}, Subject: "Hi", Body: "Line one\nLine two\n"},
+		},
+		{
+			name:  "empty body",
+			input: "To: a@example.com\nSubject: Hi\n\n   \n\n",
+			want:  Draft{To: []string{"a@example.com"}, Subject: "Hi"},
+		},
+		{
+			name:  "headers only",
+			input: "To: a@example.com\nSubject: Hi\n",
+			want:  Draft{To: []string{"a@example.com"}, Subject: "Hi"},
+		},
+		{
+			name:    "unknown header",
+			input:   "To: a@example.com\nFrom: me@example.com\n\nBody\n",
+			wantErr: true,
+		},
+		{
+			name:    "body without blank line",
+			input:   "To: a@example.com\nHello there\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAddresses(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"a@example.com, b@example.com", []string{"a@example.com", "b@example.com"}},
+		{`"Doe, Jane" <jane@example.com>; bob@example.com`, []string{`"Doe, Jane" <jane@example.com>`, "bob@example.com"}},
+		{"Jane <jane@example.com>,", []string{"Jane <jane@example.com>"}},
+		{"  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := SplitAddresses(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitAddresses(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got, err := Markdown("# Status\n\n- **done**: tests\n- [link](https://example.com)\n\n<script>alert(1)</script>\n")
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+
+	for _, want := range []string{"<h1>Status</h1>", "<li><strong>done</strong>: tests</li>", `<a href="https://example.com">link</a>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown() missing %q in %q", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") {
+		t.Errorf("Markdown() passed raw HTML through: %q", got)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	if got := EditorCommand(); !reflect.DeepEqual(got, []string{"code", "--wait"}) {
+		t.Errorf("EditorCommand() = %q, want [code --wait]", got)
+	}
+
+	t.Setenv("VISUAL", "nano")
+	if got := EditorCommand(); !reflect.DeepEqual(got, []string{"nano"}) {
+		t.Errorf("EditorCommand() = %q, want VISUAL to win", got)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho 'Thanks!' >> \"$1\"\n"), 0700); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Setenv("VISUAL", script)
+
+	text, path, err := Edit("To: a@example.com\n\n", ".eml")
+	if err != nil {
+		t.Fatalf("Edit() error = %v", err)
+	}
+	defer os.Remove(path)
+
+	if text != "To: a@example.com\n\nThanks!\n" {
+		t.Errorf("Edit() = %q", text)
+	}
+	if filepath.Ext(path) != ".eml" {
+		t.Errorf("Edit() path = %q, want .eml extension", path)
+	}
+}
@@ -0,0 +1,61 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// EditorCommand returns the user's editor command from $VISUAL or $EDITOR,
+// split into arguments, falling back to vi (notepad on Windows)
+func EditorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// Edit writes initial to a temporary file with the given extension, opens
+// it in the user's editor, and returns the edited text along with the
+// file's path. The caller removes the file once the text has been used, so
+// it survives a failed send.
+func Edit(initial, ext string) (string, string, error) {
+	f, err := os.CreateTemp("", "octl-*"+ext)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+
+	if _, err := f.WriteString(initial); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := EditorCommand()
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		os.Remove(path)
+		return "", "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", path, fmt.Errorf("failed to read edited message: %w", err)
+	}
+	return string(data), path, nil
+}
@@ -0,0 +1,24 @@
+package compose
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders GitHub-flavoured Markdown. Raw HTML in the source is
+// omitted rather than passed through.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Markdown renders a Markdown body to an HTML document
+func Markdown(src string) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("<html><body>\n")
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	buf.WriteString("</body></html>\n")
+	return buf.String(), nil
+}
@@ -0,0 +1,131 @@
+package compose
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Draft is a message being composed
+type Draft struct {
+	To      []string
+	Cc      []string
+	Bcc     []string
+	Subject string
+	Body    string
+}
+
+// Template renders a draft as RFC 822-style headers, a blank line, and the
+// body, ready to open in an editor
+func Template(d Draft) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\n", strings.Join(d.To, ", "))
+	fmt.Fprintf(&b, "Cc: %s\n", strings.Join(d.Cc, ", "))
+	fmt.Fprintf(&b, "Bcc: %s\n", strings.Join(d.Bcc, ", "))
+	fmt.Fprintf(&b, "Subject: %s\n", d.Subject)
+	b.WriteString("\n")
+	b.WriteString(d.Body)
+	if d.Body != "" && !strings.HasSuffix(d.Body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Parse reads a draft back from its template form. Header names are
+// case-insensitive, long values may be folded onto indented lines, and the
+// body starts after the first blank line.
+func Parse(text string) (*Draft, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	d := &Draft{}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(text)+1)
+
+	var headers []string
+	lineNo := 0
+	inBody := false
+	var body strings.Builder
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		if inBody {
+			body.WriteString(line)
+			body.WriteString("\n")
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			inBody = true
+		case line[0] == ' ' || line[0] == '\t':
+			if len(headers) == 0 {
+				return nil, fmt.Errorf("line %d: continuation line before any header", lineNo)
+			}
+			headers[len(headers)-1] += " " + strings.TrimSpace(line)
+		default:
+			if !strings.Contains(line, ":") {
+				return nil, fmt.Errorf("line %d: expected a header such as \"To: ...\" or a blank line before the body", lineNo)
+			}
+			headers = append(headers, line)
+		}
+	}
+
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ":")
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "to":
+			d.To = SplitAddresses(value)
+		case "cc":
+			d.Cc = SplitAddresses(value)
+		case "bcc":
+			d.Bcc = SplitAddresses(value)
+		case "subject":
+			d.Subject = value
+		default:
+			return nil, fmt.Errorf("unknown header: %s", strings.TrimSpace(name))
+		}
+	}
+
+	d.Body = strings.TrimRight(body.String(), " \t\n") + "\n"
+	if strings.TrimSpace(d.Body) == "" {
+		d.Body = ""
+	}
+	return d, nil
+}
+
+// SplitAddresses splits a comma-separated address list, keeping commas
+// inside quoted display names and angle brackets
+func SplitAddresses(s string) []string {
+	var addrs []string
+	var current strings.Builder
+	inQuotes, inAngle := false, false
+
+	flush := func() {
+		if a := strings.TrimSpace(current.String()); a != "" {
+			addrs = append(addrs, a)
+		}
+		current.Reset()
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '<' && !inQuotes:
+			inAngle = true
+		case r == '>' && !inQuotes:
+			inAngle = false
+		case (r == ',' || r == ';') && !inQuotes && !inAngle:
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return addrs
+}
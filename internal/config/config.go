@@ -31,6 +31,20 @@ func ConfigDir() (string, error) {
 	return configDir()
 }
 
+// DataDir returns the directory for local data such as mail mirrors.
+// It honors XDG_DATA_HOME and defaults to ~/.local/share/octl.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, configDirName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", configDirName), nil
+}
+
 // configPath returns the full path to the config file
 func configPath() (string, error) {
 	dir, err := configDir()
@@ -90,4 +90,33 @@ func TestConfig(t *testing.T) {
 			t.Errorf("ConfigDir() = %q, want %q", dir, expected)
 		}
 	})
+	t.Run("DataDir defaults under home", func(t *testing.T) {
+		originalXDG := os.Getenv("XDG_DATA_HOME")
+		os.Unsetenv("XDG_DATA_HOME")
+		defer os.Setenv("XDG_DATA_HOME", originalXDG)
+
+		dir, err := DataDir()
+		if err != nil {
+			t.Fatalf("DataDir() error = %v", err)
+		}
+		expected := filepath.Join(tmpDir, ".local", "share", "octl")
+		if dir != expected {
+			t.Errorf("DataDir() = %q, want %q", dir, expected)
+		}
+	})
+
+	t.Run("DataDir honors XDG_DATA_HOME", func(t *testing.T) {
+		originalXDG := os.Getenv("XDG_DATA_HOME")
+		os.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "data"))
+		defer os.Setenv("XDG_DATA_HOME", originalXDG)
+
+		dir, err := DataDir()
+		if err != nil {
+			t.Fatalf("DataDir() error = %v", err)
+		}
+		expected := filepath.Join(tmpDir, "data", "octl")
+		if dir != expected {
+			t.Errorf("DataDir() = %q, want %q", dir, expected)
+		}
+	})
 }
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// checkpointEntry is one line of an export checkpoint. An entry without an
+// ID marks where a run started.
+type checkpointEntry struct {
+	ID     string `json:"id,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+}
+
+// checkpoint is an append-only JSON lines record of exported messages.
+// Each entry is synced to disk as it is written, so recording a message
+// costs one line however large the export grows.
+type checkpoint struct {
+	f       *os.File
+	done    map[string]bool
+	offset  int64
+	entries int
+}
+
+// openCheckpoint opens or creates a checkpoint and reads the messages
+// already exported
+func openCheckpoint(path string) (*checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export checkpoint: %w", err)
+	}
+
+	c := &checkpoint{f: f, done: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A crash can leave a partial last line
+			continue
+		}
+		if e.ID != "" {
+			c.done[e.ID] = true
+		}
+		c.offset = e.Offset
+		c.entries++
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read export checkpoint: %w", err)
+	}
+
+	return c, nil
+}
+
+// Done reports whether a message was exported by an earlier run
+func (c *checkpoint) Done(id string) bool {
+	return c.done[id]
+}
+
+// Offset returns the output size recorded last, if anything was recorded
+func (c *checkpoint) Offset() (int64, bool) {
+	return c.offset, c.entries > 0
+}
+
+// Append writes an entry and syncs it to disk
+func (c *checkpoint) Append(e checkpointEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export checkpoint: %w", err)
+	}
+	if _, err := c.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write export checkpoint: %w", err)
+	}
+	if err := c.f.Sync(); err != nil {
+		return fmt.Errorf("failed to write export checkpoint: %w", err)
+	}
+	if e.ID != "" {
+		c.done[e.ID] = true
+	}
+	c.offset = e.Offset
+	c.entries++
+	return nil
+}
+
+// Close closes the checkpoint file
+func (c *checkpoint) Close() error {
+	return c.f.Close()
+}
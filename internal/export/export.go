@@ -0,0 +1,272 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Format names
+const (
+	FormatEML  = "eml"
+	FormatMbox = "mbox"
+)
+
+// DefaultNameTemplate names exported .eml files
+const DefaultNameTemplate = "{{.Date}}_{{.Subject}}_{{.ShortID}}.eml"
+
+// FetchFunc downloads the raw MIME content of a message
+type FetchFunc func(ctx context.Context, messageID string) ([]byte, error)
+
+// Options configures an export
+type Options struct {
+	// Format is FormatEML or FormatMbox
+	Format string
+	// Output is a directory for .eml files or a file path for mbox
+	Output string
+	// NameTemplate is a text/template for .eml file names
+	NameTemplate string
+	// Concurrency bounds the number of parallel downloads
+	Concurrency int
+}
+
+// Failure records a message that could not be exported
+type Failure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// Result summarizes an export run
+type Result struct {
+	Exported int       `json:"exported"`
+	Skipped  int       `json:"skipped"`
+	Failed   []Failure `json:"failed,omitempty"`
+	Output   string    `json:"output"`
+}
+
+// Run exports the given message IDs. Messages recorded in the checkpoint
+// from an earlier run are skipped, so an interrupted export can simply be
+// run again.
+func Run(ctx context.Context, fetch FetchFunc, ids []string, opts Options) (*Result, error) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.NameTemplate == "" {
+		opts.NameTemplate = DefaultNameTemplate
+	}
+
+	var sink sink
+	var err error
+	switch opts.Format {
+	case FormatEML, "":
+		sink, err = newEMLSink(opts.Output, opts.NameTemplate)
+	case FormatMbox:
+		sink, err = newMboxSink(opts.Output)
+	default:
+		return nil, fmt.Errorf("unknown export format: %s (use eml or mbox)", opts.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer sink.Close()
+
+	cp, err := openCheckpoint(sink.CheckpointPath())
+	if err != nil {
+		return nil, err
+	}
+	defer cp.Close()
+
+	// Drop anything a crashed run wrote without recording it, then record
+	// where this run starts
+	if offset, ok := cp.Offset(); ok {
+		if err := sink.Truncate(offset); err != nil {
+			return nil, err
+		}
+	}
+	committed := sink.Offset()
+	if err := cp.Append(checkpointEntry{Offset: committed}); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Output: opts.Output}
+
+	var pending []string
+	for _, id := range ids {
+		if cp.Done(id) {
+			result.Skipped++
+			continue
+		}
+		pending = append(pending, id)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// fatal stops further writes once the output holds a write the
+	// checkpoint does not know about
+	var fatal error
+	jobs := make(chan string)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				data, err := fetch(ctx, id)
+				if err == nil {
+					mu.Lock()
+					if err = fatal; err == nil {
+						err = sink.Write(id, data)
+					}
+					if err == nil {
+						offset := sink.Offset()
+						if err = cp.Append(checkpointEntry{ID: id, Offset: offset}); err != nil {
+							// Not recorded, so it will be retried; do not keep a copy
+							if terr := sink.Truncate(committed); terr != nil {
+								fatal = fmt.Errorf("failed to drop unrecorded message: %w", terr)
+							}
+						} else {
+							committed = offset
+						}
+					}
+					mu.Unlock()
+				}
+
+				mu.Lock()
+				if err != nil {
+					result.Failed = append(result.Failed, Failure{ID: id, Error: err.Error()})
+				} else {
+					result.Exported++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range pending {
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if fatal != nil {
+		return result, fatal
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// sink receives exported messages
+type sink interface {
+	Write(id string, data []byte) error
+	// Offset is the size of the output after the last write, recorded with
+	// each exported message so a resume can drop unrecorded writes
+	Offset() int64
+	// Truncate drops everything written after offset
+	Truncate(offset int64) error
+	CheckpointPath() string
+	Close() error
+}
+
+// emlSink writes one .eml file per message into a directory
+type emlSink struct {
+	dir  string
+	name *nameTemplate
+}
+
+func newEMLSink(dir, tmpl string) (*emlSink, error) {
+	name, err := parseNameTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return &emlSink{dir: dir, name: name}, nil
+}
+
+func (s *emlSink) Write(id string, data []byte) error {
+	name, err := s.name.Execute(id, data)
+	if err != nil {
+		return err
+	}
+
+	path, written := uniquePath(filepath.Join(s.dir, name), data)
+	if written {
+		// A run that died before recording the message already wrote it
+		return nil
+	}
+
+	// Write under a temporary name so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(s.dir, ".octl-export-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *emlSink) Offset() int64 {
+	return 0
+}
+
+// Truncate does nothing: every .eml file stands on its own
+func (s *emlSink) Truncate(offset int64) error {
+	return nil
+}
+
+func (s *emlSink) CheckpointPath() string {
+	return filepath.Join(s.dir, ".octl-export.jsonl")
+}
+
+func (s *emlSink) Close() error {
+	return nil
+}
+
+// uniquePath appends a counter to path until it names a file that does not
+// exist yet. If one of the names already holds data, it returns that name
+// and true instead.
+func uniquePath(path string, data []byte) (string, bool) {
+	ext := filepath.Ext(path)
+	base := path[:len(path)-len(ext)]
+	for i := 1; ; i++ {
+		candidate := path
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		existing, err := os.ReadFile(candidate)
+		if os.IsNotExist(err) {
+			return candidate, false
+		}
+		if err == nil && bytes.Equal(existing, data) {
+			return candidate, true
+		}
+	}
+}
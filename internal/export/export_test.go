@@ -0,0 +1,252 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const sampleMessage = "From: Jane Doe <jane@example.com>\r\n" +
+	"Subject: Quarterly report\r\n" +
+	"Date: Mon, 15 Jan 2024 14:30:00 +0000\r\n" +
+	"\r\n" +
+	"Hello\r\n" +
+	"From the team\r\n"
+
+func fakeFetch(calls *int32, fail map[string]bool) FetchFunc {
+	return func(ctx context.Context, id string) ([]byte, error) {
+		atomic.AddInt32(calls, 1)
+		if fail[id] {
+			return nil, errors.New("boom")
+		}
+		return []byte(strings.Replace(sampleMessage, "Quarterly report", "Report "+id, 1)), nil
+	}
+}
+
+func TestRunEML(t *testing.T) {
+	dir := t.TempDir()
+	var calls int32
+	fail := map[string]bool{"m3": true}
+
+	opts := Options{Format: FormatEML, Output: dir, Concurrency: 2}
+	result, err := Run(context.Background(), fakeFetch(&calls, fail), []string{"m1", "m2", "m3"}, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Exported != 2 || len(result.Failed) != 1 || result.Failed[0].ID != "m3" {
+		t.Errorf("result = %+v, want 2 exported and m3 failed", result)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(matches) != 2 {
+		t.Fatalf("got %d .eml files, want 2", len(matches))
+	}
+	for _, m := range matches {
+		if !strings.HasPrefix(filepath.Base(m), "2024-01-15_Report m") {
+			t.Errorf("unexpected file name %q", filepath.Base(m))
+		}
+	}
+
+	t.Run("resume skips exported messages", func(t *testing.T) {
+		calls = 0
+		delete(fail, "m3")
+
+		result, err := Run(context.Background(), fakeFetch(&calls, fail), []string{"m1", "m2", "m3"}, opts)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if result.Skipped != 2 || result.Exported != 1 {
+			t.Errorf("result = %+v, want 2 skipped and 1 exported", result)
+		}
+		if calls != 1 {
+			t.Errorf("fetch called %d times, want 1", calls)
+		}
+	})
+}
+
+func TestRunEMLResumeKeepsUnrecordedWrite(t *testing.T) {
+	dir := t.TempDir()
+	var calls int32
+	opts := Options{Format: FormatEML, Output: dir}
+	if _, err := Run(context.Background(), fakeFetch(&calls, nil), []string{"m1"}, opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Simulate a run that died after writing the file but before recording
+	// it
+	if err := os.Remove(filepath.Join(dir, ".octl-export.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(context.Background(), fakeFetch(&calls, nil), []string{"m1"}, opts); err != nil {
+		t.Fatalf("Run() resume error = %v", err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.eml")); len(matches) != 1 {
+		t.Errorf("files = %q, want one .eml without a duplicate", matches)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %q", matches)
+	}
+}
+
+func TestRunMbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mbox")
+	var calls int32
+
+	opts := Options{Format: FormatMbox, Output: path, Concurrency: 3}
+	if _, err := Run(context.Background(), fakeFetch(&calls, nil), []string{"m1", "m2"}, opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	content := string(data)
+
+	if n := strings.Count(content, "\nFrom jane@example.com ") + boolToInt(strings.HasPrefix(content, "From jane@example.com ")); n != 2 {
+		t.Errorf("mbox has %d From_ lines, want 2:\n%s", n, content)
+	}
+	if !strings.Contains(content, "\n>From the team\n") {
+		t.Error("body lines starting with 'From ' should be quoted")
+	}
+	if strings.Contains(content, "\r") {
+		t.Error("mbox should use LF line endings")
+	}
+}
+
+func TestRunMboxResumeDropsUnrecordedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mbox")
+	var calls int32
+
+	opts := Options{Format: FormatMbox, Output: path}
+	if _, err := Run(context.Background(), fakeFetch(&calls, nil), []string{"m1"}, opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// A crash after writing m2 but before recording it
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	f.Write(mboxEntry([]byte(strings.Replace(sampleMessage, "Quarterly report", "Report m2", 1))))
+	f.Close()
+
+	result, err := Run(context.Background(), fakeFetch(&calls, nil), []string{"m1", "m2"}, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Skipped != 1 || result.Exported != 1 {
+		t.Errorf("result = %+v, want 1 skipped and 1 exported", result)
+	}
+
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "Subject: Report m2\n"); n != 1 {
+		t.Errorf("m2 appears %d times in the mbox, want 1", n)
+	}
+}
+
+func TestCheckpointAppendsOneLinePerMessage(t *testing.T) {
+	dir := t.TempDir()
+	var calls int32
+	ids := []string{"m1", "m2", "m3", "m4"}
+
+	opts := Options{Format: FormatEML, Output: dir, Concurrency: 2}
+	if _, err := Run(context.Background(), fakeFetch(&calls, nil), ids, opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".octl-export.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	// A start marker plus one entry per message
+	if n := strings.Count(string(data), "\n"); n != len(ids)+1 {
+		t.Errorf("checkpoint has %d lines, want %d:\n%s", n, len(ids)+1, data)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestRunRejectsUnknownFormat(t *testing.T) {
+	var calls int32
+	_, err := Run(context.Background(), fakeFetch(&calls, nil), nil, Options{Format: "pst", Output: t.TempDir()})
+	if err == nil {
+		t.Error("Run() with unknown format should fail")
+	}
+}
+
+func TestMboxEntryQuoting(t *testing.T) {
+	entry := string(mboxEntry([]byte("Subject: x\n\nFrom here\n>From there\nplain\n")))
+	for _, want := range []string{"\n>From here\n", "\n>>From there\n", "\nplain\n\n"} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("mboxEntry() missing %q in:\n%s", want, entry)
+		}
+	}
+	if !strings.HasPrefix(entry, "From MAILER-DAEMON ") {
+		t.Errorf("mboxEntry() should fall back to MAILER-DAEMON, got %q", strings.SplitN(entry, "\n", 2)[0])
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data string
+		want string
+	}{
+		{
+			name: "default template",
+			tmpl: DefaultNameTemplate,
+			data: sampleMessage,
+			want: "2024-01-15_Quarterly report_",
+		},
+		{
+			name: "sender and subject",
+			tmpl: "{{.From}}/{{.Subject}}.eml",
+			data: sampleMessage,
+			want: "jane@example.com_Quarterly report.eml",
+		},
+		{
+			name: "encoded subject is decoded",
+			tmpl: "{{.Subject}}.eml",
+			data: "Subject: =?utf-8?q?Caf=C3=A9?=\r\n\r\nbody",
+			want: "Café.eml",
+		},
+		{
+			name: "missing headers fall back",
+			tmpl: "{{.Subject}}.eml",
+			data: "not a message",
+			want: "no-subject.eml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseNameTemplate(tt.tmpl)
+			if err != nil {
+				t.Fatalf("parseNameTemplate() error = %v", err)
+			}
+			got, err := n.Execute("msg-id", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Execute() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseNameTemplate("{{.Nope"); err == nil {
+		t.Error("parseNameTemplate() should reject invalid templates")
+	}
+}
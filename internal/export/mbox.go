@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mboxSink appends messages to a single mboxrd file
+type mboxSink struct {
+	path string
+	file *os.File
+	size int64
+}
+
+func newMboxSink(path string) (*mboxSink, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mbox: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open mbox: %w", err)
+	}
+
+	return &mboxSink{path: path, file: file, size: info.Size()}, nil
+}
+
+func (s *mboxSink) Write(id string, data []byte) error {
+	n, err := s.file.Write(mboxEntry(data))
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write mbox: %w", err)
+	}
+	return nil
+}
+
+func (s *mboxSink) Offset() int64 {
+	return s.size
+}
+
+// Truncate cuts the mbox back to offset. An mbox that is already shorter,
+// for example because it was replaced, is left alone.
+func (s *mboxSink) Truncate(offset int64) error {
+	if offset >= s.size {
+		return nil
+	}
+	if err := s.file.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate mbox: %w", err)
+	}
+	s.size = offset
+	return nil
+}
+
+func (s *mboxSink) CheckpointPath() string {
+	return s.path + ".checkpoint.jsonl"
+}
+
+func (s *mboxSink) Close() error {
+	return s.file.Close()
+}
+
+// mboxEntry formats a MIME message as an mboxrd entry: a From_ separator
+// line, the message with LF line endings and ">From " quoting, and a
+// trailing blank line
+func mboxEntry(data []byte) []byte {
+	var buf bytes.Buffer
+
+	sender := "MAILER-DAEMON"
+	date := time.Now()
+	if msg, err := mail.ReadMessage(bytes.NewReader(data)); err == nil {
+		if addrs, err := msg.Header.AddressList("From"); err == nil && len(addrs) > 0 {
+			sender = addrs[0].Address
+		}
+		if d, err := msg.Header.Date(); err == nil {
+			date = d
+		}
+	}
+
+	fmt.Fprintf(&buf, "From %s %s\n", sender, date.UTC().Format(time.ANSIC))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = ">" + line
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes()
+}
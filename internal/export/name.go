@@ -0,0 +1,113 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NameData is the data available to file name templates
+type NameData struct {
+	ID      string
+	ShortID string
+	Subject string
+	From    string
+	Date    string
+	Time    time.Time
+}
+
+// nameTemplate renders file names for exported messages
+type nameTemplate struct {
+	tmpl *template.Template
+}
+
+func parseNameTemplate(text string) (*nameTemplate, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	return &nameTemplate{tmpl: tmpl}, nil
+}
+
+// Execute renders a safe file name for a message
+func (n *nameTemplate) Execute(id string, data []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, nameData(id, data)); err != nil {
+		return "", fmt.Errorf("failed to render file name: %w", err)
+	}
+
+	name := sanitizeFileName(buf.String())
+	if name == "" {
+		name = sanitizeFileName(nameData(id, data).ShortID + ".eml")
+	}
+	return name, nil
+}
+
+// nameData extracts template fields from the message headers
+func nameData(id string, data []byte) NameData {
+	sum := sha1.Sum([]byte(id))
+	nd := NameData{
+		ID:      id,
+		ShortID: hex.EncodeToString(sum[:4]),
+		Subject: "no-subject",
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nd
+	}
+
+	decoder := new(mime.WordDecoder)
+	if subject := msg.Header.Get("Subject"); subject != "" {
+		if decoded, err := decoder.DecodeHeader(subject); err == nil {
+			subject = decoded
+		}
+		nd.Subject = subject
+	}
+
+	if addrs, err := msg.Header.AddressList("From"); err == nil && len(addrs) > 0 {
+		nd.From = addrs[0].Address
+	}
+
+	if d, err := msg.Header.Date(); err == nil {
+		nd.Time = d
+		nd.Date = d.Format("2006-01-02")
+	}
+
+	return nd
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names and
+// limits the length
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.TrimSpace(name)
+	name = strings.Trim(name, ".")
+
+	const maxLen = 200
+	if len(name) > maxLen {
+		ext := ""
+		if i := strings.LastIndex(name, "."); i > len(name)-10 {
+			ext = name[i:]
+		}
+		name = strings.ToValidUTF8(name[:maxLen-len(ext)], "") + ext
+	}
+
+	return name
+}
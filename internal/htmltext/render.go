@@ -0,0 +1,686 @@
+// Package htmltext renders HTML message bodies as readable plain text.
+package htmltext
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Options controls rendering
+type Options struct {
+	// Width wraps text at this many columns; 0 disables wrapping
+	Width int
+	// Hyperlinks marks links with OSC 8 terminal escapes instead of
+	// numbered footnotes
+	Hyperlinks bool
+}
+
+// minWrap keeps deeply indented text from wrapping to a sliver
+const minWrap = 20
+
+// skipped elements have no readable content
+var skipped = map[string]bool{
+	"head": true, "style": true, "script": true, "template": true, "title": true, "noscript": true,
+}
+
+// paragraphs are blocks separated from their neighbours by a blank line
+var paragraphs = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "hr": true, "address": true, "figure": true, "fieldset": true,
+}
+
+// lines are blocks that start on a new line
+var lines = map[string]bool{
+	"div": true, "section": true, "article": true, "header": true, "footer": true, "nav": true,
+	"aside": true, "main": true, "center": true, "form": true, "dl": true, "dt": true, "dd": true,
+	"tr": true, "caption": true, "figcaption": true, "summary": true, "details": true,
+}
+
+// Render converts HTML to plain text. Style, script, and head content is
+// dropped; lists, quotes, and tables keep their shape, and links become
+// numbered footnotes or terminal hyperlinks.
+func Render(src string, opts Options) string {
+	r := &renderer{opts: opts}
+	r.frames = []*frame{{width: opts.Width}}
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, or malformed input; render what was read
+			break
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.TextToken:
+			if r.skip == 0 {
+				r.text(tok.Data)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skipped[tok.Data] {
+				if tt == html.StartTagToken {
+					r.skip++
+				}
+				continue
+			}
+			if r.skip == 0 {
+				r.start(tok)
+			}
+		case html.EndTagToken:
+			if skipped[tok.Data] {
+				if r.skip > 0 {
+					r.skip--
+				}
+				continue
+			}
+			if r.skip == 0 {
+				r.end(tok)
+			}
+		}
+	}
+
+	// Close anything left open
+	for len(r.tables) > 0 {
+		r.endTable()
+	}
+	for len(r.frames) > 1 {
+		r.popFrame()
+	}
+	f := r.cur()
+	f.flush()
+
+	out := strings.Join(trimBlank(f.lines), "\n")
+	if !opts.Hyperlinks && len(r.links) > 0 {
+		var b strings.Builder
+		b.WriteString(out)
+		b.WriteString("\n")
+		for i, link := range r.links {
+			fmt.Fprintf(&b, "\n[%d] %s", i+1, link)
+		}
+		out = b.String()
+	}
+	return out
+}
+
+// renderer holds the state of one Render call
+type renderer struct {
+	opts Options
+	// frames stacks output targets; each open table cell gets its own
+	frames []*frame
+	tables []*table
+	// skip counts open elements whose content is dropped
+	skip int
+	// anchors stacks open links
+	anchors []*anchor
+	links   []string
+}
+
+// anchor is an open link. Block elements and line breaks inside it flush
+// its text before it closes, so the frame marks each flushed piece and
+// the anchor keeps what was already written.
+type anchor struct {
+	// href is empty for links that are not followed, such as #fragments
+	href string
+	// hyperlink marks flushed text with OSC 8 escapes
+	hyperlink bool
+	// start is the offset of the unflushed link text in the frame's inline
+	start   int
+	frame   *frame
+	flushed []string
+}
+
+// table collects the cells of an open table
+type table struct {
+	rows     [][][]string
+	cellOpen bool
+}
+
+func (r *renderer) cur() *frame {
+	return r.frames[len(r.frames)-1]
+}
+
+func (r *renderer) text(s string) {
+	r.cur().inline.WriteString(s)
+}
+
+func (r *renderer) start(tok html.Token) {
+	f := r.cur()
+	name := tok.Data
+
+	switch {
+	case name == "br":
+		if f.pre > 0 {
+			f.inline.WriteString("\n")
+		} else {
+			f.flush()
+		}
+		return
+	case name == "a":
+		a := &anchor{href: linkTarget(attr(tok, "href")), hyperlink: r.opts.Hyperlinks, start: f.inline.Len(), frame: f}
+		r.anchors = append(r.anchors, a)
+		f.anchors = append(f.anchors, a)
+		return
+	case name == "img":
+		if alt := strings.TrimSpace(attr(tok, "alt")); alt != "" {
+			f.inline.WriteString("[" + alt + "]")
+		}
+		return
+	case name == "table":
+		f.paragraph()
+		r.tables = append(r.tables, &table{})
+		return
+	case name == "tr":
+		if t := r.table(); t != nil {
+			r.closeCell(t)
+			t.rows = append(t.rows, nil)
+			return
+		}
+	case name == "td" || name == "th":
+		if t := r.table(); t != nil {
+			r.closeCell(t)
+			if len(t.rows) == 0 {
+				t.rows = append(t.rows, nil)
+			}
+			r.frames = append(r.frames, &frame{width: f.available()})
+			t.cellOpen = true
+			return
+		}
+	case name == "ul" || name == "ol":
+		if len(f.lists) == 0 {
+			f.paragraph()
+		} else {
+			f.flush()
+		}
+		l := &list{ordered: name == "ol", next: 1}
+		if n, err := strconv.Atoi(attr(tok, "start")); err == nil {
+			l.next = n
+		}
+		f.lists = append(f.lists, l)
+		return
+	case name == "li":
+		f.flush()
+		if len(f.lists) == 0 {
+			f.lists = append(f.lists, &list{})
+		}
+		l := f.lists[len(f.lists)-1]
+		if l.ordered {
+			l.marker = strconv.Itoa(l.next) + ". "
+			l.next++
+		} else {
+			l.marker = "- "
+		}
+		f.pending = true
+		return
+	case name == "blockquote":
+		f.paragraph()
+		f.quote++
+		return
+	case name == "pre":
+		f.paragraph()
+		f.pre++
+		return
+	case name == "hr":
+		f.paragraph()
+		f.emit("---")
+		f.paragraph()
+		return
+	}
+
+	switch {
+	case paragraphs[name]:
+		f.paragraph()
+	case lines[name]:
+		f.flush()
+	}
+}
+
+func (r *renderer) end(tok html.Token) {
+	f := r.cur()
+	name := tok.Data
+
+	switch name {
+	case "a":
+		if len(r.anchors) == 0 {
+			return
+		}
+		a := r.anchors[len(r.anchors)-1]
+		r.anchors = r.anchors[:len(r.anchors)-1]
+		a.frame.anchors = slices.DeleteFunc(a.frame.anchors, func(open *anchor) bool { return open == a })
+		if a.frame == f {
+			r.link(f, a)
+		}
+	case "table":
+		r.endTable()
+		r.cur().paragraph()
+	case "td", "th":
+		if t := r.table(); t != nil {
+			r.closeCell(t)
+		}
+	case "tr":
+		if t := r.table(); t != nil {
+			r.closeCell(t)
+		}
+	case "ul", "ol":
+		f.flush()
+		if len(f.lists) > 0 {
+			f.lists = f.lists[:len(f.lists)-1]
+		}
+		if len(f.lists) == 0 {
+			f.paragraph()
+		}
+	case "li":
+		f.flush()
+	case "blockquote":
+		f.paragraph()
+		if f.quote > 0 {
+			f.quote--
+		}
+	case "pre":
+		f.flush()
+		if f.pre > 0 {
+			f.pre--
+		}
+		f.paragraph()
+	default:
+		switch {
+		case paragraphs[name]:
+			f.paragraph()
+		case lines[name]:
+			f.flush()
+		}
+	}
+}
+
+// linkTarget returns the href of a link worth showing, or ""
+func linkTarget(href string) string {
+	href = strings.TrimSpace(href)
+	if strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	return href
+}
+
+// link finishes an anchor, marking its text as a hyperlink or giving it a
+// footnote. Text flushed while the anchor was open is already marked as a
+// hyperlink, and a footnote goes on its last line when nothing followed.
+func (r *renderer) link(f *frame, a *anchor) {
+	if a.href == "" {
+		return
+	}
+
+	all := f.inline.String()
+	if a.start > len(all) {
+		return
+	}
+	before, content := all[:a.start], all[a.start:]
+	text := strings.TrimSpace(content)
+	if text == "" && len(a.flushed) == 0 {
+		return
+	}
+
+	if r.opts.Hyperlinks {
+		if text != "" {
+			f.inline.Reset()
+			f.inline.WriteString(before + markLink(content, a.href))
+		}
+		return
+	}
+
+	shown := strings.Join(append(a.flushed, strings.Fields(text)...), " ")
+	if a.href == shown || a.href == "mailto:"+shown {
+		return
+	}
+
+	n := 0
+	for i, link := range r.links {
+		if link == a.href {
+			n = i + 1
+		}
+	}
+	if n == 0 {
+		r.links = append(r.links, a.href)
+		n = len(r.links)
+	}
+	marker := fmt.Sprintf("[%d]", n)
+
+	if text == "" {
+		// The link text ended in a block or line break and is already
+		// written
+		f.lines[len(f.lines)-1] += marker
+		return
+	}
+	lead := content[:strings.Index(content, text)]
+	trail := content[len(lead)+len(text):]
+	f.inline.Reset()
+	f.inline.WriteString(before + lead + text + marker + trail)
+}
+
+// markLink wraps the text in content in OSC 8 escapes for href, keeping
+// the surrounding whitespace outside the link
+func markLink(content, href string) string {
+	text := strings.TrimSpace(content)
+	if text == "" {
+		return content
+	}
+	lead := content[:strings.Index(content, text)]
+	trail := content[len(lead)+len(text):]
+	return lead + "\x1b]8;;" + href + "\x1b\\" + text + "\x1b]8;;\x1b\\" + trail
+}
+
+func (r *renderer) table() *table {
+	if len(r.tables) == 0 {
+		return nil
+	}
+	return r.tables[len(r.tables)-1]
+}
+
+// closeCell ends the open cell of t, if any, and stores its lines
+func (r *renderer) closeCell(t *table) {
+	if !t.cellOpen || len(r.frames) < 2 {
+		return
+	}
+	cell := r.popFrame()
+	row := len(t.rows) - 1
+	t.rows[row] = append(t.rows[row], trimBlank(cell))
+	t.cellOpen = false
+}
+
+// popFrame closes the innermost frame and returns its lines
+func (r *renderer) popFrame() []string {
+	f := r.cur()
+	f.flush()
+	r.frames = r.frames[:len(r.frames)-1]
+	return f.lines
+}
+
+// endTable closes the innermost table and lays it out in the enclosing
+// frame
+func (r *renderer) endTable() {
+	t := r.table()
+	if t == nil {
+		return
+	}
+	r.closeCell(t)
+	r.tables = r.tables[:len(r.tables)-1]
+
+	f := r.cur()
+	if isDataTable(t) {
+		f.paragraph()
+		for _, line := range layoutTable(t, f.available()) {
+			f.emit(line)
+		}
+		f.paragraph()
+		return
+	}
+
+	// Layout tables, common in HTML mail, read as a sequence of blocks
+	for _, row := range t.rows {
+		for _, cell := range row {
+			if len(cell) == 0 {
+				continue
+			}
+			f.flush()
+			for _, line := range cell {
+				f.emit(line)
+			}
+		}
+	}
+}
+
+// isDataTable reports whether a table holds data in rows and columns, as
+// opposed to a layout table: some row has several cells and every cell is
+// a single line
+func isDataTable(t *table) bool {
+	columns := 0
+	for _, row := range t.rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+		for _, cell := range row {
+			if len(cell) > 1 {
+				return false
+			}
+		}
+	}
+	return columns > 1
+}
+
+// layoutTable aligns a data table's columns, or separates cells with bars
+// when the aligned table is wider than width
+func layoutTable(t *table, width int) []string {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := visibleLen(cellText(cell)); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	total := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	aligned := width <= 0 || total <= width
+
+	var out []string
+	for _, row := range t.rows {
+		if len(row) == 0 {
+			continue
+		}
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cellText(cell)
+		}
+		if !aligned {
+			out = append(out, strings.Join(cells, " | "))
+			continue
+		}
+		for i := range cells[:len(cells)-1] {
+			cells[i] += strings.Repeat(" ", widths[i]-visibleLen(cells[i]))
+		}
+		out = append(out, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	return out
+}
+
+func cellText(cell []string) string {
+	if len(cell) == 0 {
+		return ""
+	}
+	return cell[0]
+}
+
+// list is an open ul or ol
+type list struct {
+	ordered bool
+	next    int
+	// marker is the bullet or number of the current item
+	marker string
+}
+
+// frame accumulates rendered lines for the document or a table cell
+type frame struct {
+	width  int
+	lines  []string
+	inline strings.Builder
+	// blank requests a blank line before the next line, quoted to
+	// blankQuote levels so it does not stretch into a quote that starts or
+	// ends at the break
+	blank      bool
+	blankQuote int
+	quote      int
+	pre        int
+	lists      []*list
+	// pending means the current list item's marker has not been written
+	pending bool
+	// anchors are the links open in this frame
+	anchors []*anchor
+}
+
+// paragraph ends the current block and separates the next with a blank line
+func (f *frame) paragraph() {
+	f.flush()
+	if !f.blank || f.quote < f.blankQuote {
+		f.blankQuote = f.quote
+	}
+	f.blank = true
+}
+
+// flush writes the pending inline text as wrapped lines
+func (f *frame) flush() {
+	f.flushAnchors()
+	text := f.inline.String()
+	f.inline.Reset()
+
+	if f.pre > 0 {
+		text = strings.TrimPrefix(text, "\n")
+		text = strings.TrimRight(text, "\n")
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(text, "\n") {
+			f.emit(strings.TrimRight(line, " \t\r"))
+		}
+		return
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return
+	}
+
+	avail := f.available()
+	var line strings.Builder
+	lineLen := 0
+	for _, word := range words {
+		n := visibleLen(word)
+		if lineLen > 0 && avail > 0 && lineLen+1+n > avail {
+			f.emit(line.String())
+			line.Reset()
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			line.WriteString(" ")
+			lineLen++
+		}
+		line.WriteString(word)
+		lineLen += n
+	}
+	f.emit(line.String())
+}
+
+// flushAnchors records the text of open links before a flush, marking it
+// as a hyperlink, and restarts them at the beginning of the next line
+func (f *frame) flushAnchors() {
+	all := f.inline.String()
+	for i, a := range f.anchors {
+		if a.start > len(all) {
+			a.start = len(all)
+		}
+		if text := strings.TrimSpace(all[a.start:]); text != "" {
+			a.flushed = append(a.flushed, strings.Join(strings.Fields(text), " "))
+			// Nested links are invalid HTML; only the innermost is marked
+			if a.hyperlink && a.href != "" && i == len(f.anchors)-1 {
+				all = all[:a.start] + markLink(all[a.start:], a.href)
+			}
+		}
+		a.start = 0
+	}
+	if len(f.anchors) > 0 {
+		f.inline.Reset()
+		f.inline.WriteString(all)
+	}
+}
+
+// available returns the wrap width left after indentation, or 0 for none
+func (f *frame) available() int {
+	if f.width <= 0 {
+		return 0
+	}
+	avail := f.width - visibleLen(f.quotePrefix()) - f.indent()
+	if avail < minWrap {
+		avail = minWrap
+	}
+	return avail
+}
+
+// emit writes one line with the quote and list prefixes
+func (f *frame) emit(line string) {
+	if f.blank {
+		if len(f.lines) > 0 && strings.TrimSpace(strings.ReplaceAll(f.lines[len(f.lines)-1], ">", "")) != "" {
+			depth := min(f.quote, f.blankQuote)
+			f.lines = append(f.lines, strings.TrimRight(strings.Repeat("> ", depth), " "))
+		}
+		f.blank = false
+	}
+
+	var prefix strings.Builder
+	prefix.WriteString(f.quotePrefix())
+	for i, l := range f.lists {
+		switch {
+		case i < len(f.lists)-1:
+			prefix.WriteString(strings.Repeat(" ", len(l.marker)))
+		case f.pending:
+			prefix.WriteString(l.marker)
+		default:
+			prefix.WriteString(strings.Repeat(" ", len(l.marker)))
+		}
+	}
+	f.pending = false
+
+	f.lines = append(f.lines, strings.TrimRight(prefix.String()+line, " "))
+}
+
+func (f *frame) quotePrefix() string {
+	return strings.Repeat("> ", f.quote)
+}
+
+// indent is the width of the list markers before each line
+func (f *frame) indent() int {
+	n := 0
+	for _, l := range f.lists {
+		n += len(l.marker)
+	}
+	return n
+}
+
+// trimBlank drops leading and trailing blank lines
+func trimBlank(lines []string) []string {
+	isBlank := func(s string) bool {
+		return strings.TrimSpace(strings.ReplaceAll(s, ">", "")) == ""
+	}
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+var osc8 = regexp.MustCompile("\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+// visibleLen counts the characters of s a terminal displays
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(osc8.ReplaceAllString(s, ""))
+}
+
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
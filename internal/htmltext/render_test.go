@@ -0,0 +1,210 @@
+package htmltext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "Just plain text",
+			want:  "Just plain text",
+		},
+		{
+			name:  "empty input",
+			input: "",
+			want:  "",
+		},
+		{
+			name:  "paragraphs are separated by a blank line",
+			input: "<p>Para1</p><p>Para2</p>",
+			want:  "Para1\n\nPara2",
+		},
+		{
+			name:  "line breaks",
+			input: "Line1<br>Line2<br/>Line3<br />Line4",
+			want:  "Line1\nLine2\nLine3\nLine4",
+		},
+		{
+			name:  "nested inline tags",
+			input: "<div><span><b>Bo</b>ld</span> text</div>",
+			want:  "Bold text",
+		},
+		{
+			name:  "entities",
+			input: "&amp; &lt; &gt; &quot; &#39; &eacute; &mdash; &#x263A; a&nbsp;b",
+			want:  "& < > \" ' é — ☺ a b",
+		},
+		{
+			name:  "head, style, and script are skipped",
+			input: "<html><head><title>T</title><style>p { color: red }</style></head><body><script>var x = 1 < 2;</script><p>Body</p></body></html>",
+			want:  "Body",
+		},
+		{
+			name:  "whitespace is collapsed",
+			input: "<p>\n  Hello\n\t world  </p>",
+			want:  "Hello world",
+		},
+		{
+			name:  "outlook spacer paragraphs",
+			input: `<div class=WordSection1><p class=MsoNormal>Hi<o:p></o:p></p><p class=MsoNormal><o:p>&nbsp;</o:p></p><p class=MsoNormal>Thanks<o:p></o:p></p></div>`,
+			want:  "Hi\n\nThanks",
+		},
+		{
+			name:  "links become footnotes",
+			input: `<p>See <a href="https://example.com/a">the docs</a>, <a href="https://example.com/b">this</a> and <a href="https://example.com/a">again</a>.</p>`,
+			want:  "See the docs[1], this[2] and again[1].\n\n[1] https://example.com/a\n[2] https://example.com/b",
+		},
+		{
+			name:  "links that show their target get no footnote",
+			input: `<a href="https://example.com">https://example.com</a> <a href="mailto:a@example.com">a@example.com</a> <a href="#top">top</a>`,
+			want:  "https://example.com a@example.com top",
+		},
+		{
+			name:  "hyperlinks",
+			input: `Read <a href="https://example.com"> the docs </a>now`,
+			opts:  Options{Hyperlinks: true},
+			want:  "Read \x1b]8;;https://example.com\x1b\\the docs\x1b]8;;\x1b\\ now",
+		},
+		{
+			name:  "lists",
+			input: `<p>Intro</p><ul><li>One</li><li>Two<ol start="9"><li>Nine</li><li>Ten</li></ol></li></ul><p>After</p>`,
+			want:  "Intro\n\n- One\n- Two\n  9. Nine\n  10. Ten\n\nAfter",
+		},
+		{
+			name:  "list items without end tags",
+			input: `<ol><li>One<li>Two</ol>`,
+			want:  "1. One\n2. Two",
+		},
+		{
+			name:  "blockquotes",
+			input: `<p>Reply</p><blockquote><p>Original</p><blockquote>Older</blockquote></blockquote><p>Sign-off</p>`,
+			want:  "Reply\n\n> Original\n>\n> > Older\n\nSign-off",
+		},
+		{
+			name:  "preformatted text keeps its layout",
+			input: "<pre>\n  a  b\n    c</pre>",
+			want:  "  a  b\n    c",
+		},
+		{
+			name:  "data tables are aligned",
+			input: `<table><tr><th>Name</th><th>Open</th></tr><tr><td>Acme</td><td>3</td></tr><tr><td>Beta Corp</td><td>10</td></tr></table>`,
+			want:  "Name       Open\nAcme       3\nBeta Corp  10",
+		},
+		{
+			name:  "narrow data tables use bars",
+			input: `<table><tr><td>A fairly long first cell</td><td>And a second cell</td></tr></table>`,
+			opts:  Options{Width: 30},
+			want:  "A fairly long first cell | And a second cell",
+		},
+		{
+			name:  "layout tables read as blocks",
+			input: `<table><tr><td><table><tr><td><p>First</p><p>Second</p></td></tr></table></td></tr><tr><td>Footer</td></tr></table>`,
+			want:  "First\n\nSecond\nFooter",
+		},
+		{
+			name:  "cells without end tags",
+			input: `<table><tr><td>a<td>b<tr><td>c<td>d</table>`,
+			want:  "a  b\nc  d",
+		},
+		{
+			name:  "images show their alt text",
+			input: `<img src="logo.png" alt="Contoso"><img src="pixel.gif">`,
+			want:  "[Contoso]",
+		},
+		{
+			name:  "horizontal rule",
+			input: `<p>Above</p><hr><p>Below</p>`,
+			want:  "Above\n\n---\n\nBelow",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.input, tt.opts); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLinkAcrossBreaks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "block inside link",
+			input: `<a href="https://example.com/verify"><div>Verify account</div></a>`,
+			want:  "Verify account[1]\n\n[1] https://example.com/verify",
+		},
+		{
+			name:  "line break inside link",
+			input: `<a href=u>click<br>here</a> now`,
+			want:  "click\nhere[1] now\n\n[1] u",
+		},
+		{
+			name:  "block link showing its url",
+			input: `<a href="https://example.com/"><p>https://example.com/</p></a>`,
+			want:  "https://example.com/",
+		},
+		{
+			name:  "block inside hyperlink",
+			input: `<a href="https://example.com/verify"><div>Verify account</div></a>`,
+			opts:  Options{Hyperlinks: true},
+			want:  "\x1b]8;;https://example.com/verify\x1b\\Verify account\x1b]8;;\x1b\\",
+		},
+		{
+			name:  "line break inside hyperlink",
+			input: `<a href=u>click<br>here</a>`,
+			opts:  Options{Hyperlinks: true},
+			want:  "\x1b]8;;u\x1b\\click\x1b]8;;\x1b\\\n\x1b]8;;u\x1b\\here\x1b]8;;\x1b\\",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.input, tt.opts); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWraps(t *testing.T) {
+	input := `<p>The quick brown fox jumps over the lazy dog again and again.</p>` +
+		`<ul><li>A list item long enough that it has to wrap</li></ul>` +
+		`<blockquote>A quoted line long enough that it has to wrap</blockquote>`
+	want := strings.Join([]string{
+		"The quick brown fox jumps over the lazy",
+		"dog again and again.",
+		"",
+		"- A list item long enough that it has to",
+		"  wrap",
+		"",
+		"> A quoted line long enough that it has",
+		"> to wrap",
+	}, "\n")
+
+	if got := Render(input, Options{Width: 40}); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderWrapIgnoresHyperlinkEscapes(t *testing.T) {
+	input := `<p><a href="https://example.com/a/very/long/url/that/should/not/count">word</a> two three four five six</p>`
+	got := Render(input, Options{Width: 30, Hyperlinks: true})
+	lines := strings.Split(got, "\n")
+	if len(lines) != 1 {
+		t.Errorf("Render() wrapped into %d lines: %q", len(lines), got)
+	}
+}
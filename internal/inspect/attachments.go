@@ -0,0 +1,114 @@
+package inspect
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// Attachment is an attachment with the risk its type carries
+type Attachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int32  `json:"size"`
+	Extension   string `json:"extension"`
+	// Risk is "high", "medium", or "low"
+	Risk   string `json:"risk"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// executable types run code when opened
+var executable = map[string]bool{
+	".exe": true, ".scr": true, ".com": true, ".pif": true, ".bat": true, ".cmd": true,
+	".js": true, ".jse": true, ".vbs": true, ".vbe": true, ".wsf": true, ".ps1": true,
+	".hta": true, ".msi": true, ".jar": true, ".lnk": true, ".cpl": true, ".reg": true,
+	".dll": true, ".iso": true, ".img": true, ".vhd": true, ".one": true, ".appx": true,
+}
+
+// risky types often carry phishing pages, macros, or hidden payloads
+var risky = map[string]string{
+	".html":  "HTML attachments often hold credential phishing pages",
+	".htm":   "HTML attachments often hold credential phishing pages",
+	".shtml": "HTML attachments often hold credential phishing pages",
+	".svg":   "SVG images can contain scripts",
+	".docm":  "macro-enabled document",
+	".xlsm":  "macro-enabled workbook",
+	".pptm":  "macro-enabled presentation",
+	".xlam":  "macro-enabled add-in",
+	".zip":   "archives can hide executables from scanning",
+	".7z":    "archives can hide executables from scanning",
+	".rar":   "archives can hide executables from scanning",
+	".gz":    "archives can hide executables from scanning",
+	".tar":   "archives can hide executables from scanning",
+}
+
+// documentTypes are extensions attackers put before a real one to disguise it
+var documentTypes = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".txt": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".csv": true, ".ppt": true, ".pptx": true,
+}
+
+// ClassifyAttachment judges the risk of an attachment from its name and
+// content type
+func ClassifyAttachment(a mail.AttachmentInfo) Attachment {
+	name := strings.TrimSpace(a.Name)
+	ext := strings.ToLower(filepath.Ext(name))
+	result := Attachment{
+		Name:        name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Extension:   ext,
+		Risk:        "low",
+	}
+
+	inner := strings.ToLower(filepath.Ext(strings.TrimSuffix(name, filepath.Ext(name))))
+	switch {
+	case strings.ContainsRune(name, '\u202e'):
+		result.Risk = "high"
+		result.Reason = "right-to-left override hides the real extension"
+	case executable[ext] && documentTypes[inner]:
+		result.Risk = "high"
+		result.Reason = "executable disguised with a double extension"
+	case executable[ext]:
+		result.Risk = "high"
+		result.Reason = "executable or disk image"
+	case risky[ext] != "":
+		result.Risk = "medium"
+		result.Reason = risky[ext]
+	case ext != "" && contentTypeMismatch(ext, a.ContentType):
+		result.Risk = "medium"
+		result.Reason = "content type " + a.ContentType + " does not match the extension"
+	}
+	return result
+}
+
+// contentTypeMismatch reports whether a content type contradicts the
+// extension, ignoring generic types
+func contentTypeMismatch(ext, contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	if contentType == "" || contentType == "application/octet-stream" {
+		return false
+	}
+
+	expected := mime.TypeByExtension(ext)
+	if expected == "" {
+		return false
+	}
+	if i := strings.Index(expected, ";"); i >= 0 {
+		expected = expected[:i]
+	}
+	if strings.Contains(contentType, "x-msdownload") || strings.Contains(contentType, "x-dosexec") {
+		return true
+	}
+	// Only compare the broad family; mailers disagree on exact types
+	family := func(t string) string {
+		major, _, _ := strings.Cut(t, "/")
+		return major
+	}
+	return family(expected) != family(contentType)
+}
@@ -0,0 +1,121 @@
+package inspect
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// AuthResults holds the sender authentication verdicts recorded by the
+// receiving server. Empty fields were not reported.
+type AuthResults struct {
+	SPF      string `json:"spf,omitempty"`
+	DKIM     string `json:"dkim,omitempty"`
+	DMARC    string `json:"dmarc,omitempty"`
+	CompAuth string `json:"compauth,omitempty"`
+	ARC      string `json:"arc,omitempty"`
+	// MailFrom is the envelope sender domain SPF checked
+	MailFrom string `json:"smtp_mailfrom,omitempty"`
+	// DKIMDomain is the domain that signed the message
+	DKIMDomain string `json:"dkim_domain,omitempty"`
+	// HeaderFrom is the From domain DMARC aligned against
+	HeaderFrom string `json:"header_from,omitempty"`
+	// Source names the header the verdicts came from
+	Source string `json:"source,omitempty"`
+}
+
+var (
+	authMethod   = regexp.MustCompile(`(?i)\b(spf|dkim|dmarc|compauth|arc)\s*=\s*([a-z]+)`)
+	authProperty = regexp.MustCompile(`(?i)\b(smtp\.mailfrom|header\.d|header\.from)\s*=\s*([^\s;]+)`)
+	arcInstance  = regexp.MustCompile(`(?i)\bi\s*=\s*(\d+)`)
+	arcCV        = regexp.MustCompile(`(?i)\bcv\s*=\s*([a-z]+)`)
+	comments     = regexp.MustCompile(`\([^()]*\)`)
+)
+
+// ParseAuthResults reads the verdicts from the first Authentication-Results
+// header, which the receiving server adds on top; later ones may have been
+// forged by the sender. Without one, the newest ARC-Authentication-Results
+// is used, as for forwarded mail.
+func ParseAuthResults(headers []mail.Header) AuthResults {
+	var r AuthResults
+
+	value := mail.HeaderValue(headers, "Authentication-Results")
+	r.Source = "Authentication-Results"
+	if value == "" {
+		value = newestARC(mail.HeaderValues(headers, "ARC-Authentication-Results"))
+		r.Source = "ARC-Authentication-Results"
+	}
+	if value == "" {
+		r.Source = ""
+	}
+
+	// Each clause holds one method's verdict and the properties it checked
+	for _, clause := range strings.Split(comments.ReplaceAllString(value, ""), ";") {
+		m := authMethod.FindStringSubmatch(clause)
+		if m == nil {
+			continue
+		}
+		verdict := strings.ToLower(m[2])
+		props := map[string]string{}
+		for _, p := range authProperty.FindAllStringSubmatch(clause, -1) {
+			v := strings.ToLower(strings.Trim(p[2], `"<>`))
+			if i := strings.LastIndex(v, "@"); i >= 0 {
+				v = v[i+1:]
+			}
+			props[strings.ToLower(p[1])] = v
+		}
+
+		switch strings.ToLower(m[1]) {
+		case "spf":
+			if r.SPF == "" {
+				r.SPF, r.MailFrom = verdict, props["smtp.mailfrom"]
+			}
+		case "dkim":
+			// Any passing signature is enough
+			if r.DKIM == "" || (verdict == "pass" && r.DKIM != "pass") {
+				r.DKIM, r.DKIMDomain = verdict, props["header.d"]
+			}
+		case "dmarc":
+			if r.DMARC == "" {
+				r.DMARC, r.HeaderFrom = verdict, props["header.from"]
+			}
+		case "compauth":
+			r.CompAuth = first(r.CompAuth, verdict)
+		case "arc":
+			r.ARC = first(r.ARC, verdict)
+		}
+	}
+
+	if r.ARC == "" {
+		if seal := newestARC(mail.HeaderValues(headers, "ARC-Seal")); seal != "" {
+			if m := arcCV.FindStringSubmatch(seal); m != nil {
+				r.ARC = strings.ToLower(m[1])
+			}
+		}
+	}
+	return r
+}
+
+// newestARC returns the ARC header with the highest instance number
+func newestARC(values []string) string {
+	best, bestN := "", -1
+	for _, v := range values {
+		n := 0
+		if m := arcInstance.FindStringSubmatch(v); m != nil {
+			n, _ = strconv.Atoi(m[1])
+		}
+		if n > bestN {
+			best, bestN = v, n
+		}
+	}
+	return best
+}
+
+func first(current, v string) string {
+	if current != "" {
+		return current
+	}
+	return v
+}
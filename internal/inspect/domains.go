@@ -0,0 +1,186 @@
+package inspect
+
+import (
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// impersonated lists domains phishing commonly imitates; the user's own
+// domains are checked too
+var impersonated = []string{
+	"microsoft.com", "office.com", "office365.com", "outlook.com", "live.com", "sharepoint.com",
+	"onedrive.com", "apple.com", "icloud.com", "google.com", "gmail.com", "amazon.com",
+	"paypal.com", "docusign.com", "docusign.net", "dropbox.com", "adobe.com", "linkedin.com",
+	"facebook.com", "netflix.com", "dhl.com", "fedex.com", "ups.com", "chase.com",
+	"wellsfargo.com", "bankofamerica.com",
+}
+
+// homoglyphs maps characters to the ones they are mistaken for
+var homoglyphs = strings.NewReplacer(
+	"rn", "m", "vv", "w", "cl", "d",
+	"0", "o", "1", "l", "i", "l", "|", "l", "3", "e", "5", "s",
+	// Cyrillic and Greek letters that render like Latin ones
+	"а", "a", "е", "e", "о", "o", "р", "p", "с", "c", "х", "x", "у", "y", "і", "l", "ј", "j",
+	"ο", "o", "α", "a", "ν", "v",
+)
+
+// orgDomain returns the registrable part of a domain, such as example.co.uk
+// for mail.example.co.uk
+func orgDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if org, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return org
+	}
+	return domain
+}
+
+// sameOrg reports whether two domains belong to the same organisation
+func sameOrg(a, b string) bool {
+	return a != "" && b != "" && orgDomain(a) == orgDomain(b)
+}
+
+// addressDomain returns the domain of an email address
+func addressDomain(addr string) string {
+	addr = strings.Trim(strings.TrimSpace(addr), "<>")
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return strings.ToLower(addr[i+1:])
+	}
+	return ""
+}
+
+// isIP reports whether a host is an IP address literal
+func isIP(host string) bool {
+	return net.ParseIP(strings.Trim(host, "[]")) != nil
+}
+
+// isPunycode reports whether a domain has internationalised labels
+func isPunycode(domain string) bool {
+	for _, label := range strings.Split(domain, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+	}
+	return !isASCII(domain)
+}
+
+// Lookalike reports which known domain a domain imitates, if any: the same
+// name after swapping homoglyphs, a one-letter typo of a long name, or a
+// known name disguised inside another organisation's domain
+func Lookalike(domain string, known []string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" || isIP(domain) {
+		return "", false
+	}
+	if unicode, err := idna.ToUnicode(domain); err == nil {
+		domain = unicode
+	}
+
+	org := orgDomain(domain)
+	name := orgName(org)
+	for _, k := range known {
+		k = strings.ToLower(k)
+		if org == orgDomain(k) {
+			return "", false
+		}
+	}
+
+	for _, k := range known {
+		kName := orgName(orgDomain(k))
+		if kName == "" || name == kName {
+			continue
+		}
+		switch {
+		case homoglyphs.Replace(name) == homoglyphs.Replace(kName):
+			return k, true
+		case utf8.RuneCountInString(kName) >= 6 && editDistance(name, kName) == 1:
+			return k, true
+		case embeds(domain, org, k):
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// orgName returns the registrable label, such as example for example.co.uk
+func orgName(org string) string {
+	name, _, _ := strings.Cut(org, ".")
+	return name
+}
+
+// embeds reports whether a domain of another organisation dresses up as
+// a known one: the whole known domain in its subdomains, as in
+// paypal.com.example.net; a homoglyph spelling of the name in a subdomain,
+// as in paypa1-login.example.net; or the name as a hyphenated part of the
+// registrable name, as in paypal-secure.com. A subdomain that is merely the
+// name, such as live.bbc.co.uk or office.example.com, is a common word
+// rather than an imitation.
+func embeds(domain, org, known string) bool {
+	kOrg := orgDomain(known)
+	kName := orgName(kOrg)
+	if utf8.RuneCountInString(kName) < 4 {
+		return false
+	}
+
+	sub := strings.TrimSuffix(strings.TrimSuffix(domain, org), ".")
+	if sub != "" {
+		if strings.Contains("."+homoglyphs.Replace(sub)+".", "."+homoglyphs.Replace(kOrg)+".") {
+			return true
+		}
+		for _, label := range strings.Split(sub, ".") {
+			for _, part := range strings.Split(label, "-") {
+				if part != kName && imitates(part, kName) {
+					return true
+				}
+			}
+		}
+	}
+
+	if name := orgName(org); strings.Contains(name, "-") {
+		for _, part := range strings.Split(name, "-") {
+			if imitates(part, kName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imitates reports whether a label is a name or a homoglyph spelling of it
+func imitates(label, name string) bool {
+	return label == name || homoglyphs.Replace(label) == homoglyphs.Replace(name)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
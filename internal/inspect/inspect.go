@@ -0,0 +1,273 @@
+// Package inspect judges how suspicious a message is from its headers,
+// body, and attachments, without contacting any outside service.
+package inspect
+
+import (
+	"fmt"
+	netmail "net/mail"
+	"strings"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// Finding statuses
+const (
+	StatusPass = "pass"
+	StatusInfo = "info"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Risk levels by total score
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Message is the data an inspection works from
+type Message struct {
+	Headers     []mail.Header
+	Body        string
+	BodyType    string
+	Attachments []mail.AttachmentInfo
+	// OwnDomains are the user's own domains, checked for lookalikes along
+	// with commonly impersonated ones
+	OwnDomains []string
+}
+
+// Finding is the result of one check. Score is the risk it adds.
+type Finding struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// Report is the outcome of an inspection
+type Report struct {
+	Score       int          `json:"score"`
+	Risk        string       `json:"risk"`
+	From        string       `json:"from,omitempty"`
+	ReplyTo     []string     `json:"reply_to,omitempty"`
+	ReturnPath  string       `json:"return_path,omitempty"`
+	Auth        AuthResults  `json:"authentication"`
+	Findings    []Finding    `json:"findings"`
+	Links       []Link       `json:"links,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Inspect runs every check on a message
+func Inspect(msg Message) *Report {
+	r := &Report{Findings: make([]Finding, 0)}
+	known := append(append([]string{}, msg.OwnDomains...), impersonated...)
+
+	from, fromErr := netmail.ParseAddress(mail.HeaderValue(msg.Headers, "From"))
+	fromDomain := ""
+	if fromErr == nil {
+		r.From = from.String()
+		fromDomain = addressDomain(from.Address)
+	}
+
+	r.Auth = ParseAuthResults(msg.Headers)
+	r.checkAuth()
+
+	if fromErr != nil {
+		r.add("From", StatusWarn, 1, "no parseable From header")
+	} else {
+		r.checkDisplayName(from, fromDomain)
+		if imitated, ok := Lookalike(fromDomain, known); ok {
+			r.add("From domain", StatusFail, 3, fmt.Sprintf("%s looks like %s", fromDomain, imitated))
+		} else if isPunycode(fromDomain) {
+			r.add("From domain", StatusWarn, 2, fmt.Sprintf("%s uses international characters", fromDomain))
+		}
+	}
+
+	r.checkReplyTo(msg.Headers, fromDomain, known)
+	r.checkReturnPath(msg.Headers, fromDomain)
+
+	if msg.BodyType == "html" {
+		r.Links = ExtractLinks(msg.Body)
+		r.checkLinks(known)
+	}
+
+	r.checkAttachments(msg.Attachments)
+
+	switch {
+	case r.Score >= 6:
+		r.Risk = RiskHigh
+	case r.Score >= 3:
+		r.Risk = RiskMedium
+	default:
+		r.Risk = RiskLow
+	}
+	return r
+}
+
+func (r *Report) add(check, status string, score int, detail string) {
+	r.Findings = append(r.Findings, Finding{Check: check, Status: status, Score: score, Detail: detail})
+	r.Score += score
+}
+
+// checkAuth scores the SPF, DKIM, DMARC, and composite verdicts
+func (r *Report) checkAuth() {
+	a := r.Auth
+	if a.Source == "" {
+		r.add("Authentication", StatusWarn, 1, "no Authentication-Results header")
+		return
+	}
+
+	verdicts := []struct {
+		name, value, domain string
+		failScore           int
+	}{
+		{"SPF", a.SPF, a.MailFrom, 2},
+		{"DKIM", a.DKIM, a.DKIMDomain, 2},
+		{"DMARC", a.DMARC, a.HeaderFrom, 3},
+		{"CompAuth", a.CompAuth, "", 3},
+	}
+	for _, v := range verdicts {
+		detail := v.value
+		if v.domain != "" {
+			detail += " (" + v.domain + ")"
+		}
+		switch v.value {
+		case "pass", "bestguesspass":
+			r.add(v.name, StatusPass, 0, detail)
+		case "":
+			if v.name != "CompAuth" {
+				r.add(v.name, StatusWarn, 1, "not reported")
+			}
+		case "none", "neutral", "softfail", "temperror":
+			r.add(v.name, StatusWarn, 1, detail)
+		default:
+			r.add(v.name, StatusFail, v.failScore, detail)
+		}
+	}
+
+	if a.ARC != "" {
+		status := StatusInfo
+		if a.ARC == "fail" {
+			status = StatusWarn
+		}
+		r.add("ARC", status, 0, a.ARC)
+	}
+}
+
+// checkDisplayName flags display names that show a different address or
+// domain than the one the mail came from
+func (r *Report) checkDisplayName(from *netmail.Address, fromDomain string) {
+	for _, word := range strings.FieldsFunc(from.Name, func(c rune) bool {
+		return c == ' ' || c == '<' || c == '>' || c == '(' || c == ')' || c == '"' || c == ',' || c == '\''
+	}) {
+		domain := addressDomain(word)
+		if domain == "" && shownHost(word) != "" && strings.Contains(word, ".") {
+			domain = shownHost(word)
+		}
+		if domain != "" && !sameOrg(domain, fromDomain) {
+			r.add("Display name", StatusFail, 3, fmt.Sprintf("name shows %s but the address is at %s", domain, fromDomain))
+			return
+		}
+	}
+}
+
+// checkReplyTo flags replies that would go to another organisation
+func (r *Report) checkReplyTo(headers []mail.Header, fromDomain string, known []string) {
+	value := mail.HeaderValue(headers, "Reply-To")
+	if value == "" {
+		return
+	}
+	addrs, err := netmail.ParseAddressList(value)
+	if err != nil {
+		r.add("Reply-To", StatusWarn, 1, "unparseable Reply-To: "+value)
+		return
+	}
+
+	for _, a := range addrs {
+		r.ReplyTo = append(r.ReplyTo, a.Address)
+		domain := addressDomain(a.Address)
+		switch {
+		case fromDomain != "" && !sameOrg(domain, fromDomain):
+			score := 2
+			detail := fmt.Sprintf("replies go to %s, not %s", domain, fromDomain)
+			if imitated, ok := Lookalike(domain, known); ok {
+				score = 3
+				detail += fmt.Sprintf(" (looks like %s)", imitated)
+			}
+			r.add("Reply-To", StatusWarn, score, detail)
+		default:
+			r.add("Reply-To", StatusPass, 0, a.Address)
+		}
+	}
+}
+
+// checkReturnPath compares the envelope sender with From. Mailing services
+// often differ legitimately, so a mismatch only counts a little.
+func (r *Report) checkReturnPath(headers []mail.Header, fromDomain string) {
+	value := strings.TrimSpace(mail.HeaderValue(headers, "Return-Path"))
+	if value == "" || value == "<>" {
+		return
+	}
+	r.ReturnPath = strings.Trim(value, "<>")
+	domain := addressDomain(value)
+	if fromDomain == "" || sameOrg(domain, fromDomain) {
+		r.add("Return-Path", StatusPass, 0, r.ReturnPath)
+		return
+	}
+	r.add("Return-Path", StatusWarn, 1, fmt.Sprintf("bounces go to %s, not %s", domain, fromDomain))
+}
+
+// checkLinks flags links whose text names another site than they lead to,
+// and links to lookalike, international, or bare IP hosts
+func (r *Report) checkLinks(known []string) {
+	if len(r.Links) == 0 {
+		return
+	}
+
+	flagged := 0
+	for _, l := range r.Links {
+		switch {
+		case l.Mismatch:
+			r.add("Link", StatusFail, 3, fmt.Sprintf("text shows %s but goes to %s", l.Shown, l.Host))
+		case isIP(l.Host):
+			r.add("Link", StatusWarn, 2, "goes to a bare IP address: "+l.Host)
+		default:
+			if imitated, ok := Lookalike(l.Host, known); ok {
+				r.add("Link", StatusFail, 3, fmt.Sprintf("%s looks like %s", l.Host, imitated))
+			} else if isPunycode(l.Host) {
+				r.add("Link", StatusWarn, 1, l.Host+" uses international characters")
+			} else {
+				continue
+			}
+		}
+		flagged++
+	}
+
+	if flagged == 0 {
+		r.add("Links", StatusPass, 0, fmt.Sprintf("%d link(s), none suspicious", len(r.Links)))
+	}
+}
+
+// checkAttachments scores attachments by type
+func (r *Report) checkAttachments(attachments []mail.AttachmentInfo) {
+	for _, a := range attachments {
+		if a.IsInline {
+			continue
+		}
+		c := ClassifyAttachment(a)
+		r.Attachments = append(r.Attachments, c)
+
+		detail := c.Name
+		if c.ContentType != "" {
+			detail += " (" + c.ContentType + ")"
+		}
+		switch c.Risk {
+		case "high":
+			r.add("Attachment", StatusFail, 4, detail+": "+c.Reason)
+		case "medium":
+			r.add("Attachment", StatusWarn, 2, detail+": "+c.Reason)
+		default:
+			r.add("Attachment", StatusInfo, 0, detail)
+		}
+	}
+}
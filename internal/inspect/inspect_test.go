@@ -0,0 +1,187 @@
+package inspect
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+func TestParseAuthResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []mail.Header
+		want    AuthResults
+	}{
+		{
+			name: "exchange online",
+			headers: []mail.Header{
+				{Name: "Authentication-Results", Value: "spf=pass (sender IP is 192.0.2.1) smtp.mailfrom=bounce.example.com; dkim=fail (body hash did not verify) header.d=other.example;dkim=pass (signature was verified) header.d=example.com;dmarc=pass action=none header.from=example.com;compauth=pass reason=100"},
+				{Name: "Authentication-Results", Value: "spf=fail; dmarc=fail"},
+			},
+			want: AuthResults{
+				SPF: "pass", DKIM: "pass", DMARC: "pass", CompAuth: "pass",
+				MailFrom: "bounce.example.com", DKIMDomain: "example.com", HeaderFrom: "example.com",
+				Source: "Authentication-Results",
+			},
+		},
+		{
+			name: "newest ARC results when forwarded",
+			headers: []mail.Header{
+				{Name: "ARC-Seal", Value: "i=2; a=rsa-sha256; cv=pass; d=relay.example"},
+				{Name: "ARC-Authentication-Results", Value: "i=2; relay.example; spf=softfail smtp.mailfrom=example.com; dkim=none; dmarc=fail header.from=example.com"},
+				{Name: "ARC-Authentication-Results", Value: "i=1; mx.example.com; spf=pass; dkim=pass; dmarc=pass"},
+			},
+			want: AuthResults{
+				SPF: "softfail", DKIM: "none", DMARC: "fail", ARC: "pass",
+				MailFrom: "example.com", HeaderFrom: "example.com",
+				Source: "ARC-Authentication-Results",
+			},
+		},
+		{
+			name: "none",
+			want: AuthResults{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAuthResults(tt.headers); got != tt.want {
+				t.Errorf("ParseAuthResults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookalike(t *testing.T) {
+	known := []string{"contoso.com", "paypal.com", "microsoft.com", "apple.com", "live.com", "office.com"}
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"contoso.com", ""},
+		{"mail.contoso.com", ""},
+		{"c0ntoso.com", "contoso.com"},
+		{"paypa1.com", "paypal.com"},
+		{"rnicrosoft.com", "microsoft.com"},
+		{"micros0ft-support.net", "microsoft.com"},
+		{"xn--pple-43d.com", "apple.com"},
+		{"paypal.com.secure-login.example", "paypal.com"},
+		{"contosso.com", "contoso.com"},
+		{"example.org", ""},
+		{"192.0.2.1", ""},
+		{"paypal-secure.xyz", "paypal.com"},
+		{"paypa1-login.example.net", "paypal.com"},
+		{"rnicrosoft.example.net", "microsoft.com"},
+		{"live.bbc.co.uk", ""},
+		{"office.acme.com", ""},
+		{"apple.example.org", ""},
+		{"live-events.example.com", ""},
+		{"news.live.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, ok := Lookalike(tt.domain, known)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("Lookalike(%q) = %q, %v, want %q", tt.domain, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	body := `<p><a href="https://evil.example/login">https://www.paypal.com/signin</a>
+<a href="https://www.example.com/a">www.example.com</a>
+<a href="https://track.example.com/c?u=1">Click <b>here</b></a>
+<a href="mailto:a@example.com">a@example.com</a>
+<a href="http://news.example.com/x">example.com</a></p>`
+
+	got := ExtractLinks(body)
+	want := []Link{
+		{Text: "https://www.paypal.com/signin", Href: "https://evil.example/login", Host: "evil.example", Shown: "www.paypal.com", Mismatch: true},
+		{Text: "www.example.com", Href: "https://www.example.com/a", Host: "www.example.com", Shown: "www.example.com"},
+		{Text: "Click here", Href: "https://track.example.com/c?u=1", Host: "track.example.com"},
+		{Text: "example.com", Href: "http://news.example.com/x", Host: "news.example.com", Shown: "example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractLinks() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestClassifyAttachment(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{"report.pdf", "application/pdf", "low"},
+		{"invoice.pdf.exe", "application/octet-stream", "high"},
+		{"setup.msi", "application/x-msi", "high"},
+		{"invoice\u202efdp.exe", "application/octet-stream", "high"},
+		{"statement.html", "text/html", "medium"},
+		{"budget.xlsm", "application/vnd.ms-excel.sheet.macroEnabled.12", "medium"},
+		{"photo.jpg", "application/x-msdownload", "medium"},
+		{"notes.txt", "application/octet-stream", "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyAttachment(mail.AttachmentInfo{Name: tt.name, ContentType: tt.contentType})
+			if got.Risk != tt.want {
+				t.Errorf("ClassifyAttachment(%q).Risk = %q (%s), want %q", tt.name, got.Risk, got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("genuine", func(t *testing.T) {
+		r := Inspect(Message{
+			Headers: []mail.Header{
+				{Name: "From", Value: "Contoso Billing <billing@contoso.com>"},
+				{Name: "Return-Path", Value: "<bounces@mail.contoso.com>"},
+				{Name: "Authentication-Results", Value: "spf=pass smtp.mailfrom=mail.contoso.com; dkim=pass header.d=contoso.com; dmarc=pass header.from=contoso.com; compauth=pass"},
+			},
+			Body:        `<a href="https://contoso.com/invoice">contoso.com/invoice</a>`,
+			BodyType:    "html",
+			Attachments: []mail.AttachmentInfo{{Name: "invoice.pdf", ContentType: "application/pdf"}},
+			OwnDomains:  []string{"contoso.com"},
+		})
+		if r.Score != 0 || r.Risk != RiskLow {
+			t.Errorf("Inspect() score = %d (%s), findings %+v", r.Score, r.Risk, r.Findings)
+		}
+	})
+
+	t.Run("phishing", func(t *testing.T) {
+		r := Inspect(Message{
+			Headers: []mail.Header{
+				{Name: "From", Value: `"billing@contoso.com" <billing@c0ntoso.com>`},
+				{Name: "Reply-To", Value: "payments@example.net"},
+				{Name: "Return-Path", Value: "<x@bulk.example.org>"},
+				{Name: "Authentication-Results", Value: "spf=fail smtp.mailfrom=bulk.example.org; dkim=none; dmarc=fail header.from=c0ntoso.com; compauth=fail reason=000"},
+			},
+			Body:        `<a href="https://198.51.100.7/pay">https://contoso.com/pay</a>`,
+			BodyType:    "html",
+			Attachments: []mail.AttachmentInfo{{Name: "invoice.pdf.exe"}},
+			OwnDomains:  []string{"contoso.com"},
+		})
+		if r.Risk != RiskHigh {
+			t.Errorf("Inspect() risk = %s (score %d)", r.Risk, r.Score)
+		}
+
+		checks := map[string]string{}
+		for _, f := range r.Findings {
+			checks[f.Check] = f.Status
+		}
+		for check, want := range map[string]string{
+			"SPF": StatusFail, "DKIM": StatusWarn, "DMARC": StatusFail, "CompAuth": StatusFail,
+			"Display name": StatusFail, "From domain": StatusFail, "Reply-To": StatusWarn,
+			"Return-Path": StatusWarn, "Link": StatusFail, "Attachment": StatusFail,
+		} {
+			if checks[check] != want {
+				t.Errorf("%s = %q, want %q", check, checks[check], want)
+			}
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package inspect
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link is a hyperlink in the message body
+type Link struct {
+	Text string `json:"text"`
+	Href string `json:"href"`
+	Host string `json:"host"`
+	// Shown is the host the link text claims to go to, if it names one
+	Shown string `json:"shown_host,omitempty"`
+	// Mismatch means the text names a different site than the href
+	Mismatch bool `json:"mismatch"`
+}
+
+// hostLike matches link text that reads as a bare domain or URL
+var hostLike = regexp.MustCompile(`(?i)^(?:www\.)?[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*\.[a-z]{2,}(?::\d+)?(?:[/?#]\S*)?$`)
+
+// ExtractLinks lists the web links in an HTML body
+func ExtractLinks(body string) []Link {
+	var links []Link
+	var open *Link
+	var text strings.Builder
+
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		tok := z.Token()
+		switch {
+		case tt == html.StartTagToken && tok.Data == "a":
+			open = nil
+			for _, a := range tok.Attr {
+				if a.Key == "href" {
+					open = &Link{Href: strings.TrimSpace(a.Val)}
+				}
+			}
+			text.Reset()
+		case tt == html.TextToken && open != nil:
+			text.WriteString(tok.Data)
+		case tt == html.EndTagToken && tok.Data == "a" && open != nil:
+			open.Text = strings.Join(strings.Fields(text.String()), " ")
+			if finishLink(open) {
+				links = append(links, *open)
+			}
+			open = nil
+		}
+	}
+	return links
+}
+
+// finishLink fills in the hosts of a link, reporting false for links that
+// do not lead to a web page
+func finishLink(l *Link) bool {
+	u, err := url.Parse(l.Href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	l.Host = strings.ToLower(u.Hostname())
+
+	l.Shown = shownHost(l.Text)
+	l.Mismatch = l.Shown != "" && !sameOrg(l.Shown, l.Host) && l.Shown != l.Host
+	return true
+}
+
+// shownHost returns the host named by link text such as
+// "https://example.com/login" or "www.example.com", or "" for other text
+func shownHost(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, " \t") {
+		return ""
+	}
+	if u, err := url.Parse(text); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return strings.ToLower(u.Hostname())
+	}
+	if !hostLike.MatchString(text) {
+		return ""
+	}
+	if u, err := url.Parse("http://" + text); err == nil {
+		return strings.ToLower(u.Hostname())
+	}
+	return ""
+}
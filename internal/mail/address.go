@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"fmt"
+	netmail "net/mail"
+	"strings"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// ParseAddress parses an RFC 5322 address such as jane@example.com or
+// "Doe, Jane" <jane@example.com>
+func ParseAddress(s string) (*netmail.Address, error) {
+	addr, err := netmail.ParseAddress(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email address: %q", s)
+	}
+	return addr, nil
+}
+
+// Validate checks the addresses, importance, sensitivity, and attachment
+// files of opts without contacting the server
+func (o SendOptions) Validate() error {
+	for _, list := range [][]string{o.To, o.Cc, o.Bcc, o.ReplyTo} {
+		for _, s := range list {
+			if _, err := ParseAddress(s); err != nil {
+				return err
+			}
+		}
+	}
+	if o.Importance != "" {
+		if _, err := ParseImportance(o.Importance); err != nil {
+			return err
+		}
+	}
+	if o.Sensitivity != "" {
+		if _, err := ParseSensitivity(o.Sensitivity); err != nil {
+			return err
+		}
+	}
+	if _, _, err := splitAttachments(o.Attachments); err != nil {
+		return err
+	}
+	return nil
+}
+
+// formatAddress formats a named address in RFC 5322 form, quoting the
+// name only when it needs it. Unlike net/mail, non-ASCII names are kept as
+// they are rather than encoded, so they stay readable in the editor.
+func formatAddress(name, address string) string {
+	if strings.ContainsAny(name, `()<>[]:;@\,."`) {
+		name = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return name + " <" + address + ">"
+}
+
+// newRecipients converts RFC 5322 addresses to Graph recipients, keeping
+// display names
+func newRecipients(addrs []string) ([]models.Recipientable, error) {
+	recipients := make([]models.Recipientable, len(addrs))
+	for i, s := range addrs {
+		addr, err := ParseAddress(s)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients[i] = newRecipient(addr)
+	}
+	return recipients, nil
+}
+
+// newRecipient converts a parsed address to a Graph recipient
+func newRecipient(addr *netmail.Address) models.Recipientable {
+	emailAddr := models.NewEmailAddress()
+	address := addr.Address
+	emailAddr.SetAddress(&address)
+	if addr.Name != "" {
+		name := addr.Name
+		emailAddr.SetName(&name)
+	}
+	recipient := models.NewRecipient()
+	recipient.SetEmailAddress(emailAddr)
+	return recipient
+}
@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantName string
+		wantAddr string
+		wantErr  bool
+	}{
+		{input: "jane@example.com", wantAddr: "jane@example.com"},
+		{input: " Jane Doe <jane@example.com> ", wantName: "Jane Doe", wantAddr: "jane@example.com"},
+		{input: `"Doe, Jane" <jane@example.com>`, wantName: "Doe, Jane", wantAddr: "jane@example.com"},
+		{input: "<jane@example.com>", wantAddr: "jane@example.com"},
+		{input: "Jörg Müller <jorg@example.de>", wantName: "Jörg Müller", wantAddr: "jorg@example.de"},
+		{input: "jane", wantErr: true},
+		{input: "Jane <jane@example.com", wantErr: true},
+		{input: "jane@example.com, bob@example.com", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Name != tt.wantName || got.Address != tt.wantAddr) {
+				t.Errorf("ParseAddress() = %q <%s>, want %q <%s>", got.Name, got.Address, tt.wantName, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Jane Doe", "Jane Doe <jane@example.com>"},
+		{"Doe, Jane", `"Doe, Jane" <jane@example.com>`},
+		{`Jane "JD" Doe`, `"Jane \"JD\" Doe" <jane@example.com>`},
+		{"Jörg Müller", "Jörg Müller <jane@example.com>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatAddress(tt.name, "jane@example.com")
+			if got != tt.want {
+				t.Errorf("formatAddress() = %q, want %q", got, tt.want)
+			}
+			// The result must parse back to the same name
+			if addr, err := ParseAddress(got); err != nil || addr.Name != tt.name {
+				t.Errorf("ParseAddress(%q) = %v, %v", got, addr, err)
+			}
+		})
+	}
+}
+
+func TestSendOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    SendOptions
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			opts: SendOptions{
+				To: []string{"Jane <jane@example.com>"}, ReplyTo: []string{"team@example.com"},
+				Importance: "High", Sensitivity: "Confidential",
+			},
+		},
+		{name: "empty", opts: SendOptions{}},
+		{name: "invalid to", opts: SendOptions{To: []string{"jane"}}, wantErr: true},
+		{name: "invalid bcc", opts: SendOptions{To: []string{"a@example.com"}, Bcc: []string{"b@"}}, wantErr: true},
+		{name: "invalid reply-to", opts: SendOptions{ReplyTo: []string{"team"}}, wantErr: true},
+		{name: "invalid importance", opts: SendOptions{Importance: "urgent"}, wantErr: true},
+		{name: "invalid sensitivity", opts: SendOptions{Sensitivity: "secret"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewMessageDeliveryOptions(t *testing.T) {
+	msg, err := newMessage(SendOptions{
+		To:              []string{`"Doe, Jane" <jane@example.com>`, "bob@example.com"},
+		ReplyTo:         []string{"Support <support@example.com>"},
+		Subject:         "Contract",
+		Body:            "Attached.",
+		Sensitivity:     "private",
+		ReadReceipt:     true,
+		DeliveryReceipt: true,
+	})
+	if err != nil {
+		t.Fatalf("newMessage() error = %v", err)
+	}
+
+	to := msg.GetToRecipients()
+	if len(to) != 2 || safeString(to[0].GetEmailAddress().GetName()) != "Doe, Jane" ||
+		safeString(to[0].GetEmailAddress().GetAddress()) != "jane@example.com" {
+		t.Errorf("first recipient not parsed with its display name")
+	}
+	if to[1].GetEmailAddress().GetName() != nil {
+		t.Errorf("bare address got a name: %q", *to[1].GetEmailAddress().GetName())
+	}
+	if got := recipientAddresses(msg.GetToRecipients(), true); !reflect.DeepEqual(got, []string{`"Doe, Jane" <jane@example.com>`, "bob@example.com"}) {
+		t.Errorf("named recipients = %q", got)
+	}
+	if got := recipientAddresses(msg.GetReplyTo(), false); !reflect.DeepEqual(got, []string{"support@example.com"}) {
+		t.Errorf("reply-to = %q", got)
+	}
+	if !safeBool(msg.GetIsReadReceiptRequested()) || !safeBool(msg.GetIsDeliveryReceiptRequested()) {
+		t.Error("receipts not requested")
+	}
+
+	props := msg.GetSingleValueExtendedProperties()
+	if len(props) != 1 || safeString(props[0].GetId()) != messageSensitivity || safeString(props[0].GetValue()) != "2" {
+		t.Errorf("sensitivity property not set to private (2)")
+	}
+
+	if _, err := newMessage(SendOptions{To: []string{"jane"}}); err == nil {
+		t.Error("newMessage() with invalid address: expected error")
+	}
+}
@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	abstractions "github.com/microsoft/kiota-abstractions-go"
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+)
+
+// hasAlternative reports whether opts carry a plain-text alternative to an
+// HTML body. Graph's JSON messages hold a single body, so such messages
+// are uploaded as MIME.
+func (o SendOptions) hasAlternative() bool {
+	return o.BodyType == "html" && o.TextBody != ""
+}
+
+// createDraft creates a draft from opts with any extra extended
+// properties. A body with a plain-text alternative is uploaded as
+// multipart/alternative MIME, and the fields MIME cannot carry, such as
+// receipts and sensitivity, are set on the draft afterwards. Attachments
+// too large to send inline are uploaded once the draft exists; if that
+// fails, the draft is deleted.
+func createDraft(ctx context.Context, client *msgraph.GraphServiceClient, opts SendOptions, props ...models.SingleValueLegacyExtendedPropertyable) (models.Messageable, error) {
+	var draft models.Messageable
+	var attach []string
+	if !opts.hasAlternative() {
+		msg, err := newMessage(opts)
+		if err != nil {
+			return nil, err
+		}
+		_, attach, err = splitAttachments(opts.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetSingleValueExtendedProperties(append(msg.GetSingleValueExtendedProperties(), props...))
+		if draft, err = client.Me().Messages().Post(ctx, msg, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		fields, err := messageFields(opts)
+		if err != nil {
+			return nil, err
+		}
+		fields.SetSingleValueExtendedProperties(append(fields.GetSingleValueExtendedProperties(), props...))
+
+		data, err := alternativeMIME(opts)
+		if err != nil {
+			return nil, err
+		}
+		uploaded, err := postMIMEDraft(ctx, client, data)
+		if err != nil {
+			return nil, err
+		}
+
+		id := safeString(uploaded.GetId())
+		if draft, err = client.Me().Messages().ByMessageId(id).Patch(ctx, fields, nil); err != nil {
+			return nil, fmt.Errorf("failed to set message options on draft %s: %w", id, err)
+		}
+		attach = opts.Attachments
+	}
+
+	if len(attach) > 0 {
+		id := safeString(draft.GetId())
+		if err := addAttachments(ctx, client, id, attach); err != nil {
+			_ = DeleteMessage(ctx, client, id)
+			return nil, err
+		}
+	}
+	return draft, nil
+}
+
+// postMIMEDraft uploads a MIME message, which Graph saves as a draft
+func postMIMEDraft(ctx context.Context, client *msgraph.GraphServiceClient, data []byte) (models.Messageable, error) {
+	requestInfo, err := client.Me().Messages().ToPostRequestInformation(ctx, models.NewMessage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build draft request: %w", err)
+	}
+
+	// Graph accepts MIME content as base64 text in place of JSON
+	encoded := base64.StdEncoding.EncodeToString(data)
+	requestInfo.Headers.Remove("Content-Type")
+	requestInfo.SetStreamContentAndContentType([]byte(encoded), "text/plain")
+
+	errorMapping := abstractions.ErrorMappings{
+		"XXX": odataerrors.CreateODataErrorFromDiscriminatorValue,
+	}
+
+	res, err := client.GetAdapter().Send(ctx, requestInfo, models.CreateMessageFromDiscriminatorValue, errorMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload message: %w", err)
+	}
+	draft, ok := res.(models.Messageable)
+	if !ok || draft == nil {
+		return nil, fmt.Errorf("failed to upload message: empty response")
+	}
+	return draft, nil
+}
+
+// alternativeMIME builds a multipart/alternative message with the plain
+// text and HTML bodies of opts. Bcc is left out of the headers; it is set
+// on the draft instead.
+func alternativeMIME(opts SendOptions) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", opts.TextBody},
+		{"text/html; charset=utf-8", opts.Body},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build message: %w", err)
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to build message: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to build message: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("MIME-Version: 1.0\r\n")
+	for _, h := range []struct {
+		name  string
+		addrs []string
+	}{
+		{"To", opts.To},
+		{"Cc", opts.Cc},
+		{"Reply-To", opts.ReplyTo},
+	} {
+		if len(h.addrs) == 0 {
+			continue
+		}
+		encoded := make([]string, len(h.addrs))
+		for i, s := range h.addrs {
+			addr, err := ParseAddress(s)
+			if err != nil {
+				return nil, err
+			}
+			encoded[i] = addr.String()
+		}
+		fmt.Fprintf(&b, "%s: %s\r\n", h.name, strings.Join(encoded, ", "))
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", opts.Subject))
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n", w.Boundary())
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHasAlternative(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SendOptions
+		want bool
+	}{
+		{name: "html with text", opts: SendOptions{BodyType: "html", Body: "<p>Hi</p>", TextBody: "Hi"}, want: true},
+		{name: "html only", opts: SendOptions{BodyType: "html", Body: "<p>Hi</p>"}},
+		{name: "text", opts: SendOptions{BodyType: "text", Body: "Hi", TextBody: "Hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.hasAlternative(); got != tt.want {
+				t.Errorf("hasAlternative() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlternativeMIME(t *testing.T) {
+	opts := SendOptions{
+		To:       []string{"Ann Lee <ann@example.com>", "bob@example.com"},
+		Cc:       []string{"carol@example.com"},
+		Bcc:      []string{"dave@example.com"},
+		Subject:  "Café notes",
+		Body:     "<p><strong>Hi</strong> there, this line is long enough to need a soft line break in quoted-printable</p>\n",
+		BodyType: "html",
+		TextBody: "**Hi** there, this line is long enough to need a soft line break in quoted-printable\n",
+	}
+
+	data, err := alternativeMIME(opts)
+	if err != nil {
+		t.Fatalf("alternativeMIME() error = %v", err)
+	}
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	to, err := msg.Header.AddressList("To")
+	if err != nil {
+		t.Fatalf("To: %v", err)
+	}
+	if len(to) != 2 || to[0].Name != "Ann Lee" || to[0].Address != "ann@example.com" || to[1].Address != "bob@example.com" {
+		t.Errorf("To = %v", to)
+	}
+	if got := msg.Header.Get("Cc"); got != "<carol@example.com>" {
+		t.Errorf("Cc = %q", got)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("Bcc = %q, want it left out", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != opts.Subject {
+		t.Errorf("Subject = %q, %v, want %q", subject, err, opts.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+
+	var types, bodies []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		// NextPart decodes quoted-printable and drops its header
+		b, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(b))
+	}
+
+	wantTypes := []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("part types = %q, want %q", types, wantTypes)
+	}
+	// Line breaks are sent as CRLF, as MIME requires
+	crlf := strings.NewReplacer("\n", "\r\n")
+	wantBodies := []string{crlf.Replace(opts.TextBody), crlf.Replace(opts.Body)}
+	if !reflect.DeepEqual(bodies, wantBodies) {
+		t.Errorf("part bodies = %q, want %q", bodies, wantBodies)
+	}
+}
@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestNewMessageAttachments(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	big := filepath.Join(dir, "big.bin")
+	f, err := os.Create(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(maxInlineAttachments + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	opts := SendOptions{To: []string{"a@example.com"}, Subject: "Files", Attachments: []string{notes, big}}
+	msg, err := newMessage(opts)
+	if err != nil {
+		t.Fatalf("newMessage() error = %v", err)
+	}
+
+	attachments := msg.GetAttachments()
+	if len(attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1 (the large file is uploaded later)", len(attachments))
+	}
+	file, ok := attachments[0].(models.FileAttachmentable)
+	if !ok {
+		t.Fatalf("attachment is %T, want a file attachment", attachments[0])
+	}
+	if safeString(file.GetName()) != "notes.txt" || string(file.GetContentBytes()) != "hello" {
+		t.Errorf("attachment = %q %q", safeString(file.GetName()), file.GetContentBytes())
+	}
+	if got := safeString(file.GetContentType()); got != "text/plain; charset=utf-8" {
+		t.Errorf("content type = %q", got)
+	}
+
+	_, large, err := splitAttachments(opts.Attachments)
+	if err != nil {
+		t.Fatalf("splitAttachments() error = %v", err)
+	}
+	if !reflect.DeepEqual(large, []string{big}) {
+		t.Errorf("large = %q, want %q", large, []string{big})
+	}
+}
+
+func TestSplitAttachmentsLimitsTotal(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.bin", "b.bin", "c.bin"} {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Truncate(maxInlineAttachments / 2); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		paths = append(paths, path)
+	}
+
+	inline, large, err := splitAttachments(paths)
+	if err != nil {
+		t.Fatalf("splitAttachments() error = %v", err)
+	}
+	if !reflect.DeepEqual(inline, paths[:2]) || !reflect.DeepEqual(large, paths[2:]) {
+		t.Errorf("inline = %q, large = %q", inline, large)
+	}
+}
+
+func TestValidateAttachments(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing", path: filepath.Join(dir, "missing.pdf")},
+		{name: "directory", path: dir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SendOptions{To: []string{"a@example.com"}, Attachments: []string{tt.path}}
+			if err := opts.Validate(); err == nil {
+				t.Error("Validate() expected error")
+			}
+		})
+	}
+}
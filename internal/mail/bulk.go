@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BulkFailure records a message a bulk action could not be applied to
+type BulkFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// BulkResult summarizes a bulk action
+type BulkResult struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    []BulkFailure `json:"failed,omitempty"`
+}
+
+// BulkOptions configures a bulk action
+type BulkOptions struct {
+	// Concurrency is the number of messages processed in parallel
+	Concurrency int
+	// Progress, when set, is called after each message with the number of
+	// messages processed so far
+	Progress func(done, total int)
+}
+
+// RunBulk applies action to every ID with a bounded worker pool. Failures
+// are collected per message; the returned error is only set when the
+// context is cancelled before every ID was processed.
+func RunBulk(ctx context.Context, ids []string, opts BulkOptions, action func(ctx context.Context, id string) error) (*BulkResult, error) {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	result := &BulkResult{Total: len(ids)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	done := 0
+
+	jobs := make(chan string)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				err := action(ctx, id)
+
+				mu.Lock()
+				if err != nil {
+					result.Failed = append(result.Failed, BulkFailure{ID: id, Error: err.Error()})
+				} else {
+					result.Succeeded++
+				}
+				done++
+				if opts.Progress != nil {
+					opts.Progress(done, result.Total)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var ctxErr error
+feed:
+	for _, id := range ids {
+		if ctxErr = ctx.Err(); ctxErr != nil {
+			break
+		}
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return result, ctxErr
+}
+
+// ReadIDs reads message IDs from r. It accepts one ID per line (blank lines
+// and lines starting with # are ignored) or the JSON array printed by
+// `mail list --json`. Duplicate IDs are dropped.
+func ReadIDs(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message IDs: %w", err)
+	}
+
+	var ids []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var messages []Message
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse message list: %w", err)
+		}
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			ids = append(ids, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read message IDs: %w", err)
+		}
+	}
+
+	return uniqueIDs(ids), nil
+}
+
+// uniqueIDs returns ids without empty entries and duplicates, in order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
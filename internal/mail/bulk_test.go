@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunBulk(t *testing.T) {
+	t.Run("collects failures", func(t *testing.T) {
+		ids := []string{"a", "b", "c", "d"}
+		var calls []int
+
+		result, err := RunBulk(context.Background(), ids, BulkOptions{
+			Concurrency: 2,
+			Progress:    func(done, total int) { calls = append(calls, done) },
+		}, func(ctx context.Context, id string) error {
+			if id == "c" {
+				return errors.New("not found")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("RunBulk() error = %v", err)
+		}
+		if result.Total != 4 || result.Succeeded != 3 {
+			t.Errorf("Total/Succeeded = %d/%d, want 4/3", result.Total, result.Succeeded)
+		}
+		want := []BulkFailure{{ID: "c", Error: "not found"}}
+		if !reflect.DeepEqual(result.Failed, want) {
+			t.Errorf("Failed = %v, want %v", result.Failed, want)
+		}
+		if !reflect.DeepEqual(calls, []int{1, 2, 3, 4}) {
+			t.Errorf("progress calls = %v, want [1 2 3 4]", calls)
+		}
+	})
+
+	t.Run("stops on cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := RunBulk(ctx, []string{"a", "b"}, BulkOptions{}, func(ctx context.Context, id string) error {
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunBulk() error = %v, want context.Canceled", err)
+		}
+		if result.Succeeded+len(result.Failed) == result.Total {
+			t.Errorf("processed every ID after cancel")
+		}
+	})
+}
+
+func TestReadIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "one per line",
+			input: "AAA\n\n  BBB  \n# comment\nAAA\n",
+			want:  []string{"AAA", "BBB"},
+		},
+		{
+			name:  "json message list",
+			input: `[{"id":"AAA","subject":"x"},{"id":"BBB"}]`,
+			want:  []string{"AAA", "BBB"},
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadIDs(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadIDs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		if _, err := ReadIDs(strings.NewReader("[{")); err == nil {
+			t.Error("ReadIDs() error = nil, want error")
+		}
+	})
+}
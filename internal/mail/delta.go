@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// DeltaPage is one page of changes from a message delta query
+type DeltaPage struct {
+	// Messages holds messages that were added to or updated in the folder
+	Messages []Message
+	// Removed holds the IDs of messages deleted from or moved out of the folder
+	Removed []string
+	// DeltaLink is set on the last page and resumes the query next time
+	DeltaLink string
+}
+
+// DeltaOptions configures a message delta query
+type DeltaOptions struct {
+	// DeltaLink resumes a previous query; empty starts a full sync
+	DeltaLink string
+	// IncludeBody adds the message body to the returned messages
+	IncludeBody bool
+	// Since limits a new query to messages received at or after this time;
+	// it is ignored when resuming from a delta link
+	Since time.Time
+}
+
+// ErrDeltaExpired is returned when Graph no longer accepts a delta link and
+// the query has to start over
+var ErrDeltaExpired = errors.New("delta link expired")
+
+// deltaSelect lists the message fields requested by delta queries
+var deltaSelect = []string{
+	"id", "subject", "from", "toRecipients", "ccRecipients", "receivedDateTime",
+	"isRead", "hasAttachments", "bodyPreview", "internetMessageId", "conversationId",
+	"importance", "flag", "categories", "inferenceClassification",
+}
+
+// MessageDelta runs a delta query over the messages in a folder, calling fn
+// once for every page of changes. Pages are delivered in order and the last
+// page carries the delta link to store for the next run.
+func MessageDelta(ctx context.Context, client *msgraph.GraphServiceClient, folderID string, opts DeltaOptions, fn func(page *DeltaPage) error) error {
+	builder := client.Me().MailFolders().ByMailFolderId(folderID).Messages().Delta()
+
+	var requestConfig *users.ItemMailFoldersItemMessagesDeltaRequestBuilderGetRequestConfiguration
+	if opts.DeltaLink != "" {
+		builder = builder.WithUrl(opts.DeltaLink)
+	} else {
+		fields := deltaSelect
+		if opts.IncludeBody {
+			fields = append(append([]string{}, deltaSelect...), "body")
+		}
+		requestConfig = &users.ItemMailFoldersItemMessagesDeltaRequestBuilderGetRequestConfiguration{
+			QueryParameters: &users.ItemMailFoldersItemMessagesDeltaRequestBuilderGetQueryParameters{
+				Select: fields,
+			},
+		}
+		if !opts.Since.IsZero() {
+			filter := "receivedDateTime ge " + formatODataTime(opts.Since)
+			requestConfig.QueryParameters.Filter = &filter
+		}
+	}
+
+	for {
+		result, err := builder.GetAsDeltaGetResponse(ctx, requestConfig)
+		if err != nil {
+			if isDeltaExpired(err) {
+				return ErrDeltaExpired
+			}
+			return fmt.Errorf("failed to query message changes: %w", err)
+		}
+
+		page := &DeltaPage{}
+		for _, msg := range result.GetValue() {
+			if isRemoved(msg) {
+				page.Removed = append(page.Removed, safeString(msg.GetId()))
+				continue
+			}
+
+			m := convertMessage(msg)
+			if opts.IncludeBody {
+				convertBody(msg, &m)
+			}
+			page.Messages = append(page.Messages, m)
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			page.DeltaLink = safeString(result.GetOdataDeltaLink())
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+
+		if page.DeltaLink != "" || next == nil || *next == "" {
+			return nil
+		}
+
+		builder = builder.WithUrl(*next)
+		requestConfig = nil
+	}
+}
+
+// isRemoved reports whether a delta entry marks a removed message
+func isRemoved(msg models.Messageable) bool {
+	_, ok := msg.GetAdditionalData()["@removed"]
+	return ok
+}
+
+// isDeltaExpired reports whether Graph rejected a delta link as too old
+func isDeltaExpired(err error) bool {
+	var odataErr *odataerrors.ODataError
+	if !errors.As(err, &odataErr) {
+		return false
+	}
+	return odataErr.ResponseStatusCode == http.StatusGone
+}
@@ -0,0 +1,176 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// GetDraft retrieves a draft with its recipients and body, failing if the
+// message has already been sent
+func GetDraft(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) (*Message, error) {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{
+				"id", "subject", "toRecipients", "ccRecipients", "bccRecipients", "receivedDateTime",
+				"replyTo", "body", "importance", "isReadReceiptRequested",
+				"isDeliveryReceiptRequested", "isDraft",
+			},
+		},
+	}
+
+	msg, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+	if !safeBool(msg.GetIsDraft()) {
+		return nil, fmt.Errorf("message %s is not a draft", messageID)
+	}
+
+	draft := convertMessage(msg)
+	convertBody(msg, &draft)
+	// Keep display names so that editing the draft does not drop them
+	draft.To = recipientAddresses(msg.GetToRecipients(), true)
+	draft.Cc = recipientAddresses(msg.GetCcRecipients(), true)
+	draft.Bcc = recipientAddresses(msg.GetBccRecipients(), true)
+	draft.ReplyTo = recipientAddresses(msg.GetReplyTo(), true)
+	return &draft, nil
+}
+
+// UpdateDraft replaces a draft's subject, body, recipients, and receipt
+// requests with opts. Importance and sensitivity are only changed when set,
+// and opts.Attachments are added to the draft's attachments.
+func UpdateDraft(ctx context.Context, client *msgraph.GraphServiceClient, messageID string, opts SendOptions) (*Message, error) {
+	if opts.hasAlternative() {
+		return replaceDraft(ctx, client, messageID, opts)
+	}
+
+	// Attachments are added after the PATCH, which cannot carry them
+	fields := opts
+	fields.Attachments = nil
+	msg, err := newMessage(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	// Send empty lists too, so removed recipients are cleared
+	if len(opts.Cc) == 0 {
+		msg.SetCcRecipients([]models.Recipientable{})
+	}
+	if len(opts.Bcc) == 0 {
+		msg.SetBccRecipients([]models.Recipientable{})
+	}
+	if len(opts.ReplyTo) == 0 {
+		msg.SetReplyTo([]models.Recipientable{})
+	}
+
+	updated, err := client.Me().Messages().ByMessageId(messageID).Patch(ctx, msg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update draft: %w", err)
+	}
+	if err := addAttachments(ctx, client, messageID, opts.Attachments); err != nil {
+		return nil, fmt.Errorf("draft updated, but not all files were attached: %w", err)
+	}
+
+	result := convertMessage(updated)
+	return &result, nil
+}
+
+// replaceDraft recreates a draft for a body with a plain-text alternative,
+// since the MIME content of a draft cannot be changed in place. The new
+// draft keeps the old one's importance, sensitivity, and file attachments,
+// and the old draft is deleted.
+func replaceDraft(ctx context.Context, client *msgraph.GraphServiceClient, messageID string, opts SendOptions) (*Message, error) {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "importance"},
+			Expand: []string{fmt.Sprintf("singleValueExtendedProperties($filter=id eq '%s')", messageSensitivity)},
+		},
+	}
+	old, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+	if opts.Importance == "" && old.GetImportance() != nil {
+		opts.Importance = old.GetImportance().String()
+	}
+	if opts.Sensitivity == "" {
+		for _, prop := range old.GetSingleValueExtendedProperties() {
+			if n, err := strconv.Atoi(safeString(prop.GetValue())); err == nil && n >= 0 && n < len(sensitivityLevels) {
+				opts.Sensitivity = sensitivityLevels[n]
+			}
+		}
+	}
+
+	result, err := client.Me().Messages().ByMessageId(messageID).Attachments().Get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	var attachments []models.FileAttachmentable
+	for _, a := range result.GetValue() {
+		file, ok := a.(models.FileAttachmentable)
+		if !ok {
+			return nil, fmt.Errorf("draft %s has an attached item or link, which cannot be copied to a draft with a plain-text body", messageID)
+		}
+		attachments = append(attachments, file)
+	}
+
+	draft, err := createDraft(ctx, client, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update draft: %w", err)
+	}
+	id := safeString(draft.GetId())
+
+	for _, a := range attachments {
+		attachment := models.NewFileAttachment()
+		attachment.SetName(a.GetName())
+		attachment.SetContentType(a.GetContentType())
+		attachment.SetContentBytes(a.GetContentBytes())
+		attachment.SetContentId(a.GetContentId())
+		attachment.SetIsInline(a.GetIsInline())
+		if _, err := client.Me().Messages().ByMessageId(id).Attachments().Post(ctx, attachment, nil); err != nil {
+			_ = DeleteMessage(ctx, client, id)
+			return nil, fmt.Errorf("failed to copy attachment %s (the draft was left unchanged): %w", safeString(a.GetName()), err)
+		}
+	}
+
+	if err := DeleteMessage(ctx, client, messageID); err != nil {
+		return nil, fmt.Errorf("draft saved as %s, but the old draft remains: %w", id, err)
+	}
+
+	message := convertMessage(draft)
+	return &message, nil
+}
+
+// SendDraft sends an existing draft
+func SendDraft(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) error {
+	if err := client.Me().Messages().ByMessageId(messageID).Send().Post(ctx, nil); err != nil {
+		return fmt.Errorf("failed to send draft: %w", err)
+	}
+	return nil
+}
+
+// DraftOptions converts a draft back to send options for editing
+func (m *Message) DraftOptions() SendOptions {
+	bodyType := "text"
+	if m.BodyContentType == "html" {
+		bodyType = "html"
+	}
+	return SendOptions{
+		To:              m.To,
+		Cc:              m.Cc,
+		Bcc:             m.Bcc,
+		ReplyTo:         m.ReplyTo,
+		Subject:         m.Subject,
+		Body:            m.Body,
+		BodyType:        bodyType,
+		ReadReceipt:     m.ReadReceiptRequested,
+		DeliveryReceipt: m.DeliveryReceiptRequested,
+	}
+}
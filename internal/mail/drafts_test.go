@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewMessageHonoursOptions(t *testing.T) {
+	opts := SendOptions{
+		To:         []string{"a@example.com"},
+		Cc:         []string{"c@example.com"},
+		Bcc:        []string{"b1@example.com", "b2@example.com"},
+		Subject:    "Proposal",
+		Body:       "<p>Hi</p>",
+		BodyType:   "html",
+		Importance: "high",
+	}
+
+	msg, err := newMessage(opts)
+	if err != nil {
+		t.Fatalf("newMessage() error = %v", err)
+	}
+
+	m := convertMessage(msg)
+	convertBody(msg, &m)
+	if !reflect.DeepEqual(m.DraftOptions(), SendOptions{
+		To: opts.To, Cc: opts.Cc, Bcc: opts.Bcc, Subject: opts.Subject, Body: opts.Body, BodyType: "html",
+	}) {
+		t.Errorf("DraftOptions() = %+v", m.DraftOptions())
+	}
+	if m.Importance != "high" {
+		t.Errorf("Importance = %q, want high", m.Importance)
+	}
+
+	if _, err := newMessage(SendOptions{Importance: "urgent"}); err == nil {
+		t.Error("newMessage() with invalid importance: expected error")
+	}
+}
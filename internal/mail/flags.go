@@ -0,0 +1,274 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+
+	"github.com/pp/octl/internal/mailbox"
+)
+
+// Flag status values
+const (
+	FlagNotFlagged = "notFlagged"
+	FlagFlagged    = "flagged"
+	FlagComplete   = "complete"
+)
+
+// Flag is the follow-up flag on a message
+type Flag struct {
+	Status      string     `json:"status"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ParseImportance validates an importance level and returns it lower-cased
+func ParseImportance(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "low", "normal", "high":
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid importance: %s (use low, normal, or high)", s)
+}
+
+// importanceValue converts an importance level to its Graph enum
+func importanceValue(s string) (*models.Importance, error) {
+	level, err := ParseImportance(s)
+	if err != nil {
+		return nil, err
+	}
+	value, err := models.ParseImportance(level)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*models.Importance), nil
+}
+
+// sensitivityLevels lists the sensitivity levels by their MAPI value
+var sensitivityLevels = []string{"normal", "personal", "private", "confidential"}
+
+// messageSensitivity is PR_SENSITIVITY. Graph only exposes sensitivity on
+// events, so messages carry it as an extended property.
+const messageSensitivity = "Integer 0x36"
+
+// ParseSensitivity validates a sensitivity level and returns it lower-cased
+func ParseSensitivity(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if slices.Contains(sensitivityLevels, s) {
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid sensitivity: %s (use normal, personal, private, or confidential)", s)
+}
+
+// sensitivityProperty converts a sensitivity level to its extended property
+func sensitivityProperty(s string) (models.SingleValueLegacyExtendedPropertyable, error) {
+	level, err := ParseSensitivity(s)
+	if err != nil {
+		return nil, err
+	}
+
+	prop := models.NewSingleValueLegacyExtendedProperty()
+	id := messageSensitivity
+	value := strconv.Itoa(slices.Index(sensitivityLevels, level))
+	prop.SetId(&id)
+	prop.SetValue(&value)
+	return prop, nil
+}
+
+// SetFlag sets the follow-up flag of a message. A due date only applies to
+// the flagged status; Graph requires a start date with it, so the flag
+// starts now.
+func SetFlag(ctx context.Context, client *msgraph.GraphServiceClient, messageID, status string, due time.Time) error {
+	flag := models.NewFollowupFlag()
+
+	var flagStatus models.FollowupFlagStatus
+	switch status {
+	case FlagNotFlagged:
+		flagStatus = models.NOTFLAGGED_FOLLOWUPFLAGSTATUS
+	case FlagFlagged:
+		flagStatus = models.FLAGGED_FOLLOWUPFLAGSTATUS
+	case FlagComplete:
+		flagStatus = models.COMPLETE_FOLLOWUPFLAGSTATUS
+		flag.SetCompletedDateTime(flagDateTime(time.Now()))
+	default:
+		return fmt.Errorf("invalid flag status: %s", status)
+	}
+	flag.SetFlagStatus(&flagStatus)
+
+	if !due.IsZero() {
+		if status != FlagFlagged {
+			return fmt.Errorf("a due date can only be set on a flagged message")
+		}
+		start := time.Now()
+		if due.Before(start) {
+			start = due
+		}
+		flag.SetStartDateTime(flagDateTime(start))
+		flag.SetDueDateTime(flagDateTime(due))
+	}
+
+	update := models.NewMessage()
+	update.SetFlag(flag)
+
+	if _, err := client.Me().Messages().ByMessageId(messageID).Patch(ctx, update, nil); err != nil {
+		return fmt.Errorf("failed to update flag: %w", err)
+	}
+
+	return nil
+}
+
+// flagDateTime converts a time to a Graph date-time in UTC
+func flagDateTime(t time.Time) models.DateTimeTimeZoneable {
+	dt := models.NewDateTimeTimeZone()
+	value := t.UTC().Format("2006-01-02T15:04:05")
+	zone := "UTC"
+	dt.SetDateTime(&value)
+	dt.SetTimeZone(&zone)
+	return dt
+}
+
+// UpdateCategories adds and removes categories on a message and returns
+// the resulting list
+func UpdateCategories(ctx context.Context, client *msgraph.GraphServiceClient, messageID string, add, remove []string) ([]string, error) {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "categories"},
+		},
+	}
+
+	msg, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	categories := mergeCategories(msg.GetCategories(), add, remove)
+
+	update := models.NewMessage()
+	update.SetCategories(categories)
+
+	if _, err := client.Me().Messages().ByMessageId(messageID).Patch(ctx, update, nil); err != nil {
+		return nil, fmt.Errorf("failed to update categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// mergeCategories applies additions and removals to a category list.
+// Names are matched case-insensitively, as Outlook does.
+func mergeCategories(current, add, remove []string) []string {
+	removed := make(map[string]bool, len(remove))
+	for _, c := range remove {
+		removed[strings.ToLower(strings.TrimSpace(c))] = true
+	}
+
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(current)+len(add))
+	for _, c := range append(append([]string{}, current...), add...) {
+		c = strings.TrimSpace(c)
+		key := strings.ToLower(c)
+		if c == "" || seen[key] || removed[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// ParseDueDate parses a due date flag value. It accepts the absolute forms
+// of ParseDateArg plus "today", "tomorrow", and ages such as 3d or 2w
+// counted forward from now. Dates without a time are due at the end of
+// the working day (17:00 local time).
+func ParseDueDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	endOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 17, 0, 0, 0, now.Location())
+	}
+
+	switch s {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		return endOfDay(now), nil
+	case "tomorrow":
+		return endOfDay(now.AddDate(0, 0, 1)), nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(strings.TrimPrefix(s, "+")); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "h":
+			return now.Add(time.Duration(n) * time.Hour), nil
+		case "d":
+			return endOfDay(now.AddDate(0, 0, n)), nil
+		case "w":
+			return endOfDay(now.AddDate(0, 0, 7*n)), nil
+		case "m":
+			return endOfDay(now.AddDate(0, n, 0)), nil
+		case "y":
+			return endOfDay(now.AddDate(n, 0, 0)), nil
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return endOfDay(t), nil
+	}
+
+	t, err := ParseDateArg(s, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date: %s (use YYYY-MM-DD, RFC 3339, tomorrow, or a delay like 3d)", s)
+	}
+	return t, nil
+}
+
+// convertFlag converts a Graph follow-up flag, returning nil for messages
+// that were never flagged
+func convertFlag(f models.FollowupFlagable) *Flag {
+	if f == nil || f.GetFlagStatus() == nil {
+		return nil
+	}
+
+	flag := &Flag{Status: f.GetFlagStatus().String()}
+	if flag.Status == FlagNotFlagged {
+		return nil
+	}
+
+	flag.StartAt = parseGraphDateTime(f.GetStartDateTime())
+	flag.DueAt = parseGraphDateTime(f.GetDueDateTime())
+	flag.CompletedAt = parseGraphDateTime(f.GetCompletedDateTime())
+
+	return flag
+}
+
+// parseGraphDateTime converts a Graph date-time with time zone to a time
+func parseGraphDateTime(dt models.DateTimeTimeZoneable) *time.Time {
+	if dt == nil || dt.GetDateTime() == nil {
+		return nil
+	}
+
+	loc := time.UTC
+	if zone := safeString(dt.GetTimeZone()); zone != "" {
+		if l, err := mailbox.LoadLocation(zone); err == nil {
+			loc = l
+		}
+	}
+
+	value := *dt.GetDateTime()
+	for _, layout := range []string{"2006-01-02T15:04:05.0000000", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
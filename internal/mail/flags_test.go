@@ -0,0 +1,156 @@
+package mail
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestParseImportance(t *testing.T) {
+	for _, in := range []string{"low", "Normal", " HIGH "} {
+		if _, err := ParseImportance(in); err != nil {
+			t.Errorf("ParseImportance(%q) error = %v", in, err)
+		}
+	}
+	if got, _ := ParseImportance("High"); got != "high" {
+		t.Errorf("ParseImportance(High) = %q, want high", got)
+	}
+	if _, err := ParseImportance("urgent"); err == nil {
+		t.Error("ParseImportance(urgent) error = nil, want error")
+	}
+}
+
+func TestParseSensitivity(t *testing.T) {
+	if got, _ := ParseSensitivity(" Confidential "); got != "confidential" {
+		t.Errorf("ParseSensitivity(Confidential) = %q, want confidential", got)
+	}
+	if _, err := ParseSensitivity("secret"); err == nil {
+		t.Error("ParseSensitivity(secret) error = nil, want error")
+	}
+}
+
+func TestMergeCategories(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []string
+		add     []string
+		remove  []string
+		want    []string
+	}{
+		{
+			name:    "adds new categories",
+			current: []string{"Red"},
+			add:     []string{"Blue"},
+			want:    []string{"Red", "Blue"},
+		},
+		{
+			name:    "skips duplicates case-insensitively",
+			current: []string{"Project X"},
+			add:     []string{"project x", "Travel"},
+			want:    []string{"Project X", "Travel"},
+		},
+		{
+			name:    "removes categories case-insensitively",
+			current: []string{"Red", "Blue"},
+			remove:  []string{"red"},
+			want:    []string{"Blue"},
+		},
+		{
+			name:    "remove wins over add",
+			current: nil,
+			add:     []string{"Red"},
+			remove:  []string{"Red"},
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeCategories(tt.current, tt.add, tt.remove)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCategories() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDueDate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"today", time.Date(2024, 3, 15, 17, 0, 0, 0, time.UTC)},
+		{"tomorrow", time.Date(2024, 3, 16, 17, 0, 0, 0, time.UTC)},
+		{"3d", time.Date(2024, 3, 18, 17, 0, 0, 0, time.UTC)},
+		{"+1w", time.Date(2024, 3, 22, 17, 0, 0, 0, time.UTC)},
+		{"2h", time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)},
+		{"2024-04-01", time.Date(2024, 4, 1, 17, 0, 0, 0, time.UTC)},
+		{"2024-04-01T09:00:00Z", time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDueDate(tt.in, now)
+			if err != nil {
+				t.Fatalf("ParseDueDate(%q) error = %v", tt.in, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseDueDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseDueDate("someday", now); err == nil {
+		t.Error("ParseDueDate(someday) error = nil, want error")
+	}
+}
+
+func TestConvertFlag(t *testing.T) {
+	t.Run("not flagged is nil", func(t *testing.T) {
+		f := models.NewFollowupFlag()
+		status := models.NOTFLAGGED_FOLLOWUPFLAGSTATUS
+		f.SetFlagStatus(&status)
+		if got := convertFlag(f); got != nil {
+			t.Errorf("convertFlag() = %+v, want nil", got)
+		}
+	})
+
+	t.Run("flagged with due date", func(t *testing.T) {
+		f := models.NewFollowupFlag()
+		status := models.FLAGGED_FOLLOWUPFLAGSTATUS
+		f.SetFlagStatus(&status)
+		f.SetDueDateTime(flagDateTime(time.Date(2024, 4, 1, 17, 0, 0, 0, time.UTC)))
+
+		got := convertFlag(f)
+		if got == nil || got.Status != FlagFlagged {
+			t.Fatalf("convertFlag() = %+v, want flagged", got)
+		}
+		if got.DueAt == nil || !got.DueAt.Equal(time.Date(2024, 4, 1, 17, 0, 0, 0, time.UTC)) {
+			t.Errorf("DueAt = %v, want 2024-04-01 17:00 UTC", got.DueAt)
+		}
+	})
+
+	t.Run("windows time zone", func(t *testing.T) {
+		f := models.NewFollowupFlag()
+		status := models.FLAGGED_FOLLOWUPFLAGSTATUS
+		f.SetFlagStatus(&status)
+		due := models.NewDateTimeTimeZone()
+		value, zone := "2024-04-01T17:00:00.0000000", "Pacific Standard Time"
+		due.SetDateTime(&value)
+		due.SetTimeZone(&zone)
+		f.SetDueDateTime(due)
+
+		got := convertFlag(f)
+		if got == nil || got.DueAt == nil {
+			t.Fatalf("convertFlag() = %+v, want a due date", got)
+		}
+		if want := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC); !got.DueAt.Equal(want) {
+			t.Errorf("DueAt = %v, want %v", got.DueAt, want)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// Focused Inbox classifications
+const (
+	ClassificationFocused = "focused"
+	ClassificationOther   = "other"
+)
+
+// Override makes Focused Inbox classify all mail from a sender the same way
+type Override struct {
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	Address    string `json:"address"`
+	ClassifyAs string `json:"classify_as"`
+}
+
+// ParseClassification validates a Focused Inbox classification and returns
+// it lower-cased
+func ParseClassification(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case ClassificationFocused, ClassificationOther:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid classification: %s (use focused or other)", s)
+}
+
+// classificationValue converts a classification to its Graph enum
+func classificationValue(s string) (*models.InferenceClassificationType, error) {
+	classification, err := ParseClassification(s)
+	if err != nil {
+		return nil, err
+	}
+	value, err := models.ParseInferenceClassificationType(classification)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*models.InferenceClassificationType), nil
+}
+
+// ClassifyMessage moves a message to the Focused or Other tab and returns
+// the updated message
+func ClassifyMessage(ctx context.Context, client *msgraph.GraphServiceClient, messageID, classification string) (*Message, error) {
+	value, err := classificationValue(classification)
+	if err != nil {
+		return nil, err
+	}
+
+	update := models.NewMessage()
+	update.SetInferenceClassification(value)
+
+	updated, err := client.Me().Messages().ByMessageId(messageID).Patch(ctx, update, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify message: %w", err)
+	}
+
+	result := convertMessage(updated)
+	return &result, nil
+}
+
+// ListOverrides lists the Focused Inbox overrides, sorted by sender address
+func ListOverrides(ctx context.Context, client *msgraph.GraphServiceClient) ([]Override, error) {
+	builder := client.Me().InferenceClassification().Overrides()
+	result, err := builder.Get(ctx, nil)
+
+	overrides := make([]Override, 0)
+	for {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list overrides: %w", err)
+		}
+
+		for _, o := range result.GetValue() {
+			overrides = append(overrides, convertOverride(o))
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			break
+		}
+		result, err = builder.WithUrl(*next).Get(ctx, nil)
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return strings.ToLower(overrides[i].Address) < strings.ToLower(overrides[j].Address)
+	})
+	return overrides, nil
+}
+
+// SetOverride classifies all future mail from a sender. An existing
+// override for the address is updated rather than duplicated.
+func SetOverride(ctx context.Context, client *msgraph.GraphServiceClient, name, address, classification string) (*Override, error) {
+	value, err := classificationValue(classification)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ListOverrides(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if o := FindOverride(existing, address); o != nil {
+		update := models.NewInferenceClassificationOverride()
+		update.SetClassifyAs(value)
+		updated, err := client.Me().InferenceClassification().Overrides().ByInferenceClassificationOverrideId(o.ID).Patch(ctx, update, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update override: %w", err)
+		}
+		result := convertOverride(updated)
+		return &result, nil
+	}
+
+	sender := models.NewEmailAddress()
+	sender.SetAddress(&address)
+	if name != "" {
+		sender.SetName(&name)
+	}
+	override := models.NewInferenceClassificationOverride()
+	override.SetClassifyAs(value)
+	override.SetSenderEmailAddress(sender)
+
+	created, err := client.Me().InferenceClassification().Overrides().Post(ctx, override, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create override: %w", err)
+	}
+	result := convertOverride(created)
+	return &result, nil
+}
+
+// DeleteOverride deletes a Focused Inbox override
+func DeleteOverride(ctx context.Context, client *msgraph.GraphServiceClient, overrideID string) error {
+	if err := client.Me().InferenceClassification().Overrides().ByInferenceClassificationOverrideId(overrideID).Delete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to delete override: %w", err)
+	}
+	return nil
+}
+
+// FindOverride returns the override for a sender address, matched
+// case-insensitively, or nil
+func FindOverride(overrides []Override, address string) *Override {
+	for i := range overrides {
+		if strings.EqualFold(overrides[i].Address, address) {
+			return &overrides[i]
+		}
+	}
+	return nil
+}
+
+// convertOverride converts a Graph inference classification override
+func convertOverride(o models.InferenceClassificationOverrideable) Override {
+	override := Override{ID: safeString(o.GetId())}
+	if sender := o.GetSenderEmailAddress(); sender != nil {
+		override.Name = safeString(sender.GetName())
+		override.Address = safeString(sender.GetAddress())
+	}
+	if classifyAs := o.GetClassifyAs(); classifyAs != nil {
+		override.ClassifyAs = classifyAs.String()
+	}
+	return override
+}
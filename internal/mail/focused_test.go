@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestParseClassification(t *testing.T) {
+	if got, _ := ParseClassification(" Other "); got != ClassificationOther {
+		t.Errorf("ParseClassification(Other) = %q, want other", got)
+	}
+	if _, err := ParseClassification("junk"); err == nil {
+		t.Error("ParseClassification(junk) error = nil, want error")
+	}
+}
+
+func TestConvertOverride(t *testing.T) {
+	o := models.NewInferenceClassificationOverride()
+	id, name, address := "ov1", "News", "news@example.com"
+	o.SetId(&id)
+	sender := models.NewEmailAddress()
+	sender.SetName(&name)
+	sender.SetAddress(&address)
+	o.SetSenderEmailAddress(sender)
+	classifyAs := models.OTHER_INFERENCECLASSIFICATIONTYPE
+	o.SetClassifyAs(&classifyAs)
+
+	want := Override{ID: "ov1", Name: "News", Address: "news@example.com", ClassifyAs: ClassificationOther}
+	if got := convertOverride(o); got != want {
+		t.Errorf("convertOverride() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConvertMessageSenderParses(t *testing.T) {
+	// mail classify --always parses the sender back into a name and address
+	msg := models.NewMessage()
+	name, address := "Doe, Jane", "jane@example.com"
+	sender := models.NewEmailAddress()
+	sender.SetName(&name)
+	sender.SetAddress(&address)
+	from := models.NewRecipient()
+	from.SetEmailAddress(sender)
+	msg.SetFrom(from)
+
+	m := convertMessage(msg)
+	addr, err := ParseAddress(m.From)
+	if err != nil {
+		t.Fatalf("ParseAddress(%q) error = %v", m.From, err)
+	}
+	if addr.Name != name || addr.Address != address {
+		t.Errorf("sender = %q <%s>, want %q <%s>", addr.Name, addr.Address, name, address)
+	}
+}
+
+func TestFindOverride(t *testing.T) {
+	overrides := []Override{
+		{ID: "1", Address: "news@example.com", ClassifyAs: ClassificationOther},
+		{ID: "2", Address: "Boss@Example.com", ClassifyAs: ClassificationFocused},
+	}
+
+	if o := FindOverride(overrides, "boss@example.com"); o == nil || o.ID != "2" {
+		t.Errorf("FindOverride(boss@example.com) = %+v, want override 2", o)
+	}
+	if o := FindOverride(overrides, "someone@example.com"); o != nil {
+		t.Errorf("FindOverride(someone@example.com) = %+v, want nil", o)
+	}
+}
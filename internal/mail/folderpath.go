@@ -0,0 +1,227 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+
+	"github.com/pp/octl/internal/config"
+)
+
+const (
+	folderCacheFileName = "folder_cache.json"
+	folderCacheTTL      = time.Hour
+)
+
+// wellKnownFolders lists the well-known folder names accepted by Graph
+var wellKnownFolders = []string{
+	FolderInbox,
+	FolderDrafts,
+	FolderSentItems,
+	FolderDeleted,
+	FolderJunk,
+	FolderArchive,
+	FolderRoot,
+	"outbox",
+	"conversationhistory",
+	"clutter",
+	"recoverableitemsdeletions",
+	"scheduled",
+	"searchfolders",
+	"syncissues",
+}
+
+// folderCache maps lower-cased folder paths to folder IDs
+type folderCache struct {
+	UpdatedAt time.Time         `json:"updated_at"`
+	Paths     map[string]string `json:"paths"`
+}
+
+// IsWellKnownFolder reports whether name is a Graph well-known folder name
+func IsWellKnownFolder(name string) bool {
+	name = strings.ToLower(name)
+	for _, wk := range wellKnownFolders {
+		if name == wk {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveFolderID turns a folder reference into a folder ID. The reference
+// may be a well-known name (inbox, archive, ...), a folder ID, or a path of
+// display names such as "Inbox/Projects/Acme", matched case-insensitively.
+func ResolveFolderID(ctx context.Context, client *msgraph.GraphServiceClient, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", nil
+	}
+
+	if IsWellKnownFolder(ref) {
+		return strings.ToLower(ref), nil
+	}
+
+	key := normalizeFolderPath(ref)
+
+	// Try the cache first, then refresh once on a miss
+	if cache, err := loadFolderCache(); err == nil && time.Since(cache.UpdatedAt) < folderCacheTTL {
+		if id, ok := cache.Paths[key]; ok {
+			return id, nil
+		}
+	}
+
+	if looksLikeFolderID(ref) {
+		return ref, nil
+	}
+
+	paths, err := RefreshFolderCache(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	if id, ok := paths[key]; ok {
+		return id, nil
+	}
+
+	// The first segment may be a well-known name for a localized folder
+	segments := splitFolderPath(ref)
+	if len(segments) > 1 && IsWellKnownFolder(segments[0]) {
+		root, err := GetFolder(ctx, client, strings.ToLower(segments[0]))
+		if err != nil {
+			return "", err
+		}
+		for path, id := range paths {
+			if id == root.ID {
+				rest := normalizeFolderPath(strings.Join(segments[1:], "/"))
+				if id, ok := paths[path+"/"+rest]; ok {
+					return id, nil
+				}
+				break
+			}
+		}
+	}
+
+	return "", fmt.Errorf("folder not found: %s", ref)
+}
+
+// RefreshFolderCache fetches the full folder tree and rewrites the
+// path-to-ID cache. It returns the fresh path map.
+func RefreshFolderCache(ctx context.Context, client *msgraph.GraphServiceClient) (map[string]string, error) {
+	tree, err := ListFolderTree(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := buildFolderPaths(tree)
+
+	// A failed cache write only costs a refetch next time
+	_ = saveFolderCache(&folderCache{
+		UpdatedAt: time.Now(),
+		Paths:     paths,
+	})
+
+	return paths, nil
+}
+
+// InvalidateFolderCache removes the cached path-to-ID map
+func InvalidateFolderCache() error {
+	path, err := folderCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove folder cache: %w", err)
+	}
+
+	return nil
+}
+
+// buildFolderPaths maps the lower-cased path of every folder to its ID
+func buildFolderPaths(tree []Folder) map[string]string {
+	paths := make(map[string]string)
+	WalkFolders(tree, func(f Folder, depth int) {
+		paths[normalizeFolderPath(f.Path)] = f.ID
+	})
+	return paths
+}
+
+// splitFolderPath splits a folder path into its non-empty segments
+func splitFolderPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// normalizeFolderPath returns the canonical cache key for a folder path
+func normalizeFolderPath(path string) string {
+	return strings.ToLower(strings.Join(splitFolderPath(path), "/"))
+}
+
+// looksLikeFolderID reports whether ref is plausibly an opaque Graph ID
+func looksLikeFolderID(ref string) bool {
+	return len(ref) >= 40 && !strings.ContainsAny(ref, " /")
+}
+
+// folderCachePath returns the path to the folder cache file
+func folderCachePath() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, folderCacheFileName), nil
+}
+
+// loadFolderCache reads the folder cache from disk
+func loadFolderCache() (*folderCache, error) {
+	path, err := folderCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache folderCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse folder cache: %w", err)
+	}
+
+	return &cache, nil
+}
+
+// saveFolderCache writes the folder cache to disk
+func saveFolderCache(cache *folderCache) error {
+	path, err := folderCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal folder cache: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write folder cache: %w", err)
+	}
+
+	return nil
+}
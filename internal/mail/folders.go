@@ -3,6 +3,7 @@ package mail
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
@@ -11,28 +12,136 @@ import (
 
 // Folder represents a mail folder
 type Folder struct {
-	ID              string `json:"id"`
-	DisplayName     string `json:"display_name"`
-	TotalItemCount  int32  `json:"total_item_count"`
-	UnreadItemCount int32  `json:"unread_item_count"`
-	ParentFolderID  string `json:"parent_folder_id,omitempty"`
+	ID               string   `json:"id"`
+	DisplayName      string   `json:"display_name"`
+	TotalItemCount   int32    `json:"total_item_count"`
+	UnreadItemCount  int32    `json:"unread_item_count"`
+	ParentFolderID   string   `json:"parent_folder_id,omitempty"`
+	ChildFolderCount int32    `json:"child_folder_count"`
+	Path             string   `json:"path,omitempty"`
+	Children         []Folder `json:"children,omitempty"`
 }
 
-// ListFolders retrieves all mail folders
+// folderPageSize is the page size used when listing folders
+const folderPageSize int32 = 100
+
+// ListFolders retrieves all top-level mail folders
 func ListFolders(ctx context.Context, client *msgraph.GraphServiceClient) ([]Folder, error) {
-	result, err := client.Me().MailFolders().Get(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list folders: %w", err)
+	top := folderPageSize
+	requestConfig := &users.ItemMailFoldersRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailFoldersRequestBuilderGetQueryParameters{
+			Top: &top,
+		},
 	}
 
+	builder := client.Me().MailFolders()
 	folders := make([]Folder, 0)
-	for _, f := range result.GetValue() {
-		folders = append(folders, convertFolder(f))
+	for {
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list folders: %w", err)
+		}
+
+		for _, f := range result.GetValue() {
+			folders = append(folders, convertFolder(f))
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			break
+		}
+		builder = builder.WithUrl(*next)
+		requestConfig = nil
 	}
 
 	return folders, nil
 }
 
+// ListChildFolders retrieves the direct child folders of a folder
+func ListChildFolders(ctx context.Context, client *msgraph.GraphServiceClient, folderID string) ([]Folder, error) {
+	top := folderPageSize
+	requestConfig := &users.ItemMailFoldersItemChildFoldersRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailFoldersItemChildFoldersRequestBuilderGetQueryParameters{
+			Top: &top,
+		},
+	}
+
+	builder := client.Me().MailFolders().ByMailFolderId(folderID).ChildFolders()
+	folders := make([]Folder, 0)
+	for {
+		result, err := builder.Get(ctx, requestConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list child folders: %w", err)
+		}
+
+		for _, f := range result.GetValue() {
+			folders = append(folders, convertFolder(f))
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			break
+		}
+		builder = builder.WithUrl(*next)
+		requestConfig = nil
+	}
+
+	return folders, nil
+}
+
+// ListFolderTree retrieves all mail folders recursively. The returned
+// top-level folders have their Children and Path fields populated.
+func ListFolderTree(ctx context.Context, client *msgraph.GraphServiceClient) ([]Folder, error) {
+	folders, err := ListFolders(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range folders {
+		if err := loadChildren(ctx, client, &folders[i], folders[i].DisplayName); err != nil {
+			return nil, err
+		}
+	}
+
+	return folders, nil
+}
+
+// loadChildren fills in the Children of a folder, recursing into any
+// folder that reports child folders of its own
+func loadChildren(ctx context.Context, client *msgraph.GraphServiceClient, folder *Folder, path string) error {
+	folder.Path = path
+	if folder.ChildFolderCount == 0 {
+		return nil
+	}
+
+	children, err := ListChildFolders(ctx, client, folder.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range children {
+		if err := loadChildren(ctx, client, &children[i], path+"/"+children[i].DisplayName); err != nil {
+			return err
+		}
+	}
+
+	folder.Children = children
+	return nil
+}
+
+// WalkFolders calls fn for every folder in the tree in depth-first order.
+// Depth is 0 for top-level folders.
+func WalkFolders(folders []Folder, fn func(f Folder, depth int)) {
+	walkFolders(folders, 0, fn)
+}
+
+func walkFolders(folders []Folder, depth int, fn func(f Folder, depth int)) {
+	for _, f := range folders {
+		fn(f, depth)
+		walkFolders(f.Children, depth+1, fn)
+	}
+}
+
 // GetFolder retrieves a single folder by ID or well-known name
 func GetFolder(ctx context.Context, client *msgraph.GraphServiceClient, folderID string) (*Folder, error) {
 	f, err := client.Me().MailFolders().ByMailFolderId(folderID).Get(ctx, nil)
@@ -57,6 +166,132 @@ func MoveMessage(ctx context.Context, client *msgraph.GraphServiceClient, messag
 	return nil
 }
 
+// CreateFolder creates a folder under parentID, or at the top level when
+// parentID is empty
+func CreateFolder(ctx context.Context, client *msgraph.GraphServiceClient, parentID, name string) (*Folder, error) {
+	body := models.NewMailFolder()
+	body.SetDisplayName(&name)
+
+	var created models.MailFolderable
+	var err error
+
+	if parentID == "" {
+		created, err = client.Me().MailFolders().Post(ctx, body, nil)
+	} else {
+		created, err = client.Me().MailFolders().ByMailFolderId(parentID).ChildFolders().Post(ctx, body, nil)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folder %q: %w", name, err)
+	}
+
+	_ = InvalidateFolderCache()
+
+	folder := convertFolder(created)
+	return &folder, nil
+}
+
+// CreateFolderPath creates the folder at path, creating any missing parent
+// folders along the way. If the folder already exists it is returned when
+// ifNotExists is set and an error is returned otherwise. The boolean result
+// reports whether the final folder was created.
+func CreateFolderPath(ctx context.Context, client *msgraph.GraphServiceClient, path string, ifNotExists bool) (*Folder, bool, error) {
+	if len(splitFolderPath(path)) == 0 {
+		return nil, false, fmt.Errorf("folder path is empty")
+	}
+
+	paths, err := RefreshFolderCache(ctx, client)
+	if err != nil {
+		return nil, false, err
+	}
+
+	parentID, missing := missingFolderSegments(paths, path)
+	if len(missing) == 0 {
+		if !ifNotExists {
+			return nil, false, fmt.Errorf("folder already exists: %s", path)
+		}
+		folder, err := GetFolder(ctx, client, parentID)
+		if err != nil {
+			return nil, false, err
+		}
+		return folder, false, nil
+	}
+
+	var folder *Folder
+	for _, name := range missing {
+		folder, err = CreateFolder(ctx, client, parentID, name)
+		if err != nil {
+			return nil, false, err
+		}
+		parentID = folder.ID
+	}
+
+	return folder, true, nil
+}
+
+// missingFolderSegments walks path against a path-to-ID map and returns the
+// ID of the deepest existing folder along with the segments still missing
+// below it. An empty parent ID means the top level.
+func missingFolderSegments(paths map[string]string, path string) (string, []string) {
+	segments := splitFolderPath(path)
+
+	parentID := ""
+	for i := range segments {
+		key := strings.ToLower(strings.Join(segments[:i+1], "/"))
+		id, ok := paths[key]
+		if !ok {
+			return parentID, segments[i:]
+		}
+		parentID = id
+	}
+
+	return parentID, nil
+}
+
+// RenameFolder changes the display name of a folder
+func RenameFolder(ctx context.Context, client *msgraph.GraphServiceClient, folderID, name string) (*Folder, error) {
+	body := models.NewMailFolder()
+	body.SetDisplayName(&name)
+
+	updated, err := client.Me().MailFolders().ByMailFolderId(folderID).Patch(ctx, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	_ = InvalidateFolderCache()
+
+	folder := convertFolder(updated)
+	return &folder, nil
+}
+
+// MoveFolder moves a folder under a new parent folder
+func MoveFolder(ctx context.Context, client *msgraph.GraphServiceClient, folderID, destinationFolderID string) (*Folder, error) {
+	body := users.NewItemMailFoldersItemMovePostRequestBody()
+	body.SetDestinationId(&destinationFolderID)
+
+	moved, err := client.Me().MailFolders().ByMailFolderId(folderID).Move().Post(ctx, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move folder: %w", err)
+	}
+
+	_ = InvalidateFolderCache()
+
+	folder := convertFolder(moved)
+	return &folder, nil
+}
+
+// DeleteFolder deletes a folder and everything in it
+func DeleteFolder(ctx context.Context, client *msgraph.GraphServiceClient, folderID string) error {
+	err := client.Me().MailFolders().ByMailFolderId(folderID).Delete(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+
+	_ = InvalidateFolderCache()
+
+	return nil
+}
+
 // convertFolder converts a Graph API folder to our Folder type
 func convertFolder(f models.MailFolderable) Folder {
 	folder := Folder{
@@ -76,6 +311,10 @@ func convertFolder(f models.MailFolderable) Folder {
 		folder.ParentFolderID = *parentID
 	}
 
+	if count := f.GetChildFolderCount(); count != nil {
+		folder.ChildFolderCount = *count
+	}
+
 	return folder
 }
 
@@ -87,4 +326,5 @@ const (
 	FolderDeleted   = "deleteditems"
 	FolderJunk      = "junkemail"
 	FolderArchive   = "archive"
+	FolderRoot      = "msgfolderroot"
 )
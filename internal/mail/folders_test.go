@@ -0,0 +1,205 @@
+package mail
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testFolderTree() []Folder {
+	return []Folder{
+		{
+			ID:          "inbox-id",
+			DisplayName: "Inbox",
+			Path:        "Inbox",
+			Children: []Folder{
+				{
+					ID:          "projects-id",
+					DisplayName: "Projects",
+					Path:        "Inbox/Projects",
+					Children: []Folder{
+						{ID: "acme-id", DisplayName: "Acme", Path: "Inbox/Projects/Acme"},
+					},
+				},
+			},
+		},
+		{ID: "archive-id", DisplayName: "Archive", Path: "Archive"},
+	}
+}
+
+func TestWalkFolders(t *testing.T) {
+	var names []string
+	var depths []int
+	WalkFolders(testFolderTree(), func(f Folder, depth int) {
+		names = append(names, f.DisplayName)
+		depths = append(depths, depth)
+	})
+
+	wantNames := []string{"Inbox", "Projects", "Acme", "Archive"}
+	wantDepths := []int{0, 1, 2, 0}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("WalkFolders names = %v, want %v", names, wantNames)
+	}
+	if !reflect.DeepEqual(depths, wantDepths) {
+		t.Errorf("WalkFolders depths = %v, want %v", depths, wantDepths)
+	}
+}
+
+func TestBuildFolderPaths(t *testing.T) {
+	paths := buildFolderPaths(testFolderTree())
+
+	tests := map[string]string{
+		"inbox":               "inbox-id",
+		"inbox/projects":      "projects-id",
+		"inbox/projects/acme": "acme-id",
+		"archive":             "archive-id",
+	}
+	for path, want := range tests {
+		if got := paths[path]; got != want {
+			t.Errorf("paths[%q] = %q, want %q", path, got, want)
+		}
+	}
+	if len(paths) != len(tests) {
+		t.Errorf("len(paths) = %d, want %d", len(paths), len(tests))
+	}
+}
+
+func TestNormalizeFolderPath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Inbox/Projects/Acme", "inbox/projects/acme"},
+		{"/Inbox//Projects/", "inbox/projects"},
+		{" Inbox / Projects ", "inbox/projects"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeFolderPath(tt.input); got != tt.want {
+			t.Errorf("normalizeFolderPath(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIsWellKnownFolder(t *testing.T) {
+	for _, name := range []string{"inbox", "Inbox", "SENTITEMS", "archive"} {
+		if !IsWellKnownFolder(name) {
+			t.Errorf("IsWellKnownFolder(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"Projects", "Inbox/Projects", ""} {
+		if IsWellKnownFolder(name) {
+			t.Errorf("IsWellKnownFolder(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestLooksLikeFolderID(t *testing.T) {
+	id := "AAMkAGI2THVSAAA-AAAAAAEMAAAiIsqMbYjsT5e-T7KzowPTAAAYbvZJAAA="
+	if !looksLikeFolderID(id) {
+		t.Errorf("looksLikeFolderID(%q) = false, want true", id)
+	}
+	for _, ref := range []string{"Projects", "Inbox/Projects/Acme", "A folder name with spaces that is quite long"} {
+		if looksLikeFolderID(ref) {
+			t.Errorf("looksLikeFolderID(%q) = true, want false", ref)
+		}
+	}
+}
+
+func TestFolderCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "octl-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	t.Run("Save and load roundtrip", func(t *testing.T) {
+		cache := &folderCache{
+			UpdatedAt: time.Now(),
+			Paths:     map[string]string{"inbox/projects": "projects-id"},
+		}
+		if err := saveFolderCache(cache); err != nil {
+			t.Fatalf("saveFolderCache() error = %v", err)
+		}
+
+		loaded, err := loadFolderCache()
+		if err != nil {
+			t.Fatalf("loadFolderCache() error = %v", err)
+		}
+		if loaded.Paths["inbox/projects"] != "projects-id" {
+			t.Errorf("Paths[inbox/projects] = %q, want %q", loaded.Paths["inbox/projects"], "projects-id")
+		}
+	})
+
+	t.Run("Invalidate removes the cache", func(t *testing.T) {
+		if err := InvalidateFolderCache(); err != nil {
+			t.Fatalf("InvalidateFolderCache() error = %v", err)
+		}
+		if _, err := loadFolderCache(); err == nil {
+			t.Error("loadFolderCache() after invalidate should fail")
+		}
+		if err := InvalidateFolderCache(); err != nil {
+			t.Errorf("InvalidateFolderCache() on missing cache error = %v", err)
+		}
+	})
+}
+
+func TestMissingFolderSegments(t *testing.T) {
+	paths := buildFolderPaths(testFolderTree())
+
+	tests := []struct {
+		name        string
+		path        string
+		wantParent  string
+		wantMissing []string
+	}{
+		{
+			name:        "existing folder",
+			path:        "Inbox/Projects/Acme",
+			wantParent:  "acme-id",
+			wantMissing: nil,
+		},
+		{
+			name:        "case-insensitive match",
+			path:        "INBOX/projects",
+			wantParent:  "projects-id",
+			wantMissing: nil,
+		},
+		{
+			name:        "missing leaf",
+			path:        "Inbox/Projects/Globex",
+			wantParent:  "projects-id",
+			wantMissing: []string{"Globex"},
+		},
+		{
+			name:        "missing parents",
+			path:        "Inbox/Clients/Initech/2024",
+			wantParent:  "inbox-id",
+			wantMissing: []string{"Clients", "Initech", "2024"},
+		},
+		{
+			name:        "new top-level folder",
+			path:        "Receipts/2024",
+			wantParent:  "",
+			wantMissing: []string{"Receipts", "2024"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, missing := missingFolderSegments(paths, tt.path)
+			if parent != tt.wantParent {
+				t.Errorf("parent = %q, want %q", parent, tt.wantParent)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
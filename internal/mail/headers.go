@@ -0,0 +1,313 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	netmail "net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// Header is one internet message header
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// GetHeaders retrieves a message's internet headers in their original
+// order. Drafts and some sent items have none.
+func GetHeaders(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) ([]Header, error) {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "internetMessageHeaders"},
+		},
+	}
+
+	msg, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message headers: %w", err)
+	}
+
+	headers := make([]Header, 0)
+	for _, h := range msg.GetInternetMessageHeaders() {
+		headers = append(headers, Header{Name: safeString(h.GetName()), Value: safeString(h.GetValue())})
+	}
+	return headers, nil
+}
+
+// HeaderValues returns the values of every header with the given name,
+// matched case-insensitively
+func HeaderValues(headers []Header, name string) []string {
+	var values []string
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+// HeaderValue returns the first value of a header, or ""
+func HeaderValue(headers []Header, name string) string {
+	if values := HeaderValues(headers, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Hop is one Received header: a server handing the message on
+type Hop struct {
+	From string    `json:"from,omitempty"`
+	By   string    `json:"by,omitempty"`
+	With string    `json:"with,omitempty"`
+	At   time.Time `json:"at"`
+	// Delay is the time since the previous hop; clock skew between
+	// servers can make it negative
+	Delay        time.Duration `json:"-"`
+	DelaySeconds float64       `json:"delay_seconds"`
+}
+
+// Verdict is one field of an anti-spam header
+type Verdict struct {
+	Header  string `json:"header"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Meaning string `json:"meaning,omitempty"`
+}
+
+// HeaderSummary is a parsed view of the headers useful for tracing delivery
+type HeaderSummary struct {
+	MessageID  string   `json:"message_id,omitempty"`
+	InReplyTo  string   `json:"in_reply_to,omitempty"`
+	References []string `json:"references,omitempty"`
+	// Received lists the hops oldest first
+	Received []Hop     `json:"received"`
+	List     []Header  `json:"list,omitempty"`
+	AntiSpam []Verdict `json:"anti_spam,omitempty"`
+}
+
+// Transit returns the time between the first and last timestamped hops
+func (s *HeaderSummary) Transit() time.Duration {
+	var first, last time.Time
+	for _, hop := range s.Received {
+		if hop.At.IsZero() {
+			continue
+		}
+		if first.IsZero() {
+			first = hop.At
+		}
+		last = hop.At
+	}
+	return last.Sub(first)
+}
+
+// SummarizeHeaders parses the delivery-related headers
+func SummarizeHeaders(headers []Header) *HeaderSummary {
+	s := &HeaderSummary{
+		MessageID:  strings.TrimSpace(HeaderValue(headers, "Message-ID")),
+		InReplyTo:  strings.TrimSpace(HeaderValue(headers, "In-Reply-To")),
+		References: strings.Fields(HeaderValue(headers, "References")),
+		Received:   make([]Hop, 0),
+	}
+
+	// Each server prepends its Received header, so the newest comes first
+	received := HeaderValues(headers, "Received")
+	var prev time.Time
+	for i := len(received) - 1; i >= 0; i-- {
+		hop := ParseReceived(received[i])
+		if !hop.At.IsZero() {
+			if !prev.IsZero() {
+				hop.Delay = hop.At.Sub(prev)
+				hop.DelaySeconds = hop.Delay.Seconds()
+			}
+			prev = hop.At
+		}
+		s.Received = append(s.Received, hop)
+	}
+
+	for _, h := range headers {
+		if strings.HasPrefix(strings.ToLower(h.Name), "list-") {
+			s.List = append(s.List, h)
+		}
+	}
+
+	s.AntiSpam = antiSpamVerdicts(headers)
+	return s
+}
+
+var (
+	receivedFrom = regexp.MustCompile(`(?i)\bfrom\s+(\S+)`)
+	receivedBy   = regexp.MustCompile(`(?i)\bby\s+(\S+)`)
+	receivedWith = regexp.MustCompile(`(?i)\bwith\s+(\S+)`)
+	comment      = regexp.MustCompile(`\([^()]*\)`)
+)
+
+// ParseReceived parses a Received header. Fields that cannot be found are
+// left empty.
+func ParseReceived(value string) Hop {
+	value = strings.Join(strings.Fields(value), " ")
+
+	var hop Hop
+	clauses := value
+	if i := strings.LastIndex(value, ";"); i >= 0 {
+		clauses = value[:i]
+		hop.At = parseHeaderDate(value[i+1:])
+	}
+
+	// Comments hold reverse DNS and IP details that would confuse the
+	// clause matching
+	clauses = comment.ReplaceAllString(clauses, "")
+	if m := receivedFrom.FindStringSubmatch(clauses); m != nil {
+		hop.From = m[1]
+	}
+	if m := receivedBy.FindStringSubmatch(clauses); m != nil {
+		hop.By = m[1]
+	}
+	if m := receivedWith.FindStringSubmatch(clauses); m != nil {
+		hop.With = m[1]
+	}
+	return hop
+}
+
+// parseHeaderDate parses an RFC 5322 date, ignoring trailing comments
+func parseHeaderDate(s string) time.Time {
+	s = strings.TrimSpace(comment.ReplaceAllString(s, ""))
+	t, err := netmail.ParseDate(s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// forefrontMeanings explains X-Forefront-Antispam-Report fields
+var forefrontMeanings = map[string]string{
+	"CIP":  "connecting IP address",
+	"CTRY": "source country",
+	"LANG": "message language",
+	"SCL":  "spam confidence level",
+	"SFV":  "spam filtering verdict",
+	"CAT":  "protection policy category",
+	"PTR":  "reverse DNS of the connecting IP",
+	"IPV":  "IP reputation",
+	"DIR":  "direction",
+	"H":    "HELO/EHLO name",
+	"SFTY": "safety tip",
+}
+
+// verdictValues explains common verdict values
+var verdictValues = map[string]map[string]string{
+	"SFV": {
+		"NSPM": "not spam",
+		"SPM":  "spam",
+		"BLK":  "blocked sender",
+		"SFE":  "safe sender",
+		"SKA":  "allowed by policy",
+		"SKB":  "blocked by policy",
+		"SKI":  "intra-organization",
+		"SKN":  "marked not spam by a rule",
+		"SKQ":  "released from quarantine",
+		"SKS":  "marked spam by a rule",
+	},
+	"CAT": {
+		"NONE":  "no category",
+		"BULK":  "bulk",
+		"SPM":   "spam",
+		"HSPM":  "high confidence spam",
+		"PHSH":  "phishing",
+		"HPHSH": "high confidence phishing",
+		"MALW":  "malware",
+		"SPOOF": "spoofing",
+		"GIMP":  "mailbox intelligence impersonation",
+		"UIMP":  "user impersonation",
+		"DIMP":  "domain impersonation",
+		"OSPM":  "outbound spam",
+		"AMP":   "anti-malware",
+		"SAP":   "safe attachments",
+	},
+	"IPV": {
+		"CAL": "allowed by connection filter",
+		"NLI": "not on any block list",
+	},
+	"DIR": {
+		"INB": "inbound",
+		"OUT": "outbound",
+		"INT": "internal",
+	},
+}
+
+// antiSpamVerdicts extracts the verdicts from Exchange Online Protection
+// headers
+func antiSpamVerdicts(headers []Header) []Verdict {
+	var verdicts []Verdict
+
+	if v := strings.TrimSpace(HeaderValue(headers, "X-MS-Exchange-Organization-SCL")); v != "" {
+		verdicts = append(verdicts, Verdict{
+			Header: "X-MS-Exchange-Organization-SCL", Key: "SCL", Value: v, Meaning: describeSCL(v),
+		})
+	}
+
+	for _, field := range splitFields(HeaderValue(headers, "X-Forefront-Antispam-Report")) {
+		key, value := field[0], field[1]
+		meaning, ok := forefrontMeanings[key]
+		if !ok || value == "" {
+			continue
+		}
+		if explained := verdictValues[key][value]; explained != "" {
+			meaning += ": " + explained
+		}
+		if key == "SCL" {
+			meaning += ": " + describeSCL(value)
+		}
+		verdicts = append(verdicts, Verdict{Header: "X-Forefront-Antispam-Report", Key: key, Value: value, Meaning: meaning})
+	}
+
+	for _, field := range splitFields(HeaderValue(headers, "X-Microsoft-Antispam")) {
+		if field[0] == "BCL" {
+			verdicts = append(verdicts, Verdict{
+				Header: "X-Microsoft-Antispam", Key: "BCL", Value: field[1], Meaning: "bulk complaint level (0-9, higher is more likely bulk)",
+			})
+		}
+	}
+
+	if v := strings.TrimSpace(HeaderValue(headers, "X-MS-Exchange-Organization-AuthAs")); v != "" {
+		verdicts = append(verdicts, Verdict{
+			Header: "X-MS-Exchange-Organization-AuthAs", Key: "AuthAs", Value: v, Meaning: "how the sender authenticated",
+		})
+	}
+
+	return verdicts
+}
+
+// describeSCL explains a spam confidence level
+func describeSCL(v string) string {
+	switch v {
+	case "-1":
+		return "skipped filtering"
+	case "0", "1":
+		return "not spam"
+	case "5", "6":
+		return "spam"
+	case "9":
+		return "high confidence spam"
+	}
+	return ""
+}
+
+// splitFields splits a "KEY:value;KEY:value" header into pairs. Values may
+// themselves contain colons.
+func splitFields(value string) [][2]string {
+	var fields [][2]string
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		key, val, ok := strings.Cut(part, ":")
+		if !ok || key == "" {
+			continue
+		}
+		fields = append(fields, [2]string{strings.TrimSpace(key), strings.TrimSpace(val)})
+	}
+	return fields
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseReceived(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Hop
+	}{
+		{
+			name: "exchange online",
+			input: "from AM0PR01MB1234.eurprd01.prod.outlook.com (2603:10a6:208:1::1) by\n" +
+				" AM0PR01MB5678.eurprd01.prod.outlook.com with HTTPS; Tue, 14 Oct 2026\n 10:00:05 +0000",
+			want: Hop{
+				From: "AM0PR01MB1234.eurprd01.prod.outlook.com",
+				By:   "AM0PR01MB5678.eurprd01.prod.outlook.com",
+				With: "HTTPS",
+				At:   time.Date(2026, 10, 14, 10, 0, 5, 0, time.UTC),
+			},
+		},
+		{
+			name:  "comments and zone name",
+			input: "from mail.example.com (mail.example.com [192.0.2.1]) by mx.example.net (Postfix) with ESMTPS id 4Xyz; Tue, 14 Oct 2026 12:00:00 +0200 (CEST)",
+			want: Hop{
+				From: "mail.example.com",
+				By:   "mx.example.net",
+				With: "ESMTPS",
+				At:   time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "no date",
+			input: "by localhost",
+			want:  Hop{By: "localhost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseReceived(tt.input)
+			if got.From != tt.want.From || got.By != tt.want.By || got.With != tt.want.With || !got.At.Equal(tt.want.At) {
+				t.Errorf("ParseReceived() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeHeaders(t *testing.T) {
+	headers := []Header{
+		{Name: "Received", Value: "from b.example.net by c.example.org with SMTP; Tue, 14 Oct 2026 10:00:07 +0000"},
+		{Name: "Received", Value: "from a.example.com by b.example.net with ESMTPS; Tue, 14 Oct 2026 10:00:02 +0000"},
+		{Name: "Received", Value: "from laptop by a.example.com with ESMTPSA; Tue, 14 Oct 2026 10:00:00 +0000"},
+		{Name: "Message-ID", Value: "<abc@example.com>"},
+		{Name: "In-Reply-To", Value: "<prev@example.com>"},
+		{Name: "References", Value: "<root@example.com>\n <prev@example.com>"},
+		{Name: "List-Id", Value: "Team <team.example.com>"},
+		{Name: "List-Unsubscribe", Value: "<mailto:leave@example.com>"},
+		{Name: "X-MS-Exchange-Organization-SCL", Value: "1"},
+		{Name: "X-Forefront-Antispam-Report", Value: "CIP:192.0.2.1;CTRY:US;LANG:en;SCL:1;SRV:;IPV:NLI;SFV:NSPM;H:a.example.com;PTR:;CAT:NONE;SFS:(13230040);DIR:INB;"},
+		{Name: "X-Microsoft-Antispam", Value: "BCL:0;"},
+	}
+
+	s := SummarizeHeaders(headers)
+
+	if s.MessageID != "<abc@example.com>" || s.InReplyTo != "<prev@example.com>" {
+		t.Errorf("ids = %q, %q", s.MessageID, s.InReplyTo)
+	}
+	if !reflect.DeepEqual(s.References, []string{"<root@example.com>", "<prev@example.com>"}) {
+		t.Errorf("References = %v", s.References)
+	}
+
+	var from []string
+	var delays []time.Duration
+	for _, hop := range s.Received {
+		from = append(from, hop.From)
+		delays = append(delays, hop.Delay)
+	}
+	if !reflect.DeepEqual(from, []string{"laptop", "a.example.com", "b.example.net"}) {
+		t.Errorf("hops = %v, want oldest first", from)
+	}
+	if !reflect.DeepEqual(delays, []time.Duration{0, 2 * time.Second, 5 * time.Second}) {
+		t.Errorf("delays = %v", delays)
+	}
+	if s.Transit() != 7*time.Second {
+		t.Errorf("Transit() = %v, want 7s", s.Transit())
+	}
+
+	if len(s.List) != 2 || s.List[0].Name != "List-Id" {
+		t.Errorf("List = %v", s.List)
+	}
+
+	verdicts := map[string]string{}
+	for _, v := range s.AntiSpam {
+		verdicts[v.Header+" "+v.Key] = v.Value
+	}
+	want := map[string]string{
+		"X-MS-Exchange-Organization-SCL SCL": "1",
+		"X-Forefront-Antispam-Report CIP":    "192.0.2.1",
+		"X-Forefront-Antispam-Report CTRY":   "US",
+		"X-Forefront-Antispam-Report LANG":   "en",
+		"X-Forefront-Antispam-Report SCL":    "1",
+		"X-Forefront-Antispam-Report IPV":    "NLI",
+		"X-Forefront-Antispam-Report SFV":    "NSPM",
+		"X-Forefront-Antispam-Report H":      "a.example.com",
+		"X-Forefront-Antispam-Report CAT":    "NONE",
+		"X-Forefront-Antispam-Report DIR":    "INB",
+		"X-Microsoft-Antispam BCL":           "0",
+	}
+	if !reflect.DeepEqual(verdicts, want) {
+		t.Errorf("verdicts = %v, want %v", verdicts, want)
+	}
+}
+
+func TestHeaderValues(t *testing.T) {
+	headers := []Header{{Name: "received", Value: "a"}, {Name: "Subject", Value: "x"}, {Name: "RECEIVED", Value: "b"}}
+	if got := HeaderValues(headers, "Received"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("HeaderValues() = %v", got)
+	}
+	if got := HeaderValue(headers, "Missing"); got != "" {
+		t.Errorf("HeaderValue() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,291 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	netmail "net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// MaxImportSize is the largest message accepted for import. The JSON
+// request, with attachments base64-encoded, must stay under Graph's 4 MB
+// request limit.
+const MaxImportSize = 3 * 1024 * 1024
+
+// ImportItem is a parsed .eml file ready to be imported
+type ImportItem struct {
+	Path       string    `json:"path"`
+	Subject    string    `json:"subject"`
+	From       string    `json:"from"`
+	ReceivedAt time.Time `json:"received_at"`
+	IsRead     bool      `json:"is_read"`
+	IsFlagged  bool      `json:"is_flagged"`
+	Size       int       `json:"size"`
+	content    []byte
+}
+
+// ParseImportFile reads and validates a MIME message file. Read and flag
+// state come from mbox-style Status/X-Status headers or Maildir file name
+// flags; the received date comes from the newest Received header, falling
+// back to the Date header.
+func ParseImportFile(path string) (*ImportItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return parseImport(path, data)
+}
+
+// parseImport validates MIME content and extracts its import metadata
+func parseImport(path string, data []byte) (*ImportItem, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if len(data) > MaxImportSize {
+		return nil, fmt.Errorf("message is %d bytes, larger than the %d byte import limit", len(data), MaxImportSize)
+	}
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid MIME message: %w", err)
+	}
+
+	header := msg.Header
+	if header.Get("From") == "" && header.Get("Date") == "" && header.Get("Subject") == "" {
+		return nil, fmt.Errorf("not a valid MIME message: no From, Date or Subject header")
+	}
+
+	item := &ImportItem{
+		Path:    path,
+		Size:    len(data),
+		content: data,
+	}
+
+	decoder := new(mime.WordDecoder)
+	item.Subject = header.Get("Subject")
+	if decoded, err := decoder.DecodeHeader(item.Subject); err == nil {
+		item.Subject = decoded
+	}
+
+	if addrs, err := header.AddressList("From"); err == nil && len(addrs) > 0 {
+		item.From = addrs[0].Address
+	}
+
+	item.ReceivedAt = receivedTime(header)
+
+	status := header.Get("Status") + header.Get("X-Status")
+	item.IsRead = strings.Contains(status, "R")
+	item.IsFlagged = strings.Contains(status, "F")
+
+	// Maildir file names carry flags after ":2,"
+	if i := strings.LastIndex(filepath.Base(path), ":2,"); i >= 0 {
+		flags := filepath.Base(path)[i+3:]
+		item.IsRead = item.IsRead || strings.Contains(flags, "S")
+		item.IsFlagged = item.IsFlagged || strings.Contains(flags, "F")
+	}
+
+	return item, nil
+}
+
+// receivedTime returns the delivery time from the newest Received header,
+// or the Date header when there is none
+func receivedTime(header netmail.Header) time.Time {
+	if received := header["Received"]; len(received) > 0 {
+		if i := strings.LastIndex(received[0], ";"); i >= 0 {
+			if t, err := netmail.ParseDate(strings.TrimSpace(received[0][i+1:])); err == nil {
+				return t
+			}
+		}
+	}
+
+	if t, err := header.Date(); err == nil {
+		return t
+	}
+
+	return time.Time{}
+}
+
+// Extended properties set when an imported message is created. Exchange
+// only accepts them at creation, not in a later update.
+const (
+	// PR_MESSAGE_FLAGS; leaving out MSGFLAG_UNSENT makes the message a
+	// received one rather than a draft
+	propMessageFlags = "Integer 0x0E07"
+	// PR_MESSAGE_DELIVERY_TIME, shown by Outlook as the received date
+	propDeliveryTime = "SystemTime 0x0E06"
+	// PR_CLIENT_SUBMIT_TIME, the sent date
+	propSubmitTime = "SystemTime 0x0039"
+	// PR_TRANSPORT_MESSAGE_HEADERS, the original internet headers
+	propTransportHeaders = "String 0x007D"
+)
+
+// msgflagRead is MSGFLAG_READ
+const msgflagRead = 1
+
+// ImportMessage creates a message in a folder from the item's MIME content.
+// Graph creates messages uploaded as MIME as drafts and cannot change that
+// afterwards, so the message is converted and created with its read state,
+// flag, dates, and original headers set. Parts that could not be converted
+// cleanly are returned as warnings.
+func ImportMessage(ctx context.Context, client *msgraph.GraphServiceClient, folderID string, item *ImportItem) (*Message, []string, error) {
+	msg, warnings, err := newImportMessage(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, err := client.Me().MailFolders().ByMailFolderId(folderID).Messages().Post(ctx, msg, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to import message: %w", err)
+	}
+
+	message := convertMessage(created)
+	return &message, warnings, nil
+}
+
+// newImportMessage converts an import item to the Graph message that
+// creates it
+func newImportMessage(item *ImportItem) (models.Messageable, []string, error) {
+	parsed, err := netmail.ReadMessage(bytes.NewReader(item.content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a valid MIME message: %w", err)
+	}
+	header := parsed.Header
+
+	content, err := parseMIME(textproto.MIMEHeader(header), parsed.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings := content.warnings
+
+	msg := models.NewMessage()
+	subject := item.Subject
+	msg.SetSubject(&subject)
+
+	if from, err := header.AddressList("From"); err == nil && len(from) > 0 {
+		msg.SetFrom(newRecipient(from[0]))
+		msg.SetSender(newRecipient(from[0]))
+	}
+	lists := []struct {
+		name string
+		set  func([]models.Recipientable)
+	}{
+		{"To", msg.SetToRecipients},
+		{"Cc", msg.SetCcRecipients},
+		{"Bcc", msg.SetBccRecipients},
+		{"Reply-To", msg.SetReplyTo},
+	}
+	for _, list := range lists {
+		if header.Get(list.name) == "" {
+			continue
+		}
+		addrs, err := header.AddressList(list.name)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not parse %s header: %v", list.name, err))
+			continue
+		}
+		recipients := make([]models.Recipientable, len(addrs))
+		for i, addr := range addrs {
+			recipients[i] = newRecipient(addr)
+		}
+		list.set(recipients)
+	}
+
+	if id := strings.TrimSpace(header.Get("Message-Id")); id != "" {
+		msg.SetInternetMessageId(&id)
+	}
+
+	body := models.NewItemBody()
+	bodyType := models.TEXT_BODYTYPE
+	bodyContent := content.text
+	if content.html != "" {
+		bodyType = models.HTML_BODYTYPE
+		bodyContent = content.html
+	}
+	body.SetContentType(&bodyType)
+	body.SetContent(&bodyContent)
+	msg.SetBody(body)
+
+	if len(content.attachments) > 0 {
+		attachments := make([]models.Attachmentable, len(content.attachments))
+		for i, a := range content.attachments {
+			attachment := models.NewFileAttachment()
+			name, contentType, data, inline := a.name, a.contentType, a.data, a.inline
+			attachment.SetName(&name)
+			attachment.SetContentType(&contentType)
+			attachment.SetContentBytes(data)
+			attachment.SetIsInline(&inline)
+			if a.contentID != "" {
+				contentID := a.contentID
+				attachment.SetContentId(&contentID)
+			}
+			attachments[i] = attachment
+		}
+		msg.SetAttachments(attachments)
+		hasAttachments := true
+		msg.SetHasAttachments(&hasAttachments)
+	}
+
+	if item.IsFlagged {
+		flag := models.NewFollowupFlag()
+		status := models.FLAGGED_FOLLOWUPFLAGSTATUS
+		flag.SetFlagStatus(&status)
+		msg.SetFlag(flag)
+	}
+
+	flags := 0
+	if item.IsRead {
+		flags |= msgflagRead
+	}
+	props := []models.SingleValueLegacyExtendedPropertyable{
+		extendedProperty(propMessageFlags, strconv.Itoa(flags)),
+	}
+	if !item.ReceivedAt.IsZero() {
+		props = append(props, extendedProperty(propDeliveryTime, item.ReceivedAt.UTC().Format(time.RFC3339)))
+	}
+	if sent, err := header.Date(); err == nil {
+		props = append(props, extendedProperty(propSubmitTime, sent.UTC().Format(time.RFC3339)))
+	}
+	if raw := rawHeaders(item.content); raw != "" {
+		props = append(props, extendedProperty(propTransportHeaders, raw))
+	}
+	msg.SetSingleValueExtendedProperties(props)
+
+	return msg, warnings, nil
+}
+
+// extendedProperty builds a single-value extended property
+func extendedProperty(id, value string) models.SingleValueLegacyExtendedPropertyable {
+	prop := models.NewSingleValueLegacyExtendedProperty()
+	prop.SetId(&id)
+	prop.SetValue(&value)
+	return prop
+}
+
+// rawHeaders returns the header section of a MIME message with CRLF line
+// endings, as Exchange stores transport headers
+func rawHeaders(data []byte) string {
+	end := len(data)
+	if i := bytes.Index(data, []byte("\r\n\r\n")); i >= 0 {
+		end = i
+	}
+	if i := bytes.Index(data, []byte("\n\n")); i >= 0 && i < end {
+		end = i
+	}
+	text := strings.ReplaceAll(string(data[:end]), "\r\n", "\n")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	return strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"
+}
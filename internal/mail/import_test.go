@@ -0,0 +1,242 @@
+package mail
+
+import (
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+const importSample = "Received: from mx.example.com by mail.example.net; Tue, 16 Jan 2024 08:00:05 +0000\r\n" +
+	"From: \"Jane Doe\" <jane@example.com>\r\n" +
+	"Subject: =?utf-8?q?Caf=C3=A9_plans?=\r\n" +
+	"Date: Tue, 16 Jan 2024 07:59:00 +0000\r\n" +
+	"\r\n" +
+	"See you there.\r\n"
+
+func TestParseImport(t *testing.T) {
+	t.Run("extracts metadata", func(t *testing.T) {
+		item, err := parseImport("msg.eml", []byte(importSample))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		if item.Subject != "Café plans" {
+			t.Errorf("Subject = %q, want %q", item.Subject, "Café plans")
+		}
+		if item.From != "jane@example.com" {
+			t.Errorf("From = %q, want %q", item.From, "jane@example.com")
+		}
+		want := time.Date(2024, 1, 16, 8, 0, 5, 0, time.UTC)
+		if !item.ReceivedAt.Equal(want) {
+			t.Errorf("ReceivedAt = %v, want %v (from Received header)", item.ReceivedAt, want)
+		}
+		if item.IsRead || item.IsFlagged {
+			t.Errorf("IsRead/IsFlagged = %v/%v, want false/false", item.IsRead, item.IsFlagged)
+		}
+	})
+
+	t.Run("falls back to Date header", func(t *testing.T) {
+		data := strings.SplitN(importSample, "\r\n", 2)[1]
+		item, err := parseImport("msg.eml", []byte(data))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		want := time.Date(2024, 1, 16, 7, 59, 0, 0, time.UTC)
+		if !item.ReceivedAt.Equal(want) {
+			t.Errorf("ReceivedAt = %v, want %v", item.ReceivedAt, want)
+		}
+	})
+
+	t.Run("reads mbox status headers", func(t *testing.T) {
+		data := "Status: RO\r\nX-Status: F\r\n" + importSample
+		item, err := parseImport("msg.eml", []byte(data))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		if !item.IsRead || !item.IsFlagged {
+			t.Errorf("IsRead/IsFlagged = %v/%v, want true/true", item.IsRead, item.IsFlagged)
+		}
+	})
+
+	t.Run("reads maildir flags", func(t *testing.T) {
+		item, err := parseImport("cur/1700000000.abc.host:2,FS", []byte(importSample))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		if !item.IsRead || !item.IsFlagged {
+			t.Errorf("IsRead/IsFlagged = %v/%v, want true/true", item.IsRead, item.IsFlagged)
+		}
+	})
+
+	t.Run("rejects invalid content", func(t *testing.T) {
+		tests := map[string][]byte{
+			"empty":      nil,
+			"no headers": []byte("just some text without headers"),
+			"too large":  make([]byte, MaxImportSize+1),
+		}
+		for name, data := range tests {
+			if _, err := parseImport("bad.eml", data); err == nil {
+				t.Errorf("parseImport(%s) should fail", name)
+			}
+		}
+	})
+}
+
+func TestParseImportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "msg.eml")
+	if err := os.WriteFile(path, []byte(importSample), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	item, err := ParseImportFile(path)
+	if err != nil {
+		t.Fatalf("ParseImportFile() error = %v", err)
+	}
+	if item.Path != path || item.Size != len(importSample) {
+		t.Errorf("item = %+v, want path %q and size %d", item, path, len(importSample))
+	}
+
+	if _, err := ParseImportFile(filepath.Join(t.TempDir(), "missing.eml")); err == nil {
+		t.Error("ParseImportFile() of a missing file should fail")
+	}
+}
+
+const importMultipart = "Status: RO\r\n" +
+	"Received: from mx.example.com by mail.example.net; Tue, 16 Jan 2024 08:00:05 +0000\r\n" +
+	"From: \"Doe, Jane\" <jane@example.com>\r\n" +
+	"To: Bob <bob@example.com>, carol@example.com\r\n" +
+	"Subject: Invoice\r\n" +
+	"Date: Tue, 16 Jan 2024 07:59:00 +0000\r\n" +
+	"Message-ID: <inv-1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Gr=FC=DFe\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+R3LDvMOfZTwvcD4=\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename*=utf-8''Rechnung%20M%C3%A4rz.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQ=\r\n" +
+	"--outer--\r\n"
+
+// extendedProperties maps the extended properties of a message by ID
+func extendedProperties(msg interface {
+	GetSingleValueExtendedProperties() []models.SingleValueLegacyExtendedPropertyable
+}) map[string]string {
+	props := map[string]string{}
+	for _, p := range msg.GetSingleValueExtendedProperties() {
+		props[safeString(p.GetId())] = safeString(p.GetValue())
+	}
+	return props
+}
+
+func TestNewImportMessage(t *testing.T) {
+	t.Run("created as a received message with its dates", func(t *testing.T) {
+		item, err := parseImport("msg.eml", []byte(importSample))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		msg, warnings, err := newImportMessage(item)
+		if err != nil {
+			t.Fatalf("newImportMessage() error = %v", err)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("warnings = %q", warnings)
+		}
+
+		props := extendedProperties(msg)
+		want := map[string]string{
+			propMessageFlags:     "0",
+			propDeliveryTime:     "2024-01-16T08:00:05Z",
+			propSubmitTime:       "2024-01-16T07:59:00Z",
+			propTransportHeaders: strings.SplitN(importSample, "\r\n\r\n", 2)[0] + "\r\n",
+		}
+		for id, value := range want {
+			if props[id] != value {
+				t.Errorf("property %s = %q, want %q", id, props[id], value)
+			}
+		}
+		if msg.GetIsRead() != nil || msg.GetIsDraft() != nil {
+			t.Error("read and draft state should only come from PR_MESSAGE_FLAGS")
+		}
+		if got := safeString(msg.GetBody().GetContent()); got != "See you there.\r\n" {
+			t.Errorf("body = %q", got)
+		}
+	})
+
+	t.Run("converts multipart content", func(t *testing.T) {
+		item, err := parseImport("msg.eml", []byte(importMultipart))
+		if err != nil {
+			t.Fatalf("parseImport() error = %v", err)
+		}
+		msg, warnings, err := newImportMessage(item)
+		if err != nil {
+			t.Fatalf("newImportMessage() error = %v", err)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("warnings = %q", warnings)
+		}
+
+		if got := extendedProperties(msg)[propMessageFlags]; got != "1" {
+			t.Errorf("PR_MESSAGE_FLAGS = %q, want 1 (read)", got)
+		}
+		if got := msg.GetBody(); got.GetContentType().String() != "html" || safeString(got.GetContent()) != "<p>Grüße</p>" {
+			t.Errorf("body = %s %q, want the HTML part", got.GetContentType(), safeString(got.GetContent()))
+		}
+		if got := recipientAddresses([]models.Recipientable{msg.GetFrom()}, true); got[0] != `"Doe, Jane" <jane@example.com>` {
+			t.Errorf("from = %q", got)
+		}
+		if got := recipientAddresses(msg.GetToRecipients(), true); len(got) != 2 || got[0] != "Bob <bob@example.com>" {
+			t.Errorf("to = %q", got)
+		}
+		if got := safeString(msg.GetInternetMessageId()); got != "<inv-1@example.com>" {
+			t.Errorf("internetMessageId = %q", got)
+		}
+
+		attachments := msg.GetAttachments()
+		if len(attachments) != 1 {
+			t.Fatalf("got %d attachments, want 1 (the plain-text alternative is not one)", len(attachments))
+		}
+		pdf := attachments[0].(*models.FileAttachment)
+		if safeString(pdf.GetName()) != "Rechnung März.pdf" || string(pdf.GetContentBytes()) != "%PDF-1.4" {
+			t.Errorf("attachment = %q %q", safeString(pdf.GetName()), pdf.GetContentBytes())
+		}
+	})
+}
+
+func TestParseMIMECharset(t *testing.T) {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=iso-8859-1"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+	content, err := parseMIME(header, strings.NewReader("Gr=FC=DFe\r\n"))
+	if err != nil {
+		t.Fatalf("parseMIME() error = %v", err)
+	}
+	if content.text != "Grüße\r\n" {
+		t.Errorf("text = %q, want UTF-8", content.text)
+	}
+
+	content, _ = parseMIME(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=x-unknown"}}, strings.NewReader("hi"))
+	if content.text != "hi" || len(content.warnings) != 1 {
+		t.Errorf("unknown charset: text %q, warnings %q", content.text, content.warnings)
+	}
+}
@@ -3,7 +3,6 @@ package mail
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
@@ -13,16 +12,31 @@ import (
 
 // Message represents an email message
 type Message struct {
-	ID              string    `json:"id"`
-	Subject         string    `json:"subject"`
-	From            string    `json:"from"`
-	To              []string  `json:"to"`
-	ReceivedAt      time.Time `json:"received_at"`
-	IsRead          bool      `json:"is_read"`
-	HasAttachments  bool      `json:"has_attachments"`
-	BodyPreview     string    `json:"body_preview,omitempty"`
-	Body            string    `json:"body,omitempty"`
-	BodyContentType string    `json:"body_content_type,omitempty"`
+	ID                string    `json:"id"`
+	Subject           string    `json:"subject"`
+	From              string    `json:"from"`
+	To                []string  `json:"to"`
+	Cc                []string  `json:"cc,omitempty"`
+	Bcc               []string  `json:"bcc,omitempty"`
+	ReceivedAt        time.Time `json:"received_at"`
+	IsRead            bool      `json:"is_read"`
+	HasAttachments    bool      `json:"has_attachments"`
+	BodyPreview       string    `json:"body_preview,omitempty"`
+	Body              string    `json:"body,omitempty"`
+	BodyContentType   string    `json:"body_content_type,omitempty"`
+	InternetMessageID string    `json:"internet_message_id,omitempty"`
+	ConversationID    string    `json:"conversation_id,omitempty"`
+	Importance        string    `json:"importance,omitempty"`
+	Flag              *Flag     `json:"flag,omitempty"`
+	Categories        []string  `json:"categories,omitempty"`
+	// InferenceClassification is "focused" or "other"
+	InferenceClassification string `json:"inference_classification,omitempty"`
+	// Headers holds the internet message headers when they were requested
+	Headers []Header `json:"headers,omitempty"`
+	// ReplyTo and the receipt requests are only set on drafts
+	ReplyTo                  []string `json:"reply_to,omitempty"`
+	ReadReceiptRequested     bool     `json:"read_receipt_requested,omitempty"`
+	DeliveryReceiptRequested bool     `json:"delivery_receipt_requested,omitempty"`
 }
 
 // ListOptions configures message listing
@@ -31,21 +45,103 @@ type ListOptions struct {
 	Skip       int32
 	Filter     string
 	OrderBy    string
+	Search     string
 	UnreadOnly bool
 	FolderID   string
+	// IncludeBody adds the full message body to each result
+	IncludeBody bool
+}
+
+// listSelect lists the message fields returned by list and search queries
+var listSelect = []string{
+	"id", "subject", "from", "toRecipients", "ccRecipients", "receivedDateTime",
+	"isRead", "hasAttachments", "bodyPreview", "internetMessageId", "conversationId",
+	"importance", "flag", "categories", "inferenceClassification",
 }
 
 // ListMessages retrieves messages from the user's mailbox
 func ListMessages(ctx context.Context, client *msgraph.GraphServiceClient, opts ListOptions) ([]Message, error) {
+	result, err := getMessagePage(ctx, client, opts, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	messages := make([]Message, 0)
+	for _, msg := range result.GetValue() {
+		messages = append(messages, convertListed(msg, opts))
+	}
+
+	return messages, nil
+}
+
+// ListAllMessages pages through every message matching opts. Top sets the
+// page size; limit caps the number of messages returned (0 means no limit).
+func ListAllMessages(ctx context.Context, client *msgraph.GraphServiceClient, opts ListOptions, limit int) ([]Message, error) {
+	return ListMatchingMessages(ctx, client, opts, nil, limit, 0)
+}
+
+// ListMatchingMessages pages through the messages for opts, keeping those
+// that pass match (all of them when match is nil), until limit are kept or
+// maxPages pages were read. A zero limit or maxPages means no limit.
+func ListMatchingMessages(ctx context.Context, client *msgraph.GraphServiceClient, opts ListOptions, match func(Message) bool, limit, maxPages int) ([]Message, error) {
+	messages := make([]Message, 0)
+	nextLink := ""
+	for page := 1; ; page++ {
+		result, err := getMessagePage(ctx, client, opts, nextLink)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		for _, msg := range result.GetValue() {
+			m := convertListed(msg, opts)
+			if match != nil && !match(m) {
+				continue
+			}
+			messages = append(messages, m)
+			if limit > 0 && len(messages) >= limit {
+				return messages, nil
+			}
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" || (maxPages > 0 && page >= maxPages) {
+			return messages, nil
+		}
+		nextLink = *next
+	}
+}
+
+// convertListed converts a listed message, including the body when opts
+// asked for it
+func convertListed(msg models.Messageable, opts ListOptions) Message {
+	m := convertMessage(msg)
+	if opts.IncludeBody {
+		convertBody(msg, &m)
+	}
+	return m
+}
+
+// getMessagePage fetches the first page of messages for opts, or the page
+// at nextLink when it is set
+func getMessagePage(ctx context.Context, client *msgraph.GraphServiceClient, opts ListOptions, nextLink string) (models.MessageCollectionResponseable, error) {
+	if nextLink != "" {
+		return client.Me().Messages().WithUrl(nextLink).Get(ctx, nil)
+	}
+
 	// Build query parameters
 	top := opts.Top
 	if top == 0 {
 		top = 25
 	}
 
-	orderBy := opts.OrderBy
-	if orderBy == "" {
-		orderBy = "receivedDateTime desc"
+	// Graph does not allow $orderby together with $search
+	var orderBy []string
+	if opts.Search == "" {
+		if opts.OrderBy != "" {
+			orderBy = []string{opts.OrderBy}
+		} else {
+			orderBy = []string{"receivedDateTime desc"}
+		}
 	}
 
 	filter := opts.Filter
@@ -57,48 +153,59 @@ func ListMessages(ctx context.Context, client *msgraph.GraphServiceClient, opts
 		}
 	}
 
-	requestConfig := &users.ItemMessagesRequestBuilderGetRequestConfiguration{
-		QueryParameters: &users.ItemMessagesRequestBuilderGetQueryParameters{
-			Top:     &top,
-			Orderby: []string{orderBy},
-			Select:  []string{"id", "subject", "from", "toRecipients", "receivedDateTime", "isRead", "hasAttachments", "bodyPreview"},
-		},
-	}
-
+	var filterParam, searchParam *string
 	if filter != "" {
-		requestConfig.QueryParameters.Filter = &filter
+		filterParam = &filter
+	}
+	if opts.Search != "" {
+		searchParam = &opts.Search
 	}
 
+	var skipParam *int32
 	if opts.Skip > 0 {
-		requestConfig.QueryParameters.Skip = &opts.Skip
+		skipParam = &opts.Skip
 	}
 
-	var result models.MessageCollectionResponseable
-	var err error
-
-	if opts.FolderID != "" {
-		result, err = client.Me().MailFolders().ByMailFolderId(opts.FolderID).Messages().Get(ctx, nil)
-	} else {
-		result, err = client.Me().Messages().Get(ctx, requestConfig)
+	fields := listSelect
+	if opts.IncludeBody {
+		fields = append(append([]string{}, listSelect...), "body")
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to list messages: %w", err)
+	if opts.FolderID != "" {
+		requestConfig := &users.ItemMailFoldersItemMessagesRequestBuilderGetRequestConfiguration{
+			QueryParameters: &users.ItemMailFoldersItemMessagesRequestBuilderGetQueryParameters{
+				Top:     &top,
+				Skip:    skipParam,
+				Orderby: orderBy,
+				Filter:  filterParam,
+				Search:  searchParam,
+				Select:  fields,
+			},
+		}
+		return client.Me().MailFolders().ByMailFolderId(opts.FolderID).Messages().Get(ctx, requestConfig)
 	}
 
-	messages := make([]Message, 0)
-	for _, msg := range result.GetValue() {
-		messages = append(messages, convertMessage(msg))
+	requestConfig := &users.ItemMessagesRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesRequestBuilderGetQueryParameters{
+			Top:     &top,
+			Skip:    skipParam,
+			Orderby: orderBy,
+			Filter:  filterParam,
+			Search:  searchParam,
+			Select:  fields,
+		},
 	}
-
-	return messages, nil
+	return client.Me().Messages().Get(ctx, requestConfig)
 }
 
 // GetMessage retrieves a single message by ID
 func GetMessage(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) (*Message, error) {
 	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
 		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
-			Select: []string{"id", "subject", "from", "toRecipients", "receivedDateTime", "isRead", "hasAttachments", "body"},
+			Select: []string{
+				"id", "subject", "from", "toRecipients", "ccRecipients", "receivedDateTime", "isRead",
+				"hasAttachments", "body", "conversationId", "importance", "flag", "categories", "inferenceClassification",
+			},
 		},
 	}
 
@@ -110,36 +217,30 @@ func GetMessage(ctx context.Context, client *msgraph.GraphServiceClient, message
 	message := convertMessage(msg)
 
 	// Get full body
-	if body := msg.GetBody(); body != nil {
-		if content := body.GetContent(); content != nil {
-			message.Body = *content
-		}
-		if contentType := body.GetContentType(); contentType != nil {
-			message.BodyContentType = contentType.String()
-		}
-	}
+	convertBody(msg, &message)
 
 	return &message, nil
 }
 
-// SearchMessages searches messages with a query
-func SearchMessages(ctx context.Context, client *msgraph.GraphServiceClient, query string, top int32) ([]Message, error) {
-	if top == 0 {
-		top = 25
+// GetMessageMIME retrieves the raw MIME content of a message
+func GetMessageMIME(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) ([]byte, error) {
+	content, err := client.Me().Messages().ByMessageId(messageID).Content().Get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message content: %w", err)
 	}
 
-	// Use $search parameter for full-text search
-	search := fmt.Sprintf("\"%s\"", query)
+	return content, nil
+}
 
-	requestConfig := &users.ItemMessagesRequestBuilderGetRequestConfiguration{
-		QueryParameters: &users.ItemMessagesRequestBuilderGetQueryParameters{
-			Top:    &top,
-			Search: &search,
-			Select: []string{"id", "subject", "from", "toRecipients", "receivedDateTime", "isRead", "hasAttachments", "bodyPreview"},
-		},
+// SearchMessages searches messages with a query
+func SearchMessages(ctx context.Context, client *msgraph.GraphServiceClient, query string, top int32) ([]Message, error) {
+	// Use $search parameter for full-text search
+	opts := ListOptions{
+		Top:    top,
+		Search: fmt.Sprintf("\"%s\"", query),
 	}
 
-	result, err := client.Me().Messages().Get(ctx, requestConfig)
+	result, err := getMessagePage(ctx, client, opts, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to search messages: %w", err)
 	}
@@ -167,28 +268,70 @@ func convertMessage(msg models.Messageable) Message {
 			name := safeString(addr.GetName())
 			email := safeString(addr.GetAddress())
 			if name != "" && name != email {
-				m.From = fmt.Sprintf("%s <%s>", name, email)
+				m.From = formatAddress(name, email)
 			} else {
 				m.From = email
 			}
 		}
 	}
 
-	if recipients := msg.GetToRecipients(); recipients != nil {
-		for _, r := range recipients {
-			if addr := r.GetEmailAddress(); addr != nil {
-				m.To = append(m.To, safeString(addr.GetAddress()))
-			}
-		}
-	}
+	m.To = recipientAddresses(msg.GetToRecipients(), false)
+	m.Cc = recipientAddresses(msg.GetCcRecipients(), false)
+	m.Bcc = recipientAddresses(msg.GetBccRecipients(), false)
+	m.ReplyTo = recipientAddresses(msg.GetReplyTo(), false)
 
 	if received := msg.GetReceivedDateTime(); received != nil {
 		m.ReceivedAt = *received
 	}
 
+	m.InternetMessageID = safeString(msg.GetInternetMessageId())
+	m.ConversationID = safeString(msg.GetConversationId())
+
+	if importance := msg.GetImportance(); importance != nil {
+		m.Importance = importance.String()
+	}
+	m.Flag = convertFlag(msg.GetFlag())
+	m.Categories = msg.GetCategories()
+	if classification := msg.GetInferenceClassification(); classification != nil {
+		m.InferenceClassification = classification.String()
+	}
+	m.ReadReceiptRequested = safeBool(msg.GetIsReadReceiptRequested())
+	m.DeliveryReceiptRequested = safeBool(msg.GetIsDeliveryReceiptRequested())
+
 	return m
 }
 
+// recipientAddresses lists the addresses of Graph recipients. With named
+// set, display names are kept in RFC 5322 form.
+func recipientAddresses(recipients []models.Recipientable, named bool) []string {
+	var addrs []string
+	for _, r := range recipients {
+		addr := r.GetEmailAddress()
+		if addr == nil {
+			continue
+		}
+		email := safeString(addr.GetAddress())
+		name := safeString(addr.GetName())
+		if named && name != "" && name != email {
+			email = formatAddress(name, email)
+		}
+		addrs = append(addrs, email)
+	}
+	return addrs
+}
+
+// convertBody copies the body content and type of a Graph API message
+func convertBody(msg models.Messageable, m *Message) {
+	if body := msg.GetBody(); body != nil {
+		if content := body.GetContent(); content != nil {
+			m.Body = *content
+		}
+		if contentType := body.GetContentType(); contentType != nil {
+			m.BodyContentType = contentType.String()
+		}
+	}
+}
+
 func safeString(s *string) string {
 	if s == nil {
 		return ""
@@ -237,52 +380,3 @@ func (m *Message) FormatDate() string {
 	}
 	return m.ReceivedAt.Format("2006-01-02")
 }
-
-// StripHTML removes HTML tags for plain text display
-func StripHTML(html string) string {
-	// Simple HTML stripping - replace tags and decode common entities
-	s := html
-	s = strings.ReplaceAll(s, "<br>", "\n")
-	s = strings.ReplaceAll(s, "<br/>", "\n")
-	s = strings.ReplaceAll(s, "<br />", "\n")
-	s = strings.ReplaceAll(s, "</p>", "\n\n")
-	s = strings.ReplaceAll(s, "</div>", "\n")
-
-	// Remove remaining tags
-	var result strings.Builder
-	inTag := false
-	for _, r := range s {
-		if r == '<' {
-			inTag = true
-			continue
-		}
-		if r == '>' {
-			inTag = false
-			continue
-		}
-		if !inTag {
-			result.WriteRune(r)
-		}
-	}
-
-	// Decode common HTML entities
-	s = result.String()
-	s = strings.ReplaceAll(s, "&nbsp;", " ")
-	s = strings.ReplaceAll(s, "&amp;", "&")
-	s = strings.ReplaceAll(s, "&lt;", "<")
-	s = strings.ReplaceAll(s, "&gt;", ">")
-	s = strings.ReplaceAll(s, "&quot;", "\"")
-	s = strings.ReplaceAll(s, "&#39;", "'")
-
-	// Clean up extra whitespace
-	lines := strings.Split(s, "\n")
-	var cleaned []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleaned = append(cleaned, line)
-		}
-	}
-
-	return strings.Join(cleaned, "\n")
-}
@@ -72,59 +72,6 @@ func TestMessage(t *testing.T) {
 	})
 }
 
-func TestStripHTML(t *testing.T) {
-	tests := []struct {
-		name  string
-		input string
-		want  string
-	}{
-		{
-			name:  "removes simple tags",
-			input: "<p>Hello</p>",
-			want:  "Hello",
-		},
-		{
-			name:  "converts br to newline",
-			input: "Line1<br>Line2<br/>Line3<br />Line4",
-			want:  "Line1\nLine2\nLine3\nLine4",
-		},
-		{
-			name:  "converts p end tag to double newline",
-			input: "<p>Para1</p><p>Para2</p>",
-			want:  "Para1\nPara2",
-		},
-		{
-			name:  "decodes HTML entities",
-			input: "&amp; &lt; &gt; &quot; &#39; &nbsp;",
-			want:  "& < > \" '",
-		},
-		{
-			name:  "handles nested tags",
-			input: "<div><span><b>Bold</b></span></div>",
-			want:  "Bold",
-		},
-		{
-			name:  "handles empty input",
-			input: "",
-			want:  "",
-		},
-		{
-			name:  "preserves plain text",
-			input: "Just plain text",
-			want:  "Just plain text",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := StripHTML(tt.input)
-			if got != tt.want {
-				t.Errorf("StripHTML(%q) = %q, want %q", tt.input, got, tt.want)
-			}
-		})
-	}
-}
-
 func TestSafeString(t *testing.T) {
 	t.Run("returns empty string for nil", func(t *testing.T) {
 		if got := safeString(nil); got != "" {
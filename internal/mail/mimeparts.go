@@ -0,0 +1,153 @@
+package mail
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxMIMEDepth bounds nested multipart parts
+const maxMIMEDepth = 10
+
+// mimeContent is the body and attachments of a MIME message
+type mimeContent struct {
+	text        string
+	html        string
+	attachments []mimeAttachment
+	warnings    []string
+}
+
+// mimeAttachment is a decoded attachment or inline part
+type mimeAttachment struct {
+	name        string
+	contentType string
+	contentID   string
+	inline      bool
+	data        []byte
+}
+
+// parseMIME walks a MIME entity. The first text/plain and text/html parts
+// that are not attachments become the body; every other part is kept as an
+// attachment.
+func parseMIME(header textproto.MIMEHeader, body io.Reader) (*mimeContent, error) {
+	c := &mimeContent{}
+	if err := c.addPart(header, body, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *mimeContent) addPart(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045: a missing or broken Content-Type means plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" && depth < maxMIMEDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			if err := c.addPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode MIME part: %w", err)
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dispParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+
+	if disposition != "attachment" && name == "" {
+		switch {
+		case mediaType == "text/html" && c.html == "":
+			c.html = c.decodeText(params["charset"], data)
+			return nil
+		case mediaType == "text/plain" && c.text == "":
+			c.text = c.decodeText(params["charset"], data)
+			return nil
+		}
+	}
+
+	if name == "" {
+		name = "attachment" + attachmentExtension(mediaType)
+	}
+	contentID := strings.Trim(header.Get("Content-Id"), "<> ")
+	c.attachments = append(c.attachments, mimeAttachment{
+		name:        name,
+		contentType: mediaType,
+		contentID:   contentID,
+		inline:      contentID != "" && disposition != "attachment",
+		data:        data,
+	})
+	return nil
+}
+
+// decodeText converts a text part to UTF-8
+func (c *mimeContent) decodeText(label string, data []byte) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || label == "utf-8" || label == "us-ascii" {
+		return string(data)
+	}
+
+	enc, _ := charset.Lookup(label)
+	if enc == nil {
+		c.warnings = append(c.warnings, fmt.Sprintf("unknown charset %q, text kept as is", label))
+		return string(data)
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		c.warnings = append(c.warnings, fmt.Sprintf("could not decode %s text: %v", label, err))
+		return string(data)
+	}
+	return string(text)
+}
+
+// transferDecoder undoes a Content-Transfer-Encoding
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// attachmentExtension picks a file extension for an unnamed part
+func attachmentExtension(mediaType string) string {
+	switch mediaType {
+	case "message/rfc822":
+		return ".eml"
+	case "text/plain":
+		return ".txt"
+	case "text/html":
+		return ".html"
+	case "text/calendar":
+		return ".ics"
+	}
+	return ""
+}
@@ -0,0 +1,284 @@
+package mail
+
+import (
+	"fmt"
+	netmail "net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Criteria describes a structured message query. Zero values are ignored.
+type Criteria struct {
+	// Text is free-text or raw KQL passed through to $search
+	Text           string
+	From           string
+	To             string
+	Subject        string
+	Since          time.Time
+	Before         time.Time
+	HasAttachments *bool
+	Importance     string
+	Flagged        *bool
+	Category       string
+	Unread         bool
+	// Classification is the Focused Inbox tab, "focused" or "other"
+	Classification string
+}
+
+// Query is a compiled message query ready to send to Graph
+type Query struct {
+	// Search is the $search value, including the surrounding quotes
+	Search string
+	// Filter is the $filter expression
+	Filter string
+	// OrderBy is the $orderby expression; always empty when Search is set
+	OrderBy string
+	// UnreadOnly asks the caller to drop read messages client-side, since
+	// read state cannot be expressed in KQL
+	UnreadOnly bool
+	// Classification asks the caller to drop messages from the other
+	// Focused Inbox tab client-side, for the same reason
+	Classification string
+}
+
+// filterFloor is a receivedDateTime clause that matches every message. Graph
+// rejects a $filter combined with $orderby=receivedDateTime unless the
+// filter starts with a receivedDateTime clause, so it is prepended when the
+// query has no date bound of its own.
+const filterFloor = "receivedDateTime ge 1900-01-01T00:00:00Z"
+
+// defaultOrderBy sorts filtered listings newest first
+const defaultOrderBy = "receivedDateTime desc"
+
+// Compile turns the criteria into a Graph query. Graph cannot combine
+// $search with $filter or $orderby, so the compiler picks one mode:
+// $filter (with ordering) when every criterion can be expressed in OData,
+// otherwise $search with every criterion translated to KQL.
+func (c Criteria) Compile() (*Query, error) {
+	if c.Importance != "" {
+		importance, err := ParseImportance(c.Importance)
+		if err != nil {
+			return nil, err
+		}
+		c.Importance = importance
+	}
+	if c.Classification != "" {
+		classification, err := ParseClassification(c.Classification)
+		if err != nil {
+			return nil, err
+		}
+		c.Classification = classification
+	}
+
+	if !c.Since.IsZero() && !c.Before.IsZero() && !c.Since.Before(c.Before) {
+		return nil, fmt.Errorf("--since must be earlier than --before")
+	}
+
+	if c.needsSearch() {
+		return c.compileSearch()
+	}
+	return c.compileFilter(), nil
+}
+
+// needsSearch reports whether any criterion can only be expressed in KQL
+func (c Criteria) needsSearch() bool {
+	if c.Text != "" || c.To != "" {
+		return true
+	}
+	// $filter can only match the sender's exact address
+	if c.From != "" && !isEmailAddress(c.From) {
+		return true
+	}
+	return false
+}
+
+// compileFilter builds an OData $filter query
+func (c Criteria) compileFilter() *Query {
+	var clauses []string
+
+	if !c.Since.IsZero() {
+		clauses = append(clauses, "receivedDateTime ge "+formatODataTime(c.Since))
+	}
+	if !c.Before.IsZero() {
+		clauses = append(clauses, "receivedDateTime lt "+formatODataTime(c.Before))
+	}
+	if c.From != "" {
+		clauses = append(clauses, fmt.Sprintf("from/emailAddress/address eq %s", quoteOData(c.From)))
+	}
+	if c.Subject != "" {
+		clauses = append(clauses, fmt.Sprintf("contains(subject, %s)", quoteOData(c.Subject)))
+	}
+	if c.HasAttachments != nil {
+		clauses = append(clauses, fmt.Sprintf("hasAttachments eq %t", *c.HasAttachments))
+	}
+	if c.Importance != "" {
+		clauses = append(clauses, fmt.Sprintf("importance eq '%s'", c.Importance))
+	}
+	if c.Flagged != nil {
+		if *c.Flagged {
+			clauses = append(clauses, "flag/flagStatus eq 'flagged'")
+		} else {
+			clauses = append(clauses, "flag/flagStatus ne 'flagged'")
+		}
+	}
+	if c.Category != "" {
+		clauses = append(clauses, fmt.Sprintf("categories/any(c:c eq %s)", quoteOData(c.Category)))
+	}
+	if c.Unread {
+		clauses = append(clauses, "isRead eq false")
+	}
+	if c.Classification != "" {
+		clauses = append(clauses, fmt.Sprintf("inferenceClassification eq '%s'", c.Classification))
+	}
+
+	if len(clauses) > 0 && c.Since.IsZero() && c.Before.IsZero() {
+		clauses = append([]string{filterFloor}, clauses...)
+	}
+
+	return &Query{
+		Filter:  strings.Join(clauses, " and "),
+		OrderBy: defaultOrderBy,
+	}
+}
+
+// compileSearch builds a KQL $search query
+func (c Criteria) compileSearch() (*Query, error) {
+	if c.Flagged != nil {
+		return nil, fmt.Errorf("--flagged cannot be combined with free-text, --to, or sender name searches")
+	}
+
+	var terms []string
+
+	if c.Text != "" {
+		terms = append(terms, c.Text)
+	}
+	if c.From != "" {
+		terms = append(terms, "from:"+quoteKQL(c.From))
+	}
+	if c.To != "" {
+		terms = append(terms, "to:"+quoteKQL(c.To))
+	}
+	if c.Subject != "" {
+		terms = append(terms, "subject:"+quoteKQL(c.Subject))
+	}
+	if !c.Since.IsZero() {
+		terms = append(terms, "received>="+c.Since.Format("2006-01-02"))
+	}
+	if !c.Before.IsZero() {
+		terms = append(terms, "received<"+c.Before.Format("2006-01-02"))
+	}
+	if c.HasAttachments != nil {
+		terms = append(terms, fmt.Sprintf("hasattachments:%t", *c.HasAttachments))
+	}
+	if c.Importance != "" {
+		terms = append(terms, "importance:"+c.Importance)
+	}
+	if c.Category != "" {
+		terms = append(terms, "category:"+quoteKQL(c.Category))
+	}
+
+	search := strings.Join(terms, " AND ")
+
+	return &Query{
+		Search:         `"` + strings.ReplaceAll(search, `"`, `\"`) + `"`,
+		UnreadOnly:     c.Unread,
+		Classification: c.Classification,
+	}, nil
+}
+
+// Apply copies the compiled query into list options
+func (q *Query) Apply(opts *ListOptions) {
+	opts.Search = q.Search
+	opts.Filter = q.Filter
+	opts.OrderBy = q.OrderBy
+}
+
+// ClientSide reports whether part of the query is applied by Match, so the
+// caller must read past the first page to fill a page of results
+func (q *Query) ClientSide() bool {
+	return q.UnreadOnly || q.Classification != ""
+}
+
+// Match reports whether a message passes the client-side part of the query
+func (q *Query) Match(m Message) bool {
+	if q.UnreadOnly && m.IsRead {
+		return false
+	}
+	if q.Classification != "" && m.InferenceClassification != q.Classification {
+		return false
+	}
+	return true
+}
+
+// quoteOData quotes a string literal for $filter
+func quoteOData(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteKQL quotes a KQL property value when it contains spaces
+func quoteKQL(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return `"` + strings.ReplaceAll(s, `"`, "") + `"`
+	}
+	return s
+}
+
+// formatODataTime formats a time as a $filter DateTimeOffset literal
+func formatODataTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// isEmailAddress reports whether s is a bare email address
+func isEmailAddress(s string) bool {
+	addr, err := netmail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+var relativeDate = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// ParseDateArg parses a date flag value. It accepts RFC 3339 times,
+// YYYY-MM-DD dates (midnight local time), "today", "yesterday", and
+// relative ages such as 24h, 7d, 2w, 3m, and 1y counted back from now.
+func ParseDateArg(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "m":
+			return now.AddDate(0, -n, 0), nil
+		case "y":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", strings.ToUpper(s), now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date: %s (use YYYY-MM-DD, RFC 3339, or a relative age like 7d)", s)
+}
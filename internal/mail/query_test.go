@@ -0,0 +1,289 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestCriteriaCompileFilter(t *testing.T) {
+	since := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+		want     string
+	}{
+		{
+			name:     "empty criteria",
+			criteria: Criteria{},
+			want:     "",
+		},
+		{
+			name:     "sender address",
+			criteria: Criteria{From: "jane@example.com"},
+			want:     filterFloor + " and from/emailAddress/address eq 'jane@example.com'",
+		},
+		{
+			name:     "date range leads the filter",
+			criteria: Criteria{Since: since, Before: before, HasAttachments: boolPtr(true)},
+			want:     "receivedDateTime ge 2024-01-15T00:00:00Z and receivedDateTime lt 2024-02-01T00:00:00Z and hasAttachments eq true",
+		},
+		{
+			name:     "focused inbox tab",
+			criteria: Criteria{Classification: "Focused", Unread: true},
+			want:     filterFloor + " and isRead eq false and inferenceClassification eq 'focused'",
+		},
+		{
+			name:     "subject is escaped",
+			criteria: Criteria{Subject: "Bob's report"},
+			want:     filterFloor + " and contains(subject, 'Bob''s report')",
+		},
+		{
+			name:     "importance is normalized",
+			criteria: Criteria{Importance: "HIGH"},
+			want:     filterFloor + " and importance eq 'high'",
+		},
+		{
+			name:     "flagged",
+			criteria: Criteria{Flagged: boolPtr(true)},
+			want:     filterFloor + " and flag/flagStatus eq 'flagged'",
+		},
+		{
+			name:     "not flagged",
+			criteria: Criteria{Flagged: boolPtr(false)},
+			want:     filterFloor + " and flag/flagStatus ne 'flagged'",
+		},
+		{
+			name:     "category",
+			criteria: Criteria{Category: "Red category"},
+			want:     filterFloor + " and categories/any(c:c eq 'Red category')",
+		},
+		{
+			name:     "unread",
+			criteria: Criteria{Unread: true, Since: since},
+			want:     "receivedDateTime ge 2024-01-15T00:00:00Z and isRead eq false",
+		},
+		{
+			name:     "without attachments",
+			criteria: Criteria{HasAttachments: boolPtr(false)},
+			want:     filterFloor + " and hasAttachments eq false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.criteria.Compile()
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if q.Search != "" {
+				t.Errorf("Search = %q, want empty in filter mode", q.Search)
+			}
+			if q.Filter != tt.want {
+				t.Errorf("Filter =\n  %q\nwant\n  %q", q.Filter, tt.want)
+			}
+			if q.OrderBy != defaultOrderBy {
+				t.Errorf("OrderBy = %q, want %q", q.OrderBy, defaultOrderBy)
+			}
+		})
+	}
+}
+
+func TestCriteriaCompileSearch(t *testing.T) {
+	since := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+		want     string
+	}{
+		{
+			name:     "free text",
+			criteria: Criteria{Text: "quarterly report"},
+			want:     `"quarterly report"`,
+		},
+		{
+			name:     "recipient forces search",
+			criteria: Criteria{To: "team@example.com"},
+			want:     `"to:team@example.com"`,
+		},
+		{
+			name:     "sender name forces search",
+			criteria: Criteria{From: "Jane Doe"},
+			want:     `"from:\"Jane Doe\""`,
+		},
+		{
+			name: "all criteria translate to KQL",
+			criteria: Criteria{
+				Text:           "budget",
+				From:           "jane@example.com",
+				To:             "bob",
+				Subject:        "Q1 plan",
+				Since:          since,
+				Before:         since.AddDate(0, 1, 0),
+				HasAttachments: boolPtr(true),
+				Importance:     "high",
+				Category:       "Finance",
+			},
+			want: `"budget AND from:jane@example.com AND to:bob AND subject:\"Q1 plan\" AND received>=2024-01-15 AND received<2024-02-15 AND hasattachments:true AND importance:high AND category:Finance"`,
+		},
+		{
+			name:     "quotes in free text are escaped",
+			criteria: Criteria{Text: `"exact phrase"`},
+			want:     `"\"exact phrase\""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.criteria.Compile()
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if q.Search != tt.want {
+				t.Errorf("Search =\n  %s\nwant\n  %s", q.Search, tt.want)
+			}
+			if q.Filter != "" || q.OrderBy != "" {
+				t.Errorf("Filter/OrderBy = %q/%q, want empty in search mode", q.Filter, q.OrderBy)
+			}
+		})
+	}
+}
+
+func TestCriteriaCompileUnreadInSearchMode(t *testing.T) {
+	q, err := Criteria{Text: "invoice", Unread: true}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if strings.Contains(q.Search, "isread") {
+		t.Errorf("Search = %q, read state is not valid KQL", q.Search)
+	}
+	if !q.UnreadOnly {
+		t.Error("UnreadOnly should be set so the caller filters client-side")
+	}
+	if !q.ClientSide() {
+		t.Error("ClientSide() should be true so the caller pages past the first page")
+	}
+	if q.Match(Message{IsRead: true}) {
+		t.Error("Match() should reject read messages")
+	}
+	if !q.Match(Message{IsRead: false}) {
+		t.Error("Match() should accept unread messages")
+	}
+}
+
+func TestCriteriaCompileClassificationInSearchMode(t *testing.T) {
+	q, err := Criteria{Text: "invoice", Classification: ClassificationOther}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if q.Classification != ClassificationOther {
+		t.Errorf("Classification = %q, want other so the caller filters client-side", q.Classification)
+	}
+	if q.Match(Message{InferenceClassification: ClassificationFocused}) {
+		t.Error("Match() should reject focused messages")
+	}
+	if !q.Match(Message{InferenceClassification: ClassificationOther}) {
+		t.Error("Match() should accept other messages")
+	}
+}
+
+func TestQueryClientSideFilterMode(t *testing.T) {
+	q, err := Criteria{From: "jane@example.com", Unread: true, Classification: ClassificationFocused}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if q.ClientSide() {
+		t.Errorf("ClientSide() = true, filter mode applies everything server-side (filter %q)", q.Filter)
+	}
+}
+
+func TestCriteriaCompileErrors(t *testing.T) {
+	since := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		criteria Criteria
+	}{
+		{"invalid importance", Criteria{Importance: "urgent"}},
+		{"inverted date range", Criteria{Since: since, Before: since.AddDate(0, 0, -1)}},
+		{"flagged in search mode", Criteria{Text: "x", Flagged: boolPtr(true)}},
+		{"invalid classification", Criteria{Classification: "junk"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.criteria.Compile(); err == nil {
+				t.Error("Compile() should fail")
+			}
+		})
+	}
+}
+
+func TestQueryApply(t *testing.T) {
+	q := &Query{Filter: "isRead eq false", OrderBy: defaultOrderBy}
+	opts := ListOptions{Top: 10, FolderID: "inbox"}
+	q.Apply(&opts)
+
+	if opts.Filter != q.Filter || opts.OrderBy != q.OrderBy || opts.Search != "" {
+		t.Errorf("Apply() opts = %+v", opts)
+	}
+	if opts.Top != 10 || opts.FolderID != "inbox" {
+		t.Error("Apply() should keep unrelated options")
+	}
+}
+
+func TestParseDateArg(t *testing.T) {
+	now := time.Date(2024, 3, 20, 15, 45, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"today", time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.Date(2024, 3, 19, 0, 0, 0, 0, time.UTC)},
+		{"24h", time.Date(2024, 3, 19, 15, 45, 0, 0, time.UTC)},
+		{"7d", time.Date(2024, 3, 13, 15, 45, 0, 0, time.UTC)},
+		{"2w", time.Date(2024, 3, 6, 15, 45, 0, 0, time.UTC)},
+		{"3m", time.Date(2023, 12, 20, 15, 45, 0, 0, time.UTC)},
+		{"1y", time.Date(2023, 3, 20, 15, 45, 0, 0, time.UTC)},
+		{"2024-01-15", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"2024-01-15T09:30:00", time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)},
+		{"2024-01-15T09:30:00Z", time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDateArg(tt.input, now)
+		if err != nil {
+			t.Errorf("ParseDateArg(%q) error = %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDateArg(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"soon", "7x", "2024-13-01", "d7"} {
+		if _, err := ParseDateArg(bad, now); err == nil {
+			t.Errorf("ParseDateArg(%q) should fail", bad)
+		}
+	}
+}
+
+func TestIsEmailAddress(t *testing.T) {
+	if !isEmailAddress("jane@example.com") {
+		t.Error("isEmailAddress(jane@example.com) = false, want true")
+	}
+	for _, s := range []string{"Jane Doe", "Jane <jane@example.com>", "jane"} {
+		if isEmailAddress(s) {
+			t.Errorf("isEmailAddress(%q) = true, want false", s)
+		}
+	}
+}
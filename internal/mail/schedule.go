@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// deferredSendTime is PR_DEFERRED_SEND_TIME. Exchange holds a sent message
+// carrying it in the Outbox until that time.
+const deferredSendTime = "SystemTime 0x3FEF"
+
+// expandDeferredSendTime asks Graph to include the deferred send time
+var expandDeferredSendTime = []string{
+	fmt.Sprintf("singleValueExtendedProperties($filter=id eq '%s')", deferredSendTime),
+}
+
+// ScheduledMessage is a sent message waiting in the Outbox
+type ScheduledMessage struct {
+	ID      string    `json:"id"`
+	Subject string    `json:"subject"`
+	To      []string  `json:"to"`
+	SendAt  time.Time `json:"send_at"`
+}
+
+// ScheduleMessage sends a message that Exchange delivers at the given time.
+// The message is created as a draft carrying the deferred send time and
+// then sent, so the server holds it and the client need not stay running.
+func ScheduleMessage(ctx context.Context, client *msgraph.GraphServiceClient, opts SendOptions, at time.Time) error {
+	if !at.After(time.Now()) {
+		return fmt.Errorf("send time %s is in the past", at.Format(time.RFC3339))
+	}
+
+	prop := models.NewSingleValueLegacyExtendedProperty()
+	id := deferredSendTime
+	value := at.UTC().Format(time.RFC3339)
+	prop.SetId(&id)
+	prop.SetValue(&value)
+
+	draft, err := createDraft(ctx, client, opts, prop)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled message: %w", err)
+	}
+
+	if err := client.Me().Messages().ByMessageId(safeString(draft.GetId())).Send().Post(ctx, nil); err != nil {
+		return fmt.Errorf("failed to schedule message (it was left in Drafts): %w", err)
+	}
+	return nil
+}
+
+// ListScheduled lists the messages in the Outbox that have a deferred send
+// time, soonest first
+func ListScheduled(ctx context.Context, client *msgraph.GraphServiceClient) ([]ScheduledMessage, error) {
+	top := int32(100)
+	requestConfig := &users.ItemMailFoldersItemMessagesRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailFoldersItemMessagesRequestBuilderGetQueryParameters{
+			Top:    &top,
+			Select: []string{"id", "subject", "toRecipients"},
+			Expand: expandDeferredSendTime,
+		},
+	}
+
+	builder := client.Me().MailFolders().ByMailFolderId("outbox").Messages()
+	result, err := builder.Get(ctx, requestConfig)
+
+	scheduled := make([]ScheduledMessage, 0)
+	for {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scheduled messages: %w", err)
+		}
+
+		for _, msg := range result.GetValue() {
+			if s, ok := convertScheduled(msg); ok {
+				scheduled = append(scheduled, s)
+			}
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			break
+		}
+		result, err = builder.WithUrl(*next).Get(ctx, nil)
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		return scheduled[i].SendAt.Before(scheduled[j].SendAt)
+	})
+	return scheduled, nil
+}
+
+// CancelScheduled stops a scheduled message from going out by moving it
+// from the Outbox back to Drafts
+func CancelScheduled(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) error {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "subject", "toRecipients", "parentFolderId"},
+			Expand: expandDeferredSendTime,
+		},
+	}
+	msg, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	outbox, err := client.Me().MailFolders().ByMailFolderId("outbox").Get(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get Outbox: %w", err)
+	}
+
+	if _, ok := convertScheduled(msg); !ok || safeString(msg.GetParentFolderId()) != safeString(outbox.GetId()) {
+		return fmt.Errorf("message %s is not a scheduled message in the Outbox", messageID)
+	}
+
+	return MoveMessage(ctx, client, messageID, "drafts")
+}
+
+// convertScheduled converts a message with a deferred send time, reporting
+// false when it has none
+func convertScheduled(msg models.Messageable) (ScheduledMessage, bool) {
+	var sendAt time.Time
+	for _, prop := range msg.GetSingleValueExtendedProperties() {
+		// Graph may return the ID in lower case
+		if !strings.EqualFold(safeString(prop.GetId()), deferredSendTime) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, safeString(prop.GetValue()))
+		if err != nil {
+			return ScheduledMessage{}, false
+		}
+		sendAt = t
+	}
+	if sendAt.IsZero() {
+		return ScheduledMessage{}, false
+	}
+
+	m := convertMessage(msg)
+	return ScheduledMessage{
+		ID:      m.ID,
+		Subject: m.Subject,
+		To:      m.To,
+		SendAt:  sendAt,
+	}, true
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestConvertScheduled(t *testing.T) {
+	newMsg := func(propID, value string) models.Messageable {
+		msg, _ := newMessage(SendOptions{To: []string{"a@example.com"}, Subject: "Report"})
+		id := "AAMk1"
+		msg.SetId(&id)
+		if propID != "" {
+			prop := models.NewSingleValueLegacyExtendedProperty()
+			prop.SetId(&propID)
+			prop.SetValue(&value)
+			msg.SetSingleValueExtendedProperties([]models.SingleValueLegacyExtendedPropertyable{prop})
+		}
+		return msg
+	}
+
+	tests := []struct {
+		name   string
+		msg    models.Messageable
+		want   ScheduledMessage
+		wantOK bool
+	}{
+		{
+			name: "deferred",
+			msg:  newMsg("SystemTime 0x3fef", "2026-10-20T07:00:00Z"),
+			want: ScheduledMessage{
+				ID: "AAMk1", Subject: "Report", To: []string{"a@example.com"},
+				SendAt: time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC),
+			},
+			wantOK: true,
+		},
+		{
+			name: "not deferred",
+			msg:  newMsg("", ""),
+		},
+		{
+			name: "other property",
+			msg:  newMsg("SystemTime 0x0E06", "2026-10-20T07:00:00Z"),
+		},
+		{
+			name: "unparseable time",
+			msg:  newMsg(deferredSendTime, "soon"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convertScheduled(tt.msg)
+			if ok != tt.wantOK {
+				t.Fatalf("convertScheduled() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("convertScheduled() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
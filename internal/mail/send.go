@@ -9,71 +9,51 @@ import (
 	"github.com/microsoftgraph/msgraph-sdk-go/users"
 )
 
-// SendOptions configures sending a message
+// SendOptions configures sending a message. Addresses are RFC 5322
+// addresses, with or without a display name.
 type SendOptions struct {
-	To         []string
-	Cc         []string
-	Bcc        []string
-	Subject    string
-	Body       string
-	BodyType   string // "text" or "html"
-	SaveToSent bool
+	To       []string
+	Cc       []string
+	Bcc      []string
+	ReplyTo  []string
+	Subject  string
+	Body     string
+	BodyType string // "text" or "html"
+	// TextBody is a plain-text alternative to an HTML body, for recipients
+	// that do not show HTML
+	TextBody    string
+	Importance  string // "low", "normal", or "high"; empty keeps the default
+	Sensitivity string // "normal", "personal", "private", or "confidential"; empty keeps the default
+	// ReadReceipt and DeliveryReceipt request receipts from the recipients
+	ReadReceipt     bool
+	DeliveryReceipt bool
+	SaveToSent      bool
+	// Attachments are paths of files to attach
+	Attachments []string
 }
 
-// SendMessage sends an email
+// SendMessage sends an email. A message with a plain-text alternative or
+// more than 3 MB of attachments is saved as a draft and then sent, which
+// always keeps a copy in Sent Items.
 func SendMessage(ctx context.Context, client *msgraph.GraphServiceClient, opts SendOptions) error {
-	// Create message
-	msg := models.NewMessage()
-	msg.SetSubject(&opts.Subject)
-
-	// Set body
-	body := models.NewItemBody()
-	bodyContent := opts.Body
-	body.SetContent(&bodyContent)
-	if opts.BodyType == "html" {
-		bodyType := models.HTML_BODYTYPE
-		body.SetContentType(&bodyType)
-	} else {
-		bodyType := models.TEXT_BODYTYPE
-		body.SetContentType(&bodyType)
+	_, large, err := splitAttachments(opts.Attachments)
+	if err != nil {
+		return err
 	}
-	msg.SetBody(body)
-
-	// Set recipients
-	toRecipients := make([]models.Recipientable, len(opts.To))
-	for i, addr := range opts.To {
-		recipient := models.NewRecipient()
-		emailAddr := models.NewEmailAddress()
-		emailAddr.SetAddress(&addr)
-		recipient.SetEmailAddress(emailAddr)
-		toRecipients[i] = recipient
-	}
-	msg.SetToRecipients(toRecipients)
-
-	// Set CC
-	if len(opts.Cc) > 0 {
-		ccRecipients := make([]models.Recipientable, len(opts.Cc))
-		for i, addr := range opts.Cc {
-			recipient := models.NewRecipient()
-			emailAddr := models.NewEmailAddress()
-			emailAddr.SetAddress(&addr)
-			recipient.SetEmailAddress(emailAddr)
-			ccRecipients[i] = recipient
+	if opts.hasAlternative() || len(large) > 0 {
+		draft, err := createDraft(ctx, client, opts)
+		if err != nil {
+			return fmt.Errorf("failed to send message: %w", err)
 		}
-		msg.SetCcRecipients(ccRecipients)
-	}
-
-	// Set BCC
-	if len(opts.Bcc) > 0 {
-		bccRecipients := make([]models.Recipientable, len(opts.Bcc))
-		for i, addr := range opts.Bcc {
-			recipient := models.NewRecipient()
-			emailAddr := models.NewEmailAddress()
-			emailAddr.SetAddress(&addr)
-			recipient.SetEmailAddress(emailAddr)
-			bccRecipients[i] = recipient
+		if err := client.Me().Messages().ByMessageId(safeString(draft.GetId())).Send().Post(ctx, nil); err != nil {
+			return fmt.Errorf("failed to send message (it was left in Drafts): %w", err)
 		}
-		msg.SetBccRecipients(bccRecipients)
+		return nil
+	}
+
+	msg, err := newMessage(opts)
+	if err != nil {
+		return err
 	}
 
 	// Create send mail request
@@ -83,7 +63,7 @@ func SendMessage(ctx context.Context, client *msgraph.GraphServiceClient, opts S
 	sendMailBody.SetSaveToSentItems(&saveToSent)
 
 	// Send
-	err := client.Me().SendMail().Post(ctx, sendMailBody, nil)
+	err = client.Me().SendMail().Post(ctx, sendMailBody, nil)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
@@ -91,11 +71,12 @@ func SendMessage(ctx context.Context, client *msgraph.GraphServiceClient, opts S
 	return nil
 }
 
-// CreateDraft creates a draft message
-func CreateDraft(ctx context.Context, client *msgraph.GraphServiceClient, opts SendOptions) (*Message, error) {
-	// Create message
-	msg := models.NewMessage()
-	msg.SetSubject(&opts.Subject)
+// newMessage builds a Graph message from send options
+func newMessage(opts SendOptions) (models.Messageable, error) {
+	msg, err := messageFields(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set body
 	body := models.NewItemBody()
@@ -110,21 +91,80 @@ func CreateDraft(ctx context.Context, client *msgraph.GraphServiceClient, opts S
 	}
 	msg.SetBody(body)
 
+	// Files past the inline limit are left for createDraft to upload
+	inline, _, err := splitAttachments(opts.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	if len(inline) > 0 {
+		attachments, err := fileAttachments(inline)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetAttachments(attachments)
+	}
+
+	return msg, nil
+}
+
+// messageFields builds a Graph message with everything from send options
+// but the body
+func messageFields(opts SendOptions) (models.Messageable, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	msg := models.NewMessage()
+	msg.SetSubject(&opts.Subject)
+
+	if opts.Importance != "" {
+		importance, err := importanceValue(opts.Importance)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetImportance(importance)
+	}
+	if opts.Sensitivity != "" {
+		sensitivity, err := sensitivityProperty(opts.Sensitivity)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetSingleValueExtendedProperties([]models.SingleValueLegacyExtendedPropertyable{sensitivity})
+	}
+	msg.SetIsReadReceiptRequested(&opts.ReadReceipt)
+	msg.SetIsDeliveryReceiptRequested(&opts.DeliveryReceipt)
+
 	// Set recipients
-	if len(opts.To) > 0 {
-		toRecipients := make([]models.Recipientable, len(opts.To))
-		for i, addr := range opts.To {
-			recipient := models.NewRecipient()
-			emailAddr := models.NewEmailAddress()
-			emailAddr.SetAddress(&addr)
-			recipient.SetEmailAddress(emailAddr)
-			toRecipients[i] = recipient
+	to, err := newRecipients(opts.To)
+	if err != nil {
+		return nil, err
+	}
+	msg.SetToRecipients(to)
+	optional := []struct {
+		addrs []string
+		set   func([]models.Recipientable)
+	}{
+		{opts.Cc, msg.SetCcRecipients},
+		{opts.Bcc, msg.SetBccRecipients},
+		{opts.ReplyTo, msg.SetReplyTo},
+	}
+	for _, list := range optional {
+		if len(list.addrs) == 0 {
+			continue
+		}
+		recipients, err := newRecipients(list.addrs)
+		if err != nil {
+			return nil, err
 		}
-		msg.SetToRecipients(toRecipients)
+		list.set(recipients)
 	}
 
-	// Create draft
-	draft, err := client.Me().Messages().Post(ctx, msg, nil)
+	return msg, nil
+}
+
+// CreateDraft creates a draft message
+func CreateDraft(ctx context.Context, client *msgraph.GraphServiceClient, opts SendOptions) (*Message, error) {
+	draft, err := createDraft(ctx, client, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create draft: %w", err)
 	}
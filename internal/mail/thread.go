@@ -0,0 +1,153 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// GetThread retrieves every message in the conversation of a message,
+// across all folders, ordered oldest first
+func GetThread(ctx context.Context, client *msgraph.GraphServiceClient, messageID string) ([]Message, error) {
+	requestConfig := &users.ItemMessagesMessageItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesMessageItemRequestBuilderGetQueryParameters{
+			Select: []string{"id", "conversationId"},
+		},
+	}
+
+	msg, err := client.Me().Messages().ByMessageId(messageID).Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	conversationID := safeString(msg.GetConversationId())
+	if conversationID == "" {
+		return nil, fmt.Errorf("message has no conversation ID")
+	}
+
+	// Graph rejects $orderby together with a conversationId filter, so the
+	// thread is sorted locally
+	filter := "conversationId eq " + quoteOData(conversationID)
+	top := int32(50)
+	listConfig := &users.ItemMessagesRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMessagesRequestBuilderGetQueryParameters{
+			Filter: &filter,
+			Top:    &top,
+			Select: append(append([]string{}, listSelect...), "body"),
+		},
+	}
+
+	builder := client.Me().Messages()
+	messages := make([]Message, 0)
+	for {
+		result, err := builder.Get(ctx, listConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+
+		for _, item := range result.GetValue() {
+			m := convertMessage(item)
+			convertBody(item, &m)
+			messages = append(messages, m)
+		}
+
+		next := result.GetOdataNextLink()
+		if next == nil || *next == "" {
+			break
+		}
+		builder = builder.WithUrl(*next)
+		listConfig = nil
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].ReceivedAt.Before(messages[j].ReceivedAt)
+	})
+
+	return messages, nil
+}
+
+var (
+	// historyHeader matches lines that start the quoted history of a reply
+	historyHeader = regexp.MustCompile(`(?i)^(-{2,}\s*original message\s*-{2,}|_{10,}|on .+ wrote:|am .+ schrieb .+:|le .+ a écrit\s?:)$`)
+	// historyFrom matches the From line of an Outlook-style quoted header
+	historyFrom = regexp.MustCompile(`(?i)^(from|von|de):\s`)
+	// historySent matches the line that follows From in such a header
+	historySent = regexp.MustCompile(`(?i)^(sent|date|gesendet|envoyé):\s`)
+)
+
+// CollapseQuoted hides quoted history in a plain-text reply. Everything
+// from the first reply header ("On ... wrote:", "-----Original Message-----",
+// or an Outlook From/Sent block) is dropped, and runs of ">"-quoted lines
+// are each replaced by a marker. It returns the collapsed text and the
+// number of lines hidden.
+func CollapseQuoted(text string) (string, int) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	history := 0
+	if cut := historyStart(lines); cut >= 0 {
+		history = countNonBlank(lines[cut:])
+		lines = lines[:cut]
+	}
+
+	var out []string
+	hidden := 0
+	run := 0
+	flush := func() {
+		if run > 0 {
+			out = append(out, fmt.Sprintf("[... %d quoted line(s) hidden]", run))
+			hidden += run
+			run = 0
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			run++
+			continue
+		}
+		flush()
+		out = append(out, line)
+	}
+	flush()
+
+	collapsed := strings.TrimRight(strings.Join(out, "\n"), "\n\t ")
+	if history > 0 {
+		collapsed += fmt.Sprintf("\n\n[... %d quoted line(s) hidden]", history)
+		hidden += history
+	}
+
+	return collapsed, hidden
+}
+
+// historyStart returns the index of the first line of quoted history, or -1
+func historyStart(lines []string) int {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if historyHeader.MatchString(trimmed) {
+			return i
+		}
+		if historyFrom.MatchString(trimmed) {
+			for _, next := range lines[i+1 : min(i+4, len(lines))] {
+				if historySent.MatchString(strings.TrimSpace(next)) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// countNonBlank counts the lines that are not empty
+func countNonBlank(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollapseQuoted(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		want       string
+		wantHidden int
+	}{
+		{
+			name:       "no history",
+			input:      "Thanks, see you then.\n",
+			want:       "Thanks, see you then.",
+			wantHidden: 0,
+		},
+		{
+			name:       "gmail style",
+			input:      "Sounds good.\n\nOn Tue, Jan 16, 2024 at 9:00 AM Jane Doe <jane@example.com> wrote:\n> Lunch at noon?\n> Jane\n",
+			want:       "Sounds good.\n\n[... 3 quoted line(s) hidden]",
+			wantHidden: 3,
+		},
+		{
+			name:       "outlook header block",
+			input:      "Approved.\n\nFrom: Jane Doe <jane@example.com>\nSent: Tuesday, January 16, 2024 9:00 AM\nTo: Bob\nSubject: Budget\n\nPlease approve.",
+			want:       "Approved.\n\n[... 5 quoted line(s) hidden]",
+			wantHidden: 5,
+		},
+		{
+			name:       "original message separator",
+			input:      "Yes.\n-----Original Message-----\nFrom: x\nOld text",
+			want:       "Yes.\n\n[... 3 quoted line(s) hidden]",
+			wantHidden: 3,
+		},
+		{
+			name:       "inline quotes are collapsed",
+			input:      "> Can you make it?\nYes.\n> And Friday?\n> Or Monday?\nMonday works.",
+			want:       "[... 1 quoted line(s) hidden]\nYes.\n[... 2 quoted line(s) hidden]\nMonday works.",
+			wantHidden: 3,
+		},
+		{
+			name:       "from line alone is kept",
+			input:      "From: the team\nHappy holidays!",
+			want:       "From: the team\nHappy holidays!",
+			wantHidden: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hidden := CollapseQuoted(tt.input)
+			if got != tt.want {
+				t.Errorf("CollapseQuoted() = %q, want %q", got, tt.want)
+			}
+			if hidden != tt.wantHidden {
+				t.Errorf("CollapseQuoted() hidden = %d, want %d", hidden, tt.wantHidden)
+			}
+		})
+	}
+
+	t.Run("handles CRLF", func(t *testing.T) {
+		got, _ := CollapseQuoted("Ok\r\n\r\nOn Monday Jane wrote:\r\n> hi\r\n")
+		if strings.Contains(got, "\r") || !strings.HasPrefix(got, "Ok\n") {
+			t.Errorf("CollapseQuoted() = %q, want CRLF normalized", got)
+		}
+	})
+}
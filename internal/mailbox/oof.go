@@ -0,0 +1,278 @@
+package mailbox
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// Automatic reply statuses
+const (
+	StatusDisabled      = "disabled"
+	StatusAlwaysEnabled = "alwaysEnabled"
+	StatusScheduled     = "scheduled"
+)
+
+// External audiences for automatic replies
+const (
+	AudienceNone     = "none"
+	AudienceContacts = "contactsOnly"
+	AudienceAll      = "all"
+)
+
+// AutoReplies represents the automatic replies (out-of-office) setting.
+// Scheduled times are in the mailbox time zone.
+type AutoReplies struct {
+	Status          string     `json:"status"`
+	Audience        string     `json:"external_audience"`
+	InternalMessage string     `json:"internal_message,omitempty"`
+	ExternalMessage string     `json:"external_message,omitempty"`
+	Start           *time.Time `json:"start,omitempty"`
+	End             *time.Time `json:"end,omitempty"`
+	TimeZone        string     `json:"time_zone,omitempty"`
+}
+
+// GetAutoReplies retrieves the automatic replies setting
+func GetAutoReplies(ctx context.Context, client *msgraph.GraphServiceClient) (*AutoReplies, error) {
+	requestConfig := &users.ItemMailboxSettingsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailboxSettingsRequestBuilderGetQueryParameters{
+			Select: []string{"timeZone", "automaticRepliesSetting"},
+		},
+	}
+
+	settings, err := client.Me().MailboxSettings().Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get automatic replies: %w", err)
+	}
+
+	zone := safeString(settings.GetTimeZone())
+	replies := convertAutoReplies(settings.GetAutomaticRepliesSetting(), zone, mailboxLocation(zone))
+	return &replies, nil
+}
+
+// SetAutoReplies turns automatic replies on. They are scheduled when Start
+// and End are set and always on otherwise. The window is sent in zone,
+// the mailbox time zone from GetTimeZone.
+func SetAutoReplies(ctx context.Context, client *msgraph.GraphServiceClient, replies AutoReplies, zone string) (*AutoReplies, error) {
+	setting, err := toGraphAutoReplies(replies, zone, mailboxLocation(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	return patchAutoReplies(ctx, client, setting, zone)
+}
+
+// DisableAutoReplies turns automatic replies off, keeping the messages
+func DisableAutoReplies(ctx context.Context, client *msgraph.GraphServiceClient) error {
+	setting := models.NewAutomaticRepliesSetting()
+	status := models.DISABLED_AUTOMATICREPLIESSTATUS
+	setting.SetStatus(&status)
+
+	_, err := patchAutoReplies(ctx, client, setting, "")
+	return err
+}
+
+// GetTimeZone returns the mailbox time zone as stored by Outlook, which is
+// usually a Windows time zone name
+func GetTimeZone(ctx context.Context, client *msgraph.GraphServiceClient) (string, error) {
+	requestConfig := &users.ItemMailboxSettingsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailboxSettingsRequestBuilderGetQueryParameters{
+			Select: []string{"timeZone"},
+		},
+	}
+
+	settings, err := client.Me().MailboxSettings().Get(ctx, requestConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to get mailbox time zone: %w", err)
+	}
+
+	return safeString(settings.GetTimeZone()), nil
+}
+
+func patchAutoReplies(ctx context.Context, client *msgraph.GraphServiceClient, setting models.AutomaticRepliesSettingable, zone string) (*AutoReplies, error) {
+	body := models.NewMailboxSettings()
+	body.SetAutomaticRepliesSetting(setting)
+
+	updated, err := client.Me().MailboxSettings().Patch(ctx, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update automatic replies: %w", err)
+	}
+
+	if z := safeString(updated.GetTimeZone()); z != "" {
+		zone = z
+	}
+	replies := convertAutoReplies(updated.GetAutomaticRepliesSetting(), zone, mailboxLocation(zone))
+	return &replies, nil
+}
+
+// ParseAudience parses an --audience value
+func ParseAudience(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none", "":
+		return AudienceNone, nil
+	case "contacts", "contactsonly":
+		return AudienceContacts, nil
+	case "all":
+		return AudienceAll, nil
+	}
+	return "", fmt.Errorf("invalid audience: %s (use none, contacts, or all)", s)
+}
+
+// ParseWindowTime parses a schedule boundary. Times with an offset are
+// converted into loc; times without one, and YYYY-MM-DD dates (midnight),
+// are taken to be in loc already.
+func ParseWindowTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time: %s (use YYYY-MM-DD, YYYY-MM-DDTHH:MM, or RFC 3339)", s)
+}
+
+// MessageHTML prepares a reply message for Graph, which expects HTML.
+// Plain text is escaped and its line breaks kept.
+func MessageHTML(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if strings.HasPrefix(s, "<") {
+		return s
+	}
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>\n")
+}
+
+// toGraphAutoReplies converts an AutoReplies to a Graph setting, expressing
+// the schedule in zone
+func toGraphAutoReplies(r AutoReplies, zone string, loc *time.Location) (models.AutomaticRepliesSettingable, error) {
+	setting := models.NewAutomaticRepliesSetting()
+
+	status := models.ALWAYSENABLED_AUTOMATICREPLIESSTATUS
+	if r.Start != nil || r.End != nil {
+		if r.Start == nil || r.End == nil {
+			return nil, fmt.Errorf("a schedule needs both a start and an end")
+		}
+		if !r.End.After(*r.Start) {
+			return nil, fmt.Errorf("schedule end must be after its start")
+		}
+		status = models.SCHEDULED_AUTOMATICREPLIESSTATUS
+		setting.SetScheduledStartDateTime(graphDateTime(*r.Start, zone, loc))
+		setting.SetScheduledEndDateTime(graphDateTime(*r.End, zone, loc))
+	}
+	setting.SetStatus(&status)
+
+	audience := models.NONE_EXTERNALAUDIENCESCOPE
+	switch r.Audience {
+	case AudienceContacts:
+		audience = models.CONTACTSONLY_EXTERNALAUDIENCESCOPE
+	case AudienceAll:
+		audience = models.ALL_EXTERNALAUDIENCESCOPE
+	}
+	setting.SetExternalAudience(&audience)
+
+	internal := r.InternalMessage
+	setting.SetInternalReplyMessage(&internal)
+	if audience != models.NONE_EXTERNALAUDIENCESCOPE {
+		if r.ExternalMessage == "" {
+			return nil, fmt.Errorf("an external message is required for audience %s", r.Audience)
+		}
+		external := r.ExternalMessage
+		setting.SetExternalReplyMessage(&external)
+	}
+
+	return setting, nil
+}
+
+// convertAutoReplies converts a Graph setting, expressing the schedule in loc
+func convertAutoReplies(s models.AutomaticRepliesSettingable, zone string, loc *time.Location) AutoReplies {
+	replies := AutoReplies{
+		Status:   StatusDisabled,
+		Audience: AudienceNone,
+		TimeZone: zone,
+	}
+	if s == nil {
+		return replies
+	}
+
+	if status := s.GetStatus(); status != nil {
+		replies.Status = status.String()
+	}
+	if audience := s.GetExternalAudience(); audience != nil {
+		replies.Audience = audience.String()
+	}
+	replies.InternalMessage = safeString(s.GetInternalReplyMessage())
+	replies.ExternalMessage = safeString(s.GetExternalReplyMessage())
+
+	if replies.Status == StatusScheduled {
+		replies.Start = parseDateTime(s.GetScheduledStartDateTime(), loc)
+		replies.End = parseDateTime(s.GetScheduledEndDateTime(), loc)
+	}
+
+	return replies
+}
+
+// graphDateTime expresses t in the mailbox time zone. When the zone is not
+// known locally the time is sent in UTC instead.
+func graphDateTime(t time.Time, zone string, loc *time.Location) models.DateTimeTimeZoneable {
+	if zone == "" || loc == nil {
+		zone, loc = "UTC", time.UTC
+	}
+
+	dt := models.NewDateTimeTimeZone()
+	value := t.In(loc).Format("2006-01-02T15:04:05")
+	dt.SetDateTime(&value)
+	dt.SetTimeZone(&zone)
+	return dt
+}
+
+// parseDateTime converts a Graph date-time to a time in loc
+func parseDateTime(dt models.DateTimeTimeZoneable, loc *time.Location) *time.Time {
+	if dt == nil || dt.GetDateTime() == nil {
+		return nil
+	}
+
+	from := time.UTC
+	if l, err := LoadLocation(safeString(dt.GetTimeZone())); err == nil {
+		from = l
+	}
+
+	value := *dt.GetDateTime()
+	for _, layout := range []string{"2006-01-02T15:04:05.0000000", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, from); err == nil {
+			if loc != nil {
+				t = t.In(loc)
+			}
+			return &t
+		}
+	}
+
+	return nil
+}
+
+// mailboxLocation loads the mailbox time zone, returning nil when it is
+// unset or unknown
+func mailboxLocation(zone string) *time.Location {
+	loc, err := LoadLocation(zone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// safeString returns the value of a string pointer or empty string
+func safeString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
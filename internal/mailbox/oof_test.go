@@ -0,0 +1,208 @@
+package mailbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"Pacific Standard Time", "America/Los_Angeles", false},
+		{"W. Europe Standard Time", "Europe/Berlin", false},
+		{"Europe/London", "Europe/London", false},
+		{"UTC", "UTC", false},
+		{"Mars Standard Time", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := LoadLocation(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadLocation(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && loc.String() != tt.want {
+				t.Errorf("LoadLocation(%q) = %s, want %s", tt.name, loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAudience(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"contacts", AudienceContacts, false},
+		{"ContactsOnly", AudienceContacts, false},
+		{"all", AudienceAll, false},
+		{"none", AudienceNone, false},
+		{"everyone", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAudience(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAudience(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAudience(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWindowTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"2026-10-20", "2026-10-20T00:00:00+02:00", false},
+		{"2026-10-20T09:00", "2026-10-20T09:00:00+02:00", false},
+		{"2026-10-20 09:00", "2026-10-20T09:00:00+02:00", false},
+		{"2026-12-20T09:00:00", "2026-12-20T09:00:00+01:00", false},
+		{"2026-10-20T09:00:00Z", "2026-10-20T11:00:00+02:00", false},
+		{"2026-10-20T09:00:00-04:00", "2026-10-20T15:00:00+02:00", false},
+		{"next tuesday", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseWindowTime(tt.input, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindowTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Format(time.RFC3339) != tt.want {
+				t.Errorf("ParseWindowTime(%q) = %s, want %s", tt.input, got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "Away until Monday.\r\nAsk Bob & Co.", "Away until Monday.<br>\nAsk Bob &amp; Co."},
+		{"html kept", "<p>Away</p>\n", "<p>Away</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MessageHTML(tt.input); got != tt.want {
+				t.Errorf("MessageHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToGraphAutoReplies(t *testing.T) {
+	la, err := LoadLocation("Pacific Standard Time")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	start := time.Date(2026, 10, 20, 16, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+
+	t.Run("schedule converted to mailbox zone", func(t *testing.T) {
+		setting, err := toGraphAutoReplies(AutoReplies{
+			InternalMessage: "Away",
+			Start:           &start,
+			End:             &end,
+		}, "Pacific Standard Time", la)
+		if err != nil {
+			t.Fatalf("toGraphAutoReplies() error = %v", err)
+		}
+
+		if got := *setting.GetStatus(); got != models.SCHEDULED_AUTOMATICREPLIESSTATUS {
+			t.Errorf("status = %v, want scheduled", got)
+		}
+		dt := setting.GetScheduledStartDateTime()
+		if got := *dt.GetDateTime(); got != "2026-10-20T09:00:00" {
+			t.Errorf("start = %q, want 2026-10-20T09:00:00", got)
+		}
+		if got := *dt.GetTimeZone(); got != "Pacific Standard Time" {
+			t.Errorf("zone = %q, want Pacific Standard Time", got)
+		}
+		if got := *setting.GetExternalAudience(); got != models.NONE_EXTERNALAUDIENCESCOPE {
+			t.Errorf("audience = %v, want none", got)
+		}
+	})
+
+	t.Run("unknown zone falls back to UTC", func(t *testing.T) {
+		setting, err := toGraphAutoReplies(AutoReplies{Start: &start, End: &end}, "Mars Standard Time", nil)
+		if err != nil {
+			t.Fatalf("toGraphAutoReplies() error = %v", err)
+		}
+		dt := setting.GetScheduledStartDateTime()
+		if *dt.GetDateTime() != "2026-10-20T16:00:00" || *dt.GetTimeZone() != "UTC" {
+			t.Errorf("start = %s %s, want 2026-10-20T16:00:00 UTC", *dt.GetDateTime(), *dt.GetTimeZone())
+		}
+	})
+
+	t.Run("always on without a schedule", func(t *testing.T) {
+		setting, err := toGraphAutoReplies(AutoReplies{InternalMessage: "Away"}, "UTC", time.UTC)
+		if err != nil {
+			t.Fatalf("toGraphAutoReplies() error = %v", err)
+		}
+		if got := *setting.GetStatus(); got != models.ALWAYSENABLED_AUTOMATICREPLIESSTATUS {
+			t.Errorf("status = %v, want alwaysEnabled", got)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		replies AutoReplies
+	}{
+		{"start only", AutoReplies{Start: &start}},
+		{"end before start", AutoReplies{Start: &end, End: &start}},
+		{"external audience without message", AutoReplies{Audience: AudienceAll}},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := toGraphAutoReplies(tt.replies, "UTC", time.UTC); err == nil {
+				t.Error("toGraphAutoReplies() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestConvertAutoReplies(t *testing.T) {
+	la, err := LoadLocation("Pacific Standard Time")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+
+	setting := models.NewAutomaticRepliesSetting()
+	status := models.SCHEDULED_AUTOMATICREPLIESSTATUS
+	audience := models.CONTACTSONLY_EXTERNALAUDIENCESCOPE
+	setting.SetStatus(&status)
+	setting.SetExternalAudience(&audience)
+	setting.SetScheduledStartDateTime(graphDateTime(time.Date(2026, 10, 20, 16, 0, 0, 0, time.UTC), "UTC", time.UTC))
+	setting.SetScheduledEndDateTime(graphDateTime(time.Date(2026, 10, 23, 16, 0, 0, 0, time.UTC), "UTC", time.UTC))
+
+	replies := convertAutoReplies(setting, "Pacific Standard Time", la)
+	if replies.Status != StatusScheduled || replies.Audience != AudienceContacts {
+		t.Errorf("status, audience = %s, %s, want scheduled, contactsOnly", replies.Status, replies.Audience)
+	}
+	if replies.Start == nil || replies.Start.Format("2006-01-02 15:04") != "2026-10-20 09:00" {
+		t.Errorf("start = %v, want 2026-10-20 09:00 in mailbox zone", replies.Start)
+	}
+}
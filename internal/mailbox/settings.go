@@ -0,0 +1,364 @@
+package mailbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/microsoft/kiota-abstractions-go/serialization"
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/users"
+)
+
+// Profile represents the signed-in user's profile
+type Profile struct {
+	DisplayName       string   `json:"display_name"`
+	UserPrincipalName string   `json:"user_principal_name"`
+	Mail              string   `json:"mail,omitempty"`
+	ProxyAddresses    []string `json:"proxy_addresses,omitempty"`
+}
+
+// Settings represents the regional and working hours mailbox settings
+type Settings struct {
+	TimeZone     string        `json:"time_zone"`
+	DateFormat   string        `json:"date_format,omitempty"`
+	TimeFormat   string        `json:"time_format,omitempty"`
+	Language     string        `json:"language,omitempty"`
+	LanguageName string        `json:"language_name,omitempty"`
+	WorkingHours *WorkingHours `json:"working_hours,omitempty"`
+}
+
+// WorkingHours represents the days and hours of the work week. Start and
+// End are HH:MM times in TimeZone.
+type WorkingHours struct {
+	Days     []string `json:"days"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	TimeZone string   `json:"time_zone,omitempty"`
+}
+
+// SettingsUpdate holds the settings to change; empty fields are kept
+type SettingsUpdate struct {
+	TimeZone string
+	Days     []string
+	Start    string
+	End      string
+}
+
+// weekdays lists the Graph day names, Sunday first as Graph numbers them
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+var settingsSelect = []string{"timeZone", "dateFormat", "timeFormat", "language", "workingHours"}
+
+// GetProfile retrieves the signed-in user's profile
+func GetProfile(ctx context.Context, client *msgraph.GraphServiceClient) (*Profile, error) {
+	requestConfig := &users.UserItemRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.UserItemRequestBuilderGetQueryParameters{
+			Select: []string{"displayName", "userPrincipalName", "mail", "proxyAddresses"},
+		},
+	}
+
+	user, err := client.Me().Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &Profile{
+		DisplayName:       safeString(user.GetDisplayName()),
+		UserPrincipalName: safeString(user.GetUserPrincipalName()),
+		Mail:              safeString(user.GetMail()),
+		ProxyAddresses:    user.GetProxyAddresses(),
+	}, nil
+}
+
+// GetSettings retrieves the mailbox settings
+func GetSettings(ctx context.Context, client *msgraph.GraphServiceClient) (*Settings, error) {
+	requestConfig := &users.ItemMailboxSettingsRequestBuilderGetRequestConfiguration{
+		QueryParameters: &users.ItemMailboxSettingsRequestBuilderGetQueryParameters{
+			Select: settingsSelect,
+		},
+	}
+
+	result, err := client.Me().MailboxSettings().Get(ctx, requestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mailbox settings: %w", err)
+	}
+
+	settings := convertSettings(result)
+	return &settings, nil
+}
+
+// UpdateSettings changes the mailbox time zone and working hours and
+// returns the resulting settings
+func UpdateSettings(ctx context.Context, client *msgraph.GraphServiceClient, update SettingsUpdate) (*Settings, error) {
+	current, err := GetSettings(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := toGraphSettings(*current, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := client.Me().MailboxSettings().Patch(ctx, body, nil); err != nil {
+		return nil, fmt.Errorf("failed to update mailbox settings: %w", err)
+	}
+
+	return GetSettings(ctx, client)
+}
+
+// toGraphSettings builds the PATCH body for an update. Graph replaces
+// working hours as a whole, so unchanged parts are copied from current.
+// Working hours follow a time zone change when they were in the old zone.
+func toGraphSettings(current Settings, update SettingsUpdate) (models.MailboxSettingsable, error) {
+	body := models.NewMailboxSettings()
+
+	hours := WorkingHours{TimeZone: current.TimeZone}
+	if current.WorkingHours != nil {
+		hours = *current.WorkingHours
+	}
+	changed := len(update.Days) > 0 || update.Start != "" || update.End != ""
+
+	if update.TimeZone != "" {
+		zone := update.TimeZone
+		body.SetTimeZone(&zone)
+		if hours.TimeZone == "" || hours.TimeZone == current.TimeZone {
+			hours.TimeZone = zone
+			changed = changed || current.WorkingHours != nil
+		}
+	}
+
+	if !changed {
+		return body, nil
+	}
+
+	if len(update.Days) > 0 {
+		hours.Days = update.Days
+	}
+	if update.Start != "" {
+		hours.Start = update.Start
+	}
+	if update.End != "" {
+		hours.End = update.End
+	}
+
+	wh, err := toGraphWorkingHours(hours)
+	if err != nil {
+		return nil, err
+	}
+	body.SetWorkingHours(wh)
+
+	return body, nil
+}
+
+// toGraphWorkingHours validates and converts working hours
+func toGraphWorkingHours(hours WorkingHours) (models.WorkingHoursable, error) {
+	if len(hours.Days) == 0 {
+		return nil, fmt.Errorf("working hours need at least one day")
+	}
+
+	start, err := ParseClock(hours.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseClock(hours.End)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("working hours must end after they start")
+	}
+
+	days := make([]models.DayOfWeek, 0, len(hours.Days))
+	for _, d := range hours.Days {
+		i := dayIndex(d)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid day: %s", d)
+		}
+		days = append(days, models.DayOfWeek(i))
+	}
+
+	wh := models.NewWorkingHours()
+	wh.SetDaysOfWeek(days)
+	wh.SetStartTime(serialization.NewTimeOnly(start))
+	wh.SetEndTime(serialization.NewTimeOnly(end))
+	if hours.TimeZone != "" {
+		zone := models.NewTimeZoneBase()
+		name := hours.TimeZone
+		zone.SetName(&name)
+		wh.SetTimeZone(zone)
+	}
+
+	return wh, nil
+}
+
+// convertSettings converts Graph mailbox settings to our Settings type
+func convertSettings(s models.MailboxSettingsable) Settings {
+	settings := Settings{
+		TimeZone:   safeString(s.GetTimeZone()),
+		DateFormat: safeString(s.GetDateFormat()),
+		TimeFormat: safeString(s.GetTimeFormat()),
+	}
+
+	if lang := s.GetLanguage(); lang != nil {
+		settings.Language = safeString(lang.GetLocale())
+		settings.LanguageName = safeString(lang.GetDisplayName())
+	}
+
+	if wh := s.GetWorkingHours(); wh != nil {
+		hours := &WorkingHours{}
+		for _, d := range wh.GetDaysOfWeek() {
+			hours.Days = append(hours.Days, d.String())
+		}
+		hours.Days = sortDays(hours.Days)
+		if t := wh.GetStartTime(); t != nil {
+			hours.Start = clockString(*t)
+		}
+		if t := wh.GetEndTime(); t != nil {
+			hours.End = clockString(*t)
+		}
+		if zone := wh.GetTimeZone(); zone != nil {
+			hours.TimeZone = safeString(zone.GetName())
+		}
+		settings.WorkingHours = hours
+	}
+
+	return settings
+}
+
+// clockString formats a time of day as HH:MM
+func clockString(t serialization.TimeOnly) string {
+	s := t.String()
+	if len(s) >= 5 {
+		return s[:5]
+	}
+	return s
+}
+
+// ParseClock parses a time of day such as 9:00, 09:30, or 17:00:00
+func ParseClock(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time of day: %s (use HH:MM)", s)
+}
+
+// ParseDays parses a list of work days. It accepts comma-separated day
+// names or three-letter abbreviations, ranges such as mon-fri, and the
+// words weekdays and weekend. Days are returned Monday first.
+func ParseDays(s string) ([]string, error) {
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+			continue
+		case "weekdays":
+			part = "mon-fri"
+		case "weekend":
+			part = "sat-sun"
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+
+		first, last := dayIndex(from), dayIndex(to)
+		if first < 0 || last < 0 {
+			return nil, fmt.Errorf("invalid day: %s", part)
+		}
+		for i := first; ; i = (i + 1) % 7 {
+			seen[weekdays[i]] = true
+			if i == last {
+				break
+			}
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no days given")
+	}
+
+	days := make([]string, 0, len(seen))
+	for d := range seen {
+		days = append(days, d)
+	}
+	return sortDays(days), nil
+}
+
+// FormatDays describes a list of days compactly, e.g. Mon-Fri or Mon, Wed
+func FormatDays(days []string) string {
+	present := make([]bool, 7)
+	for _, d := range days {
+		if i := dayIndex(d); i >= 0 {
+			present[i] = true
+		}
+	}
+
+	// Walk Monday to Sunday, grouping consecutive days into ranges
+	var parts []string
+	for n := 0; n < 7; n++ {
+		i := (n + 1) % 7
+		if !present[i] {
+			continue
+		}
+		end := n
+		for end+1 < 7 && present[(end+2)%7] {
+			end++
+		}
+		first, last := abbreviate(weekdays[i]), abbreviate(weekdays[(end+1)%7])
+		switch {
+		case end == n:
+			parts = append(parts, first)
+		case end == n+1:
+			parts = append(parts, first, last)
+		default:
+			parts = append(parts, first+"-"+last)
+		}
+		n = end
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// dayIndex returns the Graph index of a day name or abbreviation, or -1
+func dayIndex(s string) int {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 3 {
+		return -1
+	}
+	for i, d := range weekdays {
+		if strings.HasPrefix(d, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortDays orders day names Monday first
+func sortDays(days []string) []string {
+	sorted := make([]string, 0, len(days))
+	for n := 0; n < 7; n++ {
+		d := weekdays[(n+1)%7]
+		for _, day := range days {
+			if strings.EqualFold(day, d) {
+				sorted = append(sorted, d)
+				break
+			}
+		}
+	}
+	return sorted
+}
+
+// abbreviate returns the three-letter form of a day name
+func abbreviate(day string) string {
+	return strings.ToUpper(day[:1]) + day[1:3]
+}
@@ -0,0 +1,157 @@
+package mailbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"mon-fri", []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, false},
+		{"weekdays", []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, false},
+		{"weekend", []string{"saturday", "sunday"}, false},
+		{"fri-mon", []string{"monday", "friday", "saturday", "sunday"}, false},
+		{"Wednesday, mon, wed", []string{"monday", "wednesday"}, false},
+		{"sun-tue,thu", []string{"monday", "tuesday", "thursday", "sunday"}, false},
+		{"mo", nil, true},
+		{"mon-funday", nil, true},
+		{"", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDays(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDays(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDays(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDays(t *testing.T) {
+	tests := []struct {
+		days []string
+		want string
+	}{
+		{[]string{"monday", "tuesday", "wednesday", "thursday", "friday"}, "Mon-Fri"},
+		{[]string{"monday", "wednesday"}, "Mon, Wed"},
+		{[]string{"monday", "tuesday"}, "Mon, Tue"},
+		{[]string{"sunday", "saturday", "friday"}, "Fri-Sun"},
+		{[]string{"monday", "tuesday", "wednesday", "friday", "saturday", "sunday"}, "Mon-Wed, Fri-Sun"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatDays(tt.days); got != tt.want {
+				t.Errorf("FormatDays(%v) = %q, want %q", tt.days, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"9:00", "09:00", false},
+		{"17:30", "17:30", false},
+		{"08:15:00", "08:15", false},
+		{"25:00", "", true},
+		{"9am", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseClock(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClock(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Format("15:04") != tt.want {
+				t.Errorf("ParseClock(%q) = %s, want %s", tt.input, got.Format("15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestToGraphSettings(t *testing.T) {
+	current := Settings{
+		TimeZone: "Pacific Standard Time",
+		WorkingHours: &WorkingHours{
+			Days:     []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+			Start:    "08:00",
+			End:      "17:00",
+			TimeZone: "Pacific Standard Time",
+		},
+	}
+
+	t.Run("time zone moves working hours along", func(t *testing.T) {
+		body, err := toGraphSettings(current, SettingsUpdate{TimeZone: "Eastern Standard Time"})
+		if err != nil {
+			t.Fatalf("toGraphSettings() error = %v", err)
+		}
+		if got := *body.GetTimeZone(); got != "Eastern Standard Time" {
+			t.Errorf("time zone = %q", got)
+		}
+		wh := body.GetWorkingHours()
+		if wh == nil {
+			t.Fatal("working hours not sent")
+		}
+		if got := *wh.GetTimeZone().GetName(); got != "Eastern Standard Time" {
+			t.Errorf("working hours zone = %q", got)
+		}
+		if got := wh.GetStartTime().String(); got != "08:00:00" {
+			t.Errorf("start = %q, want kept 08:00:00", got)
+		}
+	})
+
+	t.Run("working hours in another zone are kept", func(t *testing.T) {
+		other := current
+		hours := *current.WorkingHours
+		hours.TimeZone = "UTC"
+		other.WorkingHours = &hours
+
+		body, err := toGraphSettings(other, SettingsUpdate{TimeZone: "Eastern Standard Time"})
+		if err != nil {
+			t.Fatalf("toGraphSettings() error = %v", err)
+		}
+		if body.GetWorkingHours() != nil {
+			t.Error("working hours sent, want unchanged")
+		}
+	})
+
+	t.Run("partial working hours update", func(t *testing.T) {
+		body, err := toGraphSettings(current, SettingsUpdate{Days: []string{"monday", "tuesday"}, End: "16:00"})
+		if err != nil {
+			t.Fatalf("toGraphSettings() error = %v", err)
+		}
+		if body.GetTimeZone() != nil {
+			t.Error("time zone sent, want unchanged")
+		}
+		wh := body.GetWorkingHours()
+		if len(wh.GetDaysOfWeek()) != 2 || wh.GetStartTime().String() != "08:00:00" || wh.GetEndTime().String() != "16:00:00" {
+			t.Errorf("working hours = %v %s-%s", wh.GetDaysOfWeek(), wh.GetStartTime(), wh.GetEndTime())
+		}
+	})
+
+	t.Run("end before start", func(t *testing.T) {
+		if _, err := toGraphSettings(current, SettingsUpdate{Start: "18:00"}); err == nil {
+			t.Error("toGraphSettings() error = nil, want error")
+		}
+	})
+
+	t.Run("no working hours yet", func(t *testing.T) {
+		if _, err := toGraphSettings(Settings{TimeZone: "UTC"}, SettingsUpdate{Start: "09:00"}); err == nil {
+			t.Error("toGraphSettings() error = nil, want error for missing days and end")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package merge
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Log statuses
+const (
+	// StatusPending is written before a send; on its own it means the merge
+	// stopped mid-send and the message may or may not have gone out
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDrafted = "drafted"
+	StatusFailed  = "failed"
+)
+
+// Delivery modes. A message drafted in one run is still to be sent by a
+// later run in send mode, so the log tracks each mode separately.
+const (
+	ModeSend  = "send"
+	ModeDraft = "draft"
+)
+
+// Entry is one line of a merge log
+type Entry struct {
+	Key     string    `json:"key"`
+	Mode    string    `json:"mode,omitempty"`
+	Row     int       `json:"row"`
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Status  string    `json:"status"`
+	ID      string    `json:"id,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Log is an append-only JSON lines record of a merge. Each entry is
+// synced to disk before the next send, so a crash loses at most the
+// outcome of the message in flight.
+type Log struct {
+	f      *os.File
+	status map[logKey]string
+}
+
+// logKey identifies a message in one delivery mode
+type logKey struct {
+	mode string
+	key  string
+}
+
+// entryKey returns the log key of an entry. Entries written before modes
+// were recorded are drafts if they say so and sends otherwise.
+func entryKey(e Entry) logKey {
+	mode := e.Mode
+	if mode == "" {
+		mode = ModeSend
+		if e.Status == StatusDrafted {
+			mode = ModeDraft
+		}
+	}
+	return logKey{mode: mode, key: e.Key}
+}
+
+// OpenLog opens or creates a merge log and reads the status of every
+// message already in it
+func OpenLog(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merge log: %w", err)
+	}
+
+	l := &Log{f: f, status: map[logKey]string{}}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A crash can leave a partial last line
+			continue
+		}
+		l.status[entryKey(e)] = e.Status
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read merge log: %w", err)
+	}
+	return l, nil
+}
+
+// Status returns the latest status recorded for a message key in a
+// delivery mode, or ""
+func (l *Log) Status(mode, key string) string {
+	return l.status[logKey{mode: mode, key: key}]
+}
+
+// Append writes an entry and syncs it to disk
+func (l *Log) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write merge log: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("failed to write merge log: %w", err)
+	}
+	l.status[entryKey(e)] = e.Status
+	return nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,102 @@
+// Package merge sends personalised messages rendered from a template and a
+// table of recipients.
+package merge
+
+import (
+	"context"
+	"time"
+)
+
+// Sender sends or drafts one message and returns its ID, if known
+type Sender func(ctx context.Context, msg *Message) (string, error)
+
+// Merger delivers rendered messages, skipping those the log shows as done
+type Merger struct {
+	Send Sender
+	Log  *Log
+	// Draft creates drafts rather than sending. Drafted messages are still
+	// pending for a later run that sends.
+	Draft bool
+	// Interval is the minimum time between deliveries
+	Interval time.Duration
+	// Logf reports progress and failures; nil discards them
+	Logf func(format string, args ...any)
+}
+
+// Result counts the outcome of a merge
+type Result struct {
+	Delivered int
+	Skipped   int
+	// Uncertain counts messages whose earlier send was interrupted; they
+	// are skipped rather than risk a duplicate
+	Uncertain int
+	Failed    int
+}
+
+// Run delivers the messages in order. Failed deliveries are logged and the
+// merge continues; a log write failure stops it, since without the log a
+// rerun could send twice.
+func (m *Merger) Run(ctx context.Context, msgs []*Message) (Result, error) {
+	var result Result
+	mode, done := ModeSend, StatusSent
+	if m.Draft {
+		mode, done = ModeDraft, StatusDrafted
+	}
+
+	var last time.Time
+	for _, msg := range msgs {
+		key := msg.Key()
+		switch m.Log.Status(mode, key) {
+		case StatusSent, StatusDrafted:
+			result.Skipped++
+			continue
+		case StatusPending:
+			m.logf("row %d: skipped, an earlier run was interrupted while delivering it to %v", msg.Row, msg.To)
+			result.Uncertain++
+			continue
+		}
+
+		if !last.IsZero() && m.Interval > 0 {
+			if wait := m.Interval - time.Since(last); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return result, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		entry := Entry{Key: key, Mode: mode, Row: msg.Row, To: msg.To, Subject: msg.Subject, Status: StatusPending, Time: time.Now()}
+		if err := m.Log.Append(entry); err != nil {
+			return result, err
+		}
+
+		last = time.Now()
+		id, err := m.Send(ctx, msg)
+		entry.Time = time.Now()
+		if err != nil {
+			entry.Status = StatusFailed
+			entry.Error = err.Error()
+			result.Failed++
+			m.logf("row %d: %v", msg.Row, err)
+		} else {
+			entry.Status = done
+			entry.ID = id
+			result.Delivered++
+			m.logf("row %d: %s to %v", msg.Row, done, msg.To)
+		}
+		if err := m.Log.Append(entry); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (m *Merger) logf(format string, args ...any) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	}
+}
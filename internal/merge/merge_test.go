@@ -0,0 +1,331 @@
+package merge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffemail, name ,plan\nann@example.com,Ann,Pro\n\nbob@example.com, Bob ,Free\n"
+	rows, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	want := []Row{
+		{"email": "ann@example.com", "name": "Ann", "plan": "Pro"},
+		{"email": "bob@example.com", "name": "Bob", "plan": "Free"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ParseCSV() = %v, want %v", rows, want)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("email,name\nann@example.com\n")); err == nil {
+		t.Error("ParseCSV() with a short row: expected error")
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	rows, err := ParseJSON(strings.NewReader(`[{"email": "ann@example.com", "open": 3}]`))
+	if err != nil {
+		t.Fatalf("ParseJSON() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0]["email"] != "ann@example.com" || rows[0]["open"] != float64(3) {
+		t.Errorf("ParseJSON() = %v", rows)
+	}
+
+	for _, input := range []string{`{"email": "x"}`, `[null]`, `[1]`} {
+		if _, err := ParseJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseJSON(%s): expected error", input)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		markdown bool
+		row      Row
+		want     *Message
+		wantErr  bool
+	}{
+		{
+			name:     "headers and body",
+			template: "To: {{.name}} <{{.email}}>\nSubject: Status for {{.name}}\n\nHi {{.name}},\n\n{{.open}} open tickets.\n",
+			row:      Row{"name": "Ann", "email": "ann@example.com", "open": "3"},
+			want: &Message{
+				Row: 1, To: []string{"Ann <ann@example.com>"}, Subject: "Status for Ann",
+				Body: "Hi Ann,\n\n3 open tickets.\n", BodyType: "text",
+			},
+		},
+		{
+			name:     "email column when no To header",
+			template: "Subject: Hello\n\nHi\n",
+			row:      Row{"email": "ann@example.com"},
+			want:     &Message{Row: 1, To: []string{"ann@example.com"}, Subject: "Hello", Body: "Hi\n", BodyType: "text"},
+		},
+		{
+			name:     "markdown",
+			template: "Subject: Hello\n\n**{{.name}}**\n",
+			markdown: true,
+			row:      Row{"email": "ann@example.com", "name": "Ann"},
+			want: &Message{
+				Row: 1, To: []string{"ann@example.com"}, Subject: "Hello",
+				Body: "<html><body>\n<p><strong>Ann</strong></p>\n</body></html>\n", BodyType: "html",
+				TextBody: "**Ann**\n",
+			},
+		},
+		{
+			name:     "missing column",
+			template: "Subject: Hi {{.nmae}}\n\nBody\n",
+			row:      Row{"email": "ann@example.com", "name": "Ann"},
+			wantErr:  true,
+		},
+		{
+			name:     "no recipient",
+			template: "Subject: Hi\n\nBody\n",
+			row:      Row{"name": "Ann"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid address",
+			template: "To: {{.email}}\nSubject: Hi\n\nBody\n",
+			row:      Row{"email": "ann at example.com"},
+			wantErr:  true,
+		},
+		{
+			name:     "empty subject",
+			template: "To: {{.email}}\nSubject: {{.subject}}\n\nBody\n",
+			row:      Row{"email": "ann@example.com", "subject": ""},
+			wantErr:  true,
+		},
+		{
+			name:     "empty body",
+			template: "To: {{.email}}\nSubject: Hi\n\n{{.note}}\n",
+			row:      Row{"email": "ann@example.com", "note": ""},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate("test", tt.template)
+			if err != nil {
+				t.Fatalf("ParseTemplate() error = %v", err)
+			}
+			tmpl.Markdown = tt.markdown
+
+			got, err := tmpl.Render(1, tt.row)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Render() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderAllStopsAtFirstError(t *testing.T) {
+	tmpl, err := ParseTemplate("test", "Subject: Hi\n\nHi {{.name}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []Row{{"email": "a@example.com", "name": "A"}, {"email": "b@example.com"}}
+	if _, err := tmpl.RenderAll(rows); err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("RenderAll() error = %v, want a row 2 error", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	a := &Message{To: []string{"a@example.com"}, Subject: "Hi", Body: "Body"}
+	b := &Message{Row: 5, To: []string{"a@example.com"}, Subject: "Hi", Body: "Body"}
+	c := &Message{To: []string{"a@example.com"}, Subject: "Hi", Body: "Body!"}
+
+	if a.Key() != b.Key() {
+		t.Error("Key() depends on the row number")
+	}
+	if a.Key() == c.Key() {
+		t.Error("Key() ignores the body")
+	}
+}
+
+func TestLogReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "merge", "log.jsonl")
+
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	if err := log.Append(Entry{Key: "a", Status: StatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(Entry{Key: "a", Status: StatusSent}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(Entry{Key: "b", Status: StatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append(Entry{Key: "d", Mode: ModeDraft, Status: StatusDrafted}); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	// Simulate a crash mid-write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"key":"c","sta`)
+	f.Close()
+
+	log, err = OpenLog(path)
+	if err != nil {
+		t.Fatalf("OpenLog() reopen error = %v", err)
+	}
+	defer log.Close()
+
+	for key, want := range map[string]string{"a": StatusSent, "b": StatusPending, "c": "", "d": ""} {
+		if got := log.Status(ModeSend, key); got != want {
+			t.Errorf("Status(send, %q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := log.Status(ModeDraft, "d"); got != StatusDrafted {
+		t.Errorf("Status(draft, d) = %q, want %q", got, StatusDrafted)
+	}
+}
+
+func TestLogLegacyEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	data := `{"key":"a","status":"drafted"}` + "\n" + `{"key":"b","status":"sent"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	defer log.Close()
+
+	tests := []struct {
+		mode, key, want string
+	}{
+		{ModeDraft, "a", StatusDrafted},
+		{ModeSend, "a", ""},
+		{ModeSend, "b", StatusSent},
+		{ModeDraft, "b", ""},
+	}
+	for _, tt := range tests {
+		if got := log.Status(tt.mode, tt.key); got != tt.want {
+			t.Errorf("Status(%s, %s) = %q, want %q", tt.mode, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMergerSendAfterDraft(t *testing.T) {
+	log, err := OpenLog(filepath.Join(t.TempDir(), "log.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	msgs := []*Message{
+		{Row: 1, To: []string{"a@example.com"}, Subject: "Hi", Body: "A"},
+		{Row: 2, To: []string{"b@example.com"}, Subject: "Hi", Body: "B"},
+	}
+	send := func(ctx context.Context, msg *Message) (string, error) { return "id", nil }
+
+	m := &Merger{Log: log, Send: send, Draft: true}
+	if result, err := m.Run(context.Background(), msgs); err != nil || result.Delivered != 2 {
+		t.Fatalf("draft Run() = %+v, %v", result, err)
+	}
+
+	// Drafting again skips everything, but sending delivers every row
+	if result, err := m.Run(context.Background(), msgs); err != nil || result.Skipped != 2 {
+		t.Errorf("second draft Run() = %+v, %v, want 2 skipped", result, err)
+	}
+	m.Draft = false
+	if result, err := m.Run(context.Background(), msgs); err != nil || result.Delivered != 2 {
+		t.Errorf("send Run() = %+v, %v, want 2 delivered", result, err)
+	}
+}
+
+func TestMergerResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	msgs := []*Message{
+		{Row: 1, To: []string{"a@example.com"}, Subject: "Hi", Body: "A"},
+		{Row: 2, To: []string{"b@example.com"}, Subject: "Hi", Body: "B"},
+		{Row: 3, To: []string{"c@example.com"}, Subject: "Hi", Body: "C"},
+		{Row: 4, To: []string{"d@example.com"}, Subject: "Hi", Body: "D"},
+	}
+
+	// First run: row 2 fails, and the process dies while sending row 3
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []int
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Merger{
+		Log: log,
+		Send: func(ctx context.Context, msg *Message) (string, error) {
+			switch msg.Row {
+			case 2:
+				return "", errors.New("throttled")
+			case 3:
+				cancel()
+				return "", ctx.Err()
+			}
+			sent = append(sent, msg.Row)
+			return "id", nil
+		},
+	}
+	result, err := m.Run(ctx, msgs)
+	if err == nil {
+		t.Fatal("Run() after cancel: expected error")
+	}
+	if result.Delivered != 1 || result.Failed != 2 {
+		t.Errorf("first run result = %+v", result)
+	}
+	log.Close()
+
+	// Undo the failure record for row 3 to leave it pending, as a crash
+	// between the two log writes would
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if err := os.WriteFile(path, []byte(strings.Join(lines[:len(lines)-1], "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second run: row 1 is done, row 2 is retried, row 3 is uncertain
+	log, err = OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	m.Log = log
+	m.Send = func(ctx context.Context, msg *Message) (string, error) {
+		sent = append(sent, msg.Row)
+		return "id", nil
+	}
+	result, err = m.Run(context.Background(), msgs)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := Result{Delivered: 2, Skipped: 1, Uncertain: 1}
+	if result != want {
+		t.Errorf("second run result = %+v, want %+v", result, want)
+	}
+	if !reflect.DeepEqual(sent, []int{1, 2, 4}) {
+		t.Errorf("sent rows = %v, want [1 2 4]", sent)
+	}
+}
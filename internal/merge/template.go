@@ -0,0 +1,137 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	netmail "net/mail"
+	"strings"
+	"text/template"
+
+	"github.com/pp/octl/internal/compose"
+)
+
+// Template is a mail merge template: To/Cc/Bcc/Subject headers, a blank
+// line, and the body, all rendered per row with text/template
+type Template struct {
+	tmpl *template.Template
+	// Markdown renders the body from Markdown to HTML
+	Markdown bool
+}
+
+// Message is a template rendered for one row
+type Message struct {
+	// Row is the 1-based position of the row in the data
+	Row      int
+	To       []string
+	Cc       []string
+	Bcc      []string
+	Subject  string
+	Body     string
+	BodyType string
+	// TextBody is the Markdown source of an HTML body, sent as its
+	// plain-text alternative
+	TextBody string
+}
+
+// ParseTemplate compiles a template. Referencing a column the row does not
+// have is an error at render time rather than an empty string.
+func ParseTemplate(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render renders the template for a row. Without a To header the row's
+// email column is used as the recipient.
+func (t *Template) Render(index int, row Row) (*Message, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, row); err != nil {
+		return nil, fmt.Errorf("row %d: %w", index, err)
+	}
+
+	draft, err := compose.Parse(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("row %d: %w", index, err)
+	}
+
+	to := draft.To
+	if len(to) == 0 {
+		if email, ok := row["email"].(string); ok && email != "" {
+			to = []string{email}
+		}
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("row %d: no recipient (add a To header or an email column)", index)
+	}
+	for _, list := range [][]string{to, draft.Cc, draft.Bcc} {
+		for _, addr := range list {
+			if _, err := netmail.ParseAddress(addr); err != nil {
+				return nil, fmt.Errorf("row %d: invalid email address: %q", index, addr)
+			}
+		}
+	}
+	if strings.TrimSpace(draft.Subject) == "" {
+		return nil, fmt.Errorf("row %d: empty subject", index)
+	}
+	if draft.Body == "" {
+		return nil, fmt.Errorf("row %d: empty body", index)
+	}
+
+	msg := &Message{
+		Row:      index,
+		To:       to,
+		Cc:       draft.Cc,
+		Bcc:      draft.Bcc,
+		Subject:  draft.Subject,
+		Body:     draft.Body,
+		BodyType: "text",
+	}
+	if t.Markdown {
+		html, err := compose.Markdown(draft.Body)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", index, err)
+		}
+		msg.TextBody = draft.Body
+		msg.Body = html
+		msg.BodyType = "html"
+	}
+	return msg, nil
+}
+
+// RenderAll renders every row, stopping at the first error so a broken
+// template or row is caught before anything is sent
+func (t *Template) RenderAll(rows []Row) ([]*Message, error) {
+	msgs := make([]*Message, 0, len(rows))
+	for i, row := range rows {
+		msg, err := t.Render(i+1, row)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// Key identifies the message's content in the merge log, so a resumed
+// merge recognises what it already sent even if rows were reordered
+func (m *Message) Key() string {
+	h := sha256.New()
+	for _, part := range [][]string{m.To, m.Cc, m.Bcc, {m.Subject, m.Body}} {
+		fmt.Fprintf(h, "%q\n", part)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// String renders the message in template form for previews
+func (m *Message) String() string {
+	return compose.Template(compose.Draft{
+		To:      m.To,
+		Cc:      m.Cc,
+		Bcc:     m.Bcc,
+		Subject: m.Subject,
+		Body:    m.Body,
+	})
+}
@@ -0,0 +1,128 @@
+package mirror
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// infoSeparator separates the unique part of a Maildir file name from its flags
+const infoSeparator = ":2,"
+
+// Maildir is a single Maildir folder on disk
+type Maildir struct {
+	dir string
+}
+
+// OpenMaildir opens the Maildir at dir, creating its tmp, new and cur
+// subdirectories if needed
+func OpenMaildir(dir string) (*Maildir, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+	return &Maildir{dir: dir}, nil
+}
+
+// Dir returns the Maildir's directory
+func (md *Maildir) Dir() string {
+	return md.dir
+}
+
+// Deliver writes a message into cur with the given flags and returns its
+// file name. The message is written to tmp first so readers never see a
+// partial file.
+func (md *Maildir) Deliver(key string, data []byte, flags string) (string, error) {
+	name := uniqueName(key) + infoSeparator + normalizeFlags(flags)
+
+	tmpPath := filepath.Join(md.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(md.dir, "cur", name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to deliver message: %w", err)
+	}
+
+	return name, nil
+}
+
+// Replace overwrites the content of an existing message, keeping its name
+func (md *Maildir) Replace(name string, data []byte) error {
+	tmpPath := filepath.Join(md.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(md.dir, "cur", name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace message: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes a message from cur. Removing a missing message is not an
+// error.
+func (md *Maildir) Remove(name string) error {
+	if err := os.Remove(filepath.Join(md.dir, "cur", name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove message: %w", err)
+	}
+	return nil
+}
+
+// SetFlags renames a message so its info suffix carries the given flags and
+// returns the new file name
+func (md *Maildir) SetFlags(name, flags string) (string, error) {
+	base := name
+	if i := strings.Index(name, infoSeparator); i >= 0 {
+		base = name[:i]
+	}
+
+	newName := base + infoSeparator + normalizeFlags(flags)
+	if newName == name {
+		return name, nil
+	}
+
+	if err := os.Rename(filepath.Join(md.dir, "cur", name), filepath.Join(md.dir, "cur", newName)); err != nil {
+		return "", fmt.Errorf("failed to update message flags: %w", err)
+	}
+
+	return newName, nil
+}
+
+// uniqueName builds the unique part of a Maildir file name from the time
+// and a hash of the message key
+func uniqueName(key string) string {
+	sum := sha1.Sum([]byte(key))
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	host = strings.NewReplacer("/", "_", ":", "_").Replace(host)
+
+	return fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), hex.EncodeToString(sum[:8]), host)
+}
+
+// normalizeFlags sorts and de-duplicates Maildir flag letters as the
+// Maildir spec requires
+func normalizeFlags(flags string) string {
+	seen := make(map[rune]bool)
+	var letters []string
+	for _, r := range flags {
+		if !seen[r] {
+			seen[r] = true
+			letters = append(letters, string(r))
+		}
+	}
+	sort.Strings(letters)
+	return strings.Join(letters, "")
+}
@@ -0,0 +1,102 @@
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaildir(t *testing.T) {
+	md, err := OpenMaildir(filepath.Join(t.TempDir(), "Inbox"))
+	if err != nil {
+		t.Fatalf("OpenMaildir() error = %v", err)
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if info, err := os.Stat(filepath.Join(md.Dir(), sub)); err != nil || !info.IsDir() {
+			t.Errorf("maildir should have %s directory", sub)
+		}
+	}
+
+	var name string
+
+	t.Run("Deliver writes into cur", func(t *testing.T) {
+		name, err = md.Deliver("msg-1", []byte("Subject: hi\n\nbody\n"), "S")
+		if err != nil {
+			t.Fatalf("Deliver() error = %v", err)
+		}
+		if !strings.HasSuffix(name, ":2,S") {
+			t.Errorf("Deliver() name = %q, want suffix %q", name, ":2,S")
+		}
+		data, err := os.ReadFile(filepath.Join(md.Dir(), "cur", name))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(data) != "Subject: hi\n\nbody\n" {
+			t.Errorf("delivered content = %q", string(data))
+		}
+		entries, _ := os.ReadDir(filepath.Join(md.Dir(), "tmp"))
+		if len(entries) != 0 {
+			t.Errorf("tmp should be empty after delivery, has %d entries", len(entries))
+		}
+	})
+
+	t.Run("SetFlags renames the file", func(t *testing.T) {
+		newName, err := md.SetFlags(name, "")
+		if err != nil {
+			t.Fatalf("SetFlags() error = %v", err)
+		}
+		if !strings.HasSuffix(newName, ":2,") {
+			t.Errorf("SetFlags() name = %q, want suffix %q", newName, ":2,")
+		}
+		if _, err := os.Stat(filepath.Join(md.Dir(), "cur", newName)); err != nil {
+			t.Errorf("renamed file missing: %v", err)
+		}
+		name = newName
+	})
+
+	t.Run("Remove deletes the file", func(t *testing.T) {
+		if err := md.Remove(name); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(md.Dir(), "cur", name)); !os.IsNotExist(err) {
+			t.Error("file should be gone after Remove()")
+		}
+		if err := md.Remove(name); err != nil {
+			t.Errorf("Remove() of missing file error = %v", err)
+		}
+	})
+}
+
+func TestNormalizeFlags(t *testing.T) {
+	tests := map[string]string{
+		"":    "",
+		"S":   "S",
+		"SF":  "FS",
+		"FSF": "FS",
+	}
+	for input, want := range tests {
+		if got := normalizeFlags(input); got != want {
+			t.Errorf("normalizeFlags(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestFolderDir(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"Inbox", "Inbox"},
+		{"Inbox/Projects/Acme", filepath.Join("Inbox", "Projects", "Acme")},
+		{"Inbox/../etc", filepath.Join("Inbox", "__", "etc")},
+		{"Odd: name?", "Odd_ name_"},
+		{"", "_"},
+	}
+	for _, tt := range tests {
+		if got := folderDir(tt.path); got != tt.want {
+			t.Errorf("folderDir(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// renderMessage builds an RFC 5322 message from Graph message metadata. It
+// is used when the raw MIME content is not downloaded; the body is the full
+// body when present and the body preview otherwise.
+func renderMessage(m mail.Message) []byte {
+	var buf bytes.Buffer
+
+	writeHeader := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", name, value)
+		}
+	}
+
+	writeHeader("Message-ID", m.InternetMessageID)
+	if !m.ReceivedAt.IsZero() {
+		writeHeader("Date", m.ReceivedAt.Format(time.RFC1123Z))
+	}
+	writeHeader("From", encodeHeader(m.From))
+	writeHeader("To", strings.Join(m.To, ", "))
+	writeHeader("Cc", strings.Join(m.Cc, ", "))
+	writeHeader("Subject", encodeHeader(m.Subject))
+	writeHeader("X-Octl-Id", m.ID)
+	writeHeader("MIME-Version", "1.0")
+
+	body := m.Body
+	contentType := "text/plain"
+	if body == "" {
+		body = m.BodyPreview
+	} else if m.BodyContentType == "html" {
+		contentType = "text/html"
+	}
+
+	writeHeader("Content-Type", contentType+"; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "8bit")
+	buf.WriteString("\n")
+	buf.WriteString(strings.ReplaceAll(body, "\r\n", "\n"))
+	if !strings.HasSuffix(body, "\n") {
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes()
+}
+
+// encodeHeader Q-encodes header values that contain non-ASCII text
+func encodeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
+
+// messageFlags returns the Maildir flags for a message
+func messageFlags(m mail.Message) string {
+	// Maildir flags are kept in ASCII order
+	flags := ""
+	if m.Flag != nil && m.Flag.Status == mail.FlagFlagged {
+		flags += "F"
+	}
+	if m.IsRead {
+		flags += "S"
+	}
+	return flags
+}
+
+// folderDir turns a folder path into a relative directory, one directory
+// per path segment
+func folderDir(path string) string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		s = sanitizeSegment(s)
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return "_"
+	}
+	return filepath.Join(segments...)
+}
+
+// sanitizeSegment makes a folder name safe to use as a directory name
+func sanitizeSegment(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 32 {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "." || s == ".." {
+		return strings.Repeat("_", len(s))
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const stateFileName = "state.json"
+
+// State records what has been mirrored so later runs only fetch changes
+type State struct {
+	Folders map[string]*FolderState `json:"folders"`
+}
+
+// FolderState tracks one mirrored folder
+type FolderState struct {
+	// Path is the folder path the mirror was created for
+	Path string `json:"path"`
+	// Dir is the Maildir directory relative to the mirror root
+	Dir string `json:"dir"`
+	// DeltaLink resumes the folder's delta query
+	DeltaLink string `json:"delta_link,omitempty"`
+	// Files maps Graph message IDs to Maildir file names
+	Files map[string]string `json:"files"`
+}
+
+// LoadState reads the mirror state from root. A missing state file yields
+// an empty state.
+func LoadState(root string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(root, stateFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{Folders: make(map[string]*FolderState)}, nil
+		}
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state: %w", err)
+	}
+
+	if state.Folders == nil {
+		state.Folders = make(map[string]*FolderState)
+	}
+
+	return &state, nil
+}
+
+// Save writes the mirror state to root, replacing the old file atomically
+func (s *State) Save(root string) error {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync state: %w", err)
+	}
+
+	path := filepath.Join(root, stateFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+
+	return nil
+}
+
+// Folder returns the state for a folder ID, creating it if needed
+func (s *State) Folder(id, path string) *FolderState {
+	fs, ok := s.Folders[id]
+	if !ok {
+		fs = &FolderState{Files: make(map[string]string)}
+		s.Folders[id] = fs
+	}
+	if fs.Files == nil {
+		fs.Files = make(map[string]string)
+	}
+	fs.Path = path
+	if fs.Dir == "" {
+		fs.Dir = folderDir(path)
+	}
+	return fs
+}
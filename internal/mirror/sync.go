@@ -0,0 +1,202 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// Source fetches message changes and content from a mailbox
+type Source interface {
+	Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error
+	MIME(ctx context.Context, messageID string) ([]byte, error)
+}
+
+// GraphSource is a Source backed by Microsoft Graph
+type GraphSource struct {
+	Client *msgraph.GraphServiceClient
+}
+
+// Delta runs a message delta query for a folder
+func (g *GraphSource) Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error {
+	return mail.MessageDelta(ctx, g.Client, folderID, opts, fn)
+}
+
+// MIME downloads the raw MIME content of a message
+func (g *GraphSource) MIME(ctx context.Context, messageID string) ([]byte, error) {
+	return mail.GetMessageMIME(ctx, g.Client, messageID)
+}
+
+// Options configures a mirror sync
+type Options struct {
+	// Root is the directory holding the mirror and its state
+	Root string
+	// Bodies stores full message bodies instead of previews
+	Bodies bool
+	// Attachments stores the raw MIME message, attachments included
+	Attachments bool
+	// Full ignores stored delta links and re-reads every folder
+	Full bool
+}
+
+// FolderRef identifies a folder to mirror
+type FolderRef struct {
+	ID   string
+	Path string
+}
+
+// Result summarizes the changes applied to one folder
+type Result struct {
+	Folder  string `json:"folder"`
+	Dir     string `json:"dir"`
+	Added   int    `json:"added"`
+	Updated int    `json:"updated"`
+	Removed int    `json:"removed"`
+}
+
+// Sync brings the local mirror of each folder up to date. State is saved
+// after every page so an interrupted sync resumes without duplicates.
+func Sync(ctx context.Context, src Source, opts Options, folders []FolderRef) ([]Result, error) {
+	state, err := LoadState(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(folders))
+	for _, ref := range folders {
+		result, err := syncFolder(ctx, src, opts, state, ref)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", ref.Path, err)
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// syncFolder applies the delta for a single folder
+func syncFolder(ctx context.Context, src Source, opts Options, state *State, ref FolderRef) (*Result, error) {
+	fs := state.Folder(ref.ID, ref.Path)
+	result := &Result{Folder: ref.Path, Dir: fs.Dir}
+
+	md, err := OpenMaildir(filepath.Join(opts.Root, fs.Dir))
+	if err != nil {
+		return nil, err
+	}
+
+	deltaLink := fs.DeltaLink
+	if opts.Full {
+		deltaLink = ""
+	}
+
+	err = applyDelta(ctx, src, opts, state, ref, md, result, deltaLink)
+	if errors.Is(err, mail.ErrDeltaExpired) && deltaLink != "" {
+		// Graph no longer accepts the link; a full read rebuilds it and
+		// removes whatever was deleted meanwhile
+		state.Folder(ref.ID, ref.Path).DeltaLink = ""
+		err = applyDelta(ctx, src, opts, state, ref, md, result, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyDelta runs one delta query for a folder, starting from deltaLink or
+// from scratch when it is empty, and records the changes in result
+func applyDelta(ctx context.Context, src Source, opts Options, state *State, ref FolderRef, md *Maildir, result *Result, deltaLink string) error {
+	fs := state.Folder(ref.ID, ref.Path)
+
+	// A full read lists every message, so anything not seen is gone
+	var seen map[string]bool
+	if deltaLink == "" {
+		seen = make(map[string]bool)
+	}
+
+	deltaOpts := mail.DeltaOptions{
+		DeltaLink:   deltaLink,
+		IncludeBody: opts.Bodies && !opts.Attachments,
+	}
+
+	return src.Delta(ctx, ref.ID, deltaOpts, func(page *mail.DeltaPage) error {
+		for _, id := range page.Removed {
+			name, ok := fs.Files[id]
+			if !ok {
+				continue
+			}
+			if err := md.Remove(name); err != nil {
+				return err
+			}
+			delete(fs.Files, id)
+			result.Removed++
+		}
+
+		for _, m := range page.Messages {
+			if seen != nil {
+				seen[m.ID] = true
+			}
+
+			if name, ok := fs.Files[m.ID]; ok {
+				// Delta does not say what changed, so store the message again
+				data, err := messageContent(ctx, src, opts, m)
+				if err != nil {
+					return err
+				}
+				if err := md.Replace(name, data); err != nil {
+					return err
+				}
+				newName, err := md.SetFlags(name, messageFlags(m))
+				if err != nil {
+					return err
+				}
+				fs.Files[m.ID] = newName
+				result.Updated++
+				continue
+			}
+
+			data, err := messageContent(ctx, src, opts, m)
+			if err != nil {
+				return err
+			}
+
+			name, err := md.Deliver(m.ID, data, messageFlags(m))
+			if err != nil {
+				return err
+			}
+			fs.Files[m.ID] = name
+			result.Added++
+		}
+
+		if page.DeltaLink != "" {
+			if seen != nil {
+				for id, name := range fs.Files {
+					if seen[id] {
+						continue
+					}
+					if err := md.Remove(name); err != nil {
+						return err
+					}
+					delete(fs.Files, id)
+					result.Removed++
+				}
+			}
+			fs.DeltaLink = page.DeltaLink
+		}
+
+		return state.Save(opts.Root)
+	})
+}
+
+// messageContent returns the bytes to store for a message
+func messageContent(ctx context.Context, src Source, opts Options, m mail.Message) ([]byte, error) {
+	if opts.Attachments {
+		return src.MIME(ctx, m.ID)
+	}
+	return renderMessage(m), nil
+}
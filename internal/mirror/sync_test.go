@@ -0,0 +1,216 @@
+package mirror
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// fakeSource replays canned delta pages keyed by the incoming delta link
+type fakeSource struct {
+	pages map[string][]*mail.DeltaPage
+	mime  map[string][]byte
+	links []string
+	// expired lists delta links Graph no longer accepts
+	expired map[string]bool
+}
+
+func (f *fakeSource) Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error {
+	f.links = append(f.links, opts.DeltaLink)
+	if f.expired[opts.DeltaLink] {
+		return mail.ErrDeltaExpired
+	}
+	for _, page := range f.pages[opts.DeltaLink] {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeSource) MIME(ctx context.Context, messageID string) ([]byte, error) {
+	return f.mime[messageID], nil
+}
+
+func curFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, "cur"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestSync(t *testing.T) {
+	root := t.TempDir()
+	folders := []FolderRef{{ID: "inbox-id", Path: "Inbox"}}
+	received := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	src := &fakeSource{
+		pages: map[string][]*mail.DeltaPage{
+			"": {
+				{Messages: []mail.Message{
+					{ID: "m1", Subject: "First", From: "a@example.com", ReceivedAt: received, BodyPreview: "hello"},
+				}},
+				{Messages: []mail.Message{
+					{ID: "m2", Subject: "Second", IsRead: true},
+				}, DeltaLink: "link-1"},
+			},
+			"link-1": {
+				{Messages: []mail.Message{
+					{ID: "m1", Subject: "First (edited)", From: "a@example.com", ReceivedAt: received, IsRead: true},
+				}, Removed: []string{"m2"}, DeltaLink: "link-2"},
+			},
+		},
+	}
+
+	dir := filepath.Join(root, "Inbox")
+
+	t.Run("initial sync adds every message", func(t *testing.T) {
+		results, err := Sync(context.Background(), src, Options{Root: root}, folders)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if results[0].Added != 2 || results[0].Updated != 0 || results[0].Removed != 0 {
+			t.Errorf("result = %+v, want 2 added", results[0])
+		}
+		if files := curFiles(t, dir); len(files) != 2 {
+			t.Errorf("cur has %d files, want 2", len(files))
+		}
+
+		state, err := LoadState(root)
+		if err != nil {
+			t.Fatalf("LoadState() error = %v", err)
+		}
+		if state.Folders["inbox-id"].DeltaLink != "link-1" {
+			t.Errorf("DeltaLink = %q, want %q", state.Folders["inbox-id"].DeltaLink, "link-1")
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "cur", state.Folders["inbox-id"].Files["m1"]))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		for _, want := range []string{"Subject: First\n", "From: a@example.com\n", "X-Octl-Id: m1\n", "\n\nhello\n"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("message should contain %q, got:\n%s", want, data)
+			}
+		}
+	})
+
+	t.Run("incremental sync applies updates and removals", func(t *testing.T) {
+		results, err := Sync(context.Background(), src, Options{Root: root}, folders)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if src.links[len(src.links)-1] != "link-1" {
+			t.Errorf("second sync used delta link %q, want %q", src.links[len(src.links)-1], "link-1")
+		}
+		if results[0].Added != 0 || results[0].Updated != 1 || results[0].Removed != 1 {
+			t.Errorf("result = %+v, want 1 updated and 1 removed", results[0])
+		}
+
+		files := curFiles(t, dir)
+		if len(files) != 1 || !strings.HasSuffix(files[0], ":2,S") {
+			t.Fatalf("cur = %v, want one file flagged as seen", files)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "cur", files[0]))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if !strings.Contains(string(data), "Subject: First (edited)\n") {
+			t.Errorf("updated message not rewritten, got:\n%s", data)
+		}
+		if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+			t.Errorf("tmp = %v, want empty", tmp)
+		}
+	})
+
+	t.Run("full sync removes messages no longer listed", func(t *testing.T) {
+		src.pages[""] = []*mail.DeltaPage{{DeltaLink: "link-3"}}
+
+		results, err := Sync(context.Background(), src, Options{Root: root, Full: true}, folders)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if results[0].Removed != 1 {
+			t.Errorf("result = %+v, want 1 removed", results[0])
+		}
+		if files := curFiles(t, dir); len(files) != 0 {
+			t.Errorf("cur = %v, want empty", files)
+		}
+	})
+}
+
+func TestSyncRestartsExpiredDelta(t *testing.T) {
+	root := t.TempDir()
+	folders := []FolderRef{{ID: "inbox-id", Path: "Inbox"}}
+	src := &fakeSource{
+		pages: map[string][]*mail.DeltaPage{
+			"": {{Messages: []mail.Message{{ID: "m1"}, {ID: "m2"}}, DeltaLink: "link-1"}},
+		},
+	}
+	if _, err := Sync(context.Background(), src, Options{Root: root}, folders); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// m2 was deleted while the link expired
+	src.expired = map[string]bool{"link-1": true}
+	src.pages[""] = []*mail.DeltaPage{{Messages: []mail.Message{{ID: "m1"}}, DeltaLink: "link-2"}}
+
+	results, err := Sync(context.Background(), src, Options{Root: root}, folders)
+	if err != nil {
+		t.Fatalf("Sync() after expiry error = %v", err)
+	}
+	if got := src.links[len(src.links)-2:]; got[0] != "link-1" || got[1] != "" {
+		t.Errorf("delta links = %q, want the expired link then a full read", got)
+	}
+	if results[0].Removed != 1 {
+		t.Errorf("result = %+v, want 1 removed", results[0])
+	}
+
+	state, err := LoadState(root)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if fs := state.Folders["inbox-id"]; fs.DeltaLink != "link-2" || len(fs.Files) != 1 {
+		t.Errorf("state = %+v, want link-2 and one file", fs)
+	}
+	if files := curFiles(t, filepath.Join(root, "Inbox")); len(files) != 1 {
+		t.Errorf("cur = %v, want one file", files)
+	}
+}
+
+func TestSyncWithAttachmentsStoresMIME(t *testing.T) {
+	root := t.TempDir()
+	raw := []byte("From: a@example.com\r\nSubject: Raw\r\n\r\nraw body\r\n")
+	src := &fakeSource{
+		pages: map[string][]*mail.DeltaPage{
+			"": {{Messages: []mail.Message{{ID: "m1"}}, DeltaLink: "link-1"}},
+		},
+		mime: map[string][]byte{"m1": raw},
+	}
+
+	_, err := Sync(context.Background(), src, Options{Root: root, Attachments: true}, []FolderRef{{ID: "f", Path: "Inbox/Projects"}})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	dir := filepath.Join(root, "Inbox", "Projects")
+	files := curFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("cur has %d files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "cur", files[0]))
+	if string(data) != string(raw) {
+		t.Errorf("stored content = %q, want raw MIME", string(data))
+	}
+}
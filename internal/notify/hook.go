@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ShellHook runs a command with sh -c for a change. The event is passed as
+// JSON on stdin and in OCTL_CHANGE_TYPE, OCTL_KIND and OCTL_RESOURCE_ID,
+// never interpolated into the command itself.
+func ShellHook(ctx context.Context, command string, ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"OCTL_CHANGE_TYPE="+ev.ChangeType,
+		"OCTL_KIND="+ev.Kind,
+		"OCTL_RESOURCE_ID="+ev.ID,
+	)
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
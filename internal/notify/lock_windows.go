@@ -0,0 +1,28 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile takes an exclusive lock on path, creating it if needed, and
+// returns a function that releases it
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	h := windows.Handle(f.Fd())
+	if err := windows.LockFileEx(h, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, new(windows.Overlapped)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock subscriptions: %w", err)
+	}
+	return func() {
+		windows.UnlockFileEx(h, 0, 1, 0, new(windows.Overlapped))
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,364 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pp/octl/internal/calendar"
+	"github.com/pp/octl/internal/mail"
+)
+
+func TestResourcePath(t *testing.T) {
+	tests := []struct {
+		resource string
+		folder   string
+		want     string
+		wantErr  bool
+	}{
+		{"messages", "", "me/messages", false},
+		{"messages", "AAMk=", "me/mailFolders('AAMk=')/messages", false},
+		{"Events", "", "me/events", false},
+		{"/me/mailFolders('inbox')/messages", "", "me/mailFolders('inbox')/messages", false},
+		{"events", "inbox", "", true},
+		{"contacts", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			got, err := ResourcePath(tt.resource, tt.folder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResourcePath(%q, %q) error = %v, wantErr %v", tt.resource, tt.folder, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResourcePath(%q, %q) = %q, want %q", tt.resource, tt.folder, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryFor(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 500, time.UTC)
+
+	if got := ExpiryFor(now, 72*time.Hour); !got.Equal(now.Add(72 * time.Hour).Truncate(time.Second)) {
+		t.Errorf("ExpiryFor(72h) = %v", got)
+	}
+	if got := ExpiryFor(now, 30*24*time.Hour); !got.Equal(now.Add(MaxLifetime).Truncate(time.Second)) {
+		t.Errorf("ExpiryFor(30d) = %v, want capped at MaxLifetime", got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+
+	if err := store.Put(Subscription{ID: "s1", Resource: "me/messages", ClientState: "secret"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	// Renewals come back from Graph without the client state
+	if err := store.Put(Subscription{ID: "s1", Resource: "me/messages"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(Subscription{ID: "s2", ClientState: "other"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Remove("s2"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	subs := reopened.List()
+	if len(subs) != 1 || subs[0].ID != "s1" || subs[0].ClientState != "secret" {
+		t.Errorf("reopened store = %+v, want s1 with its client state", subs)
+	}
+}
+
+func TestStoreSharedAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	listener, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if err := listener.Put(Subscription{ID: "s1", ClientState: "one"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// Another process adds a subscription after the listener loaded the file
+	other, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if err := other.Put(Subscription{ID: "s2", ClientState: "two"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if sub, ok := listener.Get("s2"); !ok || sub.ClientState != "two" {
+		t.Errorf("Get(s2) = %+v, %v, want the subscription added elsewhere", sub, ok)
+	}
+
+	// A renewal from the listener must not drop what the other process wrote
+	if err := other.Put(Subscription{ID: "s3", ClientState: "three"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := listener.Put(Subscription{ID: "s1"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	secrets := map[string]string{}
+	for _, sub := range reopened.List() {
+		secrets[sub.ID] = sub.ClientState
+	}
+	if want := map[string]string{"s1": "one", "s2": "two", "s3": "three"}; !reflect.DeepEqual(secrets, want) {
+		t.Errorf("stored client states = %v, want %v", secrets, want)
+	}
+}
+
+func TestReceiverAcceptsSubscriptionCreatedLater(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	store, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	events := make(chan Event, 1)
+	r := NewReceiver(store, &fakeFetcher{}, func(ctx context.Context, ev Event) error {
+		events <- ev
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	// subscribe create runs while listen is up
+	created, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if err := created.Put(Subscription{ID: "sub-new", ClientState: "s3cret"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	post(t, r, "/", `{"value":[{"subscriptionId":"sub-new","clientState":"s3cret","changeType":"created",
+		"resource":"Users/u1/Messages/m1","resourceData":{"id":"m1"}}]}`)
+	if ev := receive(t, events); ev.SubscriptionID != "sub-new" {
+		t.Errorf("event = %+v, want one for sub-new", ev)
+	}
+}
+
+// fakeFetcher returns canned resources
+type fakeFetcher struct {
+	err error
+}
+
+func (f *fakeFetcher) Message(ctx context.Context, id string) (*mail.Message, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &mail.Message{ID: id, Subject: "Hello"}, nil
+}
+
+func (f *fakeFetcher) Event(ctx context.Context, id string) (*calendar.Event, error) {
+	return &calendar.Event{ID: id, Subject: "Standup"}, nil
+}
+
+func newTestReceiver(t *testing.T, fetcher Fetcher) (*Receiver, chan Event) {
+	t.Helper()
+	store, err := OpenStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if err := store.Put(Subscription{ID: "sub-1", ClientState: "s3cret"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	events := make(chan Event, 10)
+	r := NewReceiver(store, fetcher, func(ctx context.Context, ev Event) error {
+		events <- ev
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go r.Run(ctx)
+
+	return r, events
+}
+
+func post(t *testing.T, r http.Handler, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func receive(t *testing.T, events chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event emitted")
+		return Event{}
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	messagePayload := `{"value":[{
+		"subscriptionId":"sub-1","clientState":"s3cret","changeType":"created",
+		"resource":"Users/u1/Messages/m1",
+		"resourceData":{"@odata.type":"#Microsoft.Graph.Message","id":"m1"}}]}`
+
+	t.Run("validation handshake echoes the token", func(t *testing.T) {
+		r, _ := newTestReceiver(t, nil)
+		rec := post(t, r, "/?validationToken=Validation%3A+Testing+client+application", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		if got := rec.Body.String(); got != "Validation: Testing client application" {
+			t.Errorf("body = %q", got)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("Content-Type = %q, want text/plain", ct)
+		}
+	})
+
+	t.Run("verified notification is fetched and emitted", func(t *testing.T) {
+		r, events := newTestReceiver(t, &fakeFetcher{})
+		rec := post(t, r, "/", messagePayload)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want 202", rec.Code)
+		}
+
+		ev := receive(t, events)
+		if ev.Kind != KindMessage || ev.ID != "m1" || ev.ChangeType != "created" {
+			t.Errorf("event = %+v", ev)
+		}
+		if ev.Message == nil || ev.Message.Subject != "Hello" {
+			t.Errorf("message = %+v, want fetched message", ev.Message)
+		}
+	})
+
+	t.Run("calendar events are fetched as events", func(t *testing.T) {
+		r, events := newTestReceiver(t, &fakeFetcher{})
+		post(t, r, "/", `{"value":[{"subscriptionId":"sub-1","clientState":"s3cret","changeType":"updated",
+			"resource":"Users/u1/Events/e1","resourceData":{"id":"e1"}}]}`)
+
+		ev := receive(t, events)
+		if ev.Kind != KindEvent || ev.Event == nil || ev.Event.Subject != "Standup" {
+			t.Errorf("event = %+v", ev)
+		}
+	})
+
+	t.Run("deleted resources are not fetched", func(t *testing.T) {
+		r, events := newTestReceiver(t, &fakeFetcher{err: errors.New("not found")})
+		post(t, r, "/", strings.Replace(messagePayload, `"created"`, `"deleted"`, 1))
+
+		ev := receive(t, events)
+		if ev.Message != nil || ev.Error != "" {
+			t.Errorf("event = %+v, want no fetch", ev)
+		}
+	})
+
+	t.Run("fetch errors are reported on the event", func(t *testing.T) {
+		r, events := newTestReceiver(t, &fakeFetcher{err: errors.New("not found")})
+		post(t, r, "/", messagePayload)
+
+		if ev := receive(t, events); ev.Error != "not found" {
+			t.Errorf("Error = %q, want not found", ev.Error)
+		}
+	})
+
+	t.Run("wrong client state is dropped", func(t *testing.T) {
+		r, events := newTestReceiver(t, &fakeFetcher{})
+		var logged []string
+		r.Logf = func(format string, args ...any) { logged = append(logged, format) }
+
+		rec := post(t, r, "/", strings.Replace(messagePayload, "s3cret", "guess", 1))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want 202", rec.Code)
+		}
+		post(t, r, "/", strings.Replace(messagePayload, "sub-1", "sub-unknown", 1))
+
+		select {
+		case ev := <-events:
+			t.Errorf("unexpected event %+v", ev)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if len(logged) != 2 {
+			t.Errorf("logged %d rejections, want 2", len(logged))
+		}
+	})
+
+	t.Run("bad payloads and methods", func(t *testing.T) {
+		r, _ := newTestReceiver(t, nil)
+		if rec := post(t, r, "/", "{not json"); rec.Code != http.StatusBadRequest {
+			t.Errorf("bad JSON status = %d, want 400", rec.Code)
+		}
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET status = %d, want 405", rec.Code)
+		}
+	})
+}
+
+// fakeRenewer records renewals
+type fakeRenewer struct {
+	renewed []string
+	fail    string
+}
+
+func (f *fakeRenewer) Renew(ctx context.Context, id string, expires time.Time) (*Subscription, error) {
+	if id == f.fail {
+		return nil, errors.New("gone")
+	}
+	f.renewed = append(f.renewed, id)
+	return &Subscription{ID: id, ExpiresAt: expires}, nil
+}
+
+func TestRenewDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	store, err := OpenStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	for _, sub := range []Subscription{
+		{ID: "soon", ExpiresAt: now.Add(2 * time.Hour), ClientState: "a"},
+		{ID: "later", ExpiresAt: now.Add(5 * 24 * time.Hour), ClientState: "b"},
+		{ID: "broken", ExpiresAt: now.Add(time.Hour), ClientState: "c"},
+	} {
+		if err := store.Put(sub); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	renewer := &fakeRenewer{fail: "broken"}
+	renewed, err := RenewDue(context.Background(), renewer, store, now, 24*time.Hour, 72*time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("RenewDue() error = %v, want failure for broken", err)
+	}
+	if len(renewed) != 1 || renewed[0].ID != "soon" {
+		t.Fatalf("renewed = %+v, want only soon", renewed)
+	}
+
+	sub, _ := store.Get("soon")
+	if !sub.ExpiresAt.Equal(now.Add(72*time.Hour)) || sub.ClientState != "a" {
+		t.Errorf("stored subscription = %+v, want new expiry and kept client state", sub)
+	}
+}
@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+
+	"github.com/pp/octl/internal/calendar"
+	"github.com/pp/octl/internal/mail"
+)
+
+// maxPayload bounds the size of a notification request body
+const maxPayload = 1 << 20
+
+// queueSize is the number of changes buffered between the HTTP handler and
+// the worker that fetches them
+const queueSize = 256
+
+// Kinds of changed resources
+const (
+	KindMessage = "message"
+	KindEvent   = "event"
+)
+
+// Notification is one change notification as posted by Graph
+type Notification struct {
+	SubscriptionID string `json:"subscriptionId"`
+	ClientState    string `json:"clientState"`
+	ChangeType     string `json:"changeType"`
+	Resource       string `json:"resource"`
+	LifecycleEvent string `json:"lifecycleEvent"`
+	ResourceData   struct {
+		ODataType string `json:"@odata.type"`
+		ID        string `json:"id"`
+	} `json:"resourceData"`
+}
+
+// Event is a verified change, with the changed resource when it could be
+// fetched
+type Event struct {
+	SubscriptionID string          `json:"subscription_id"`
+	ChangeType     string          `json:"change_type"`
+	Kind           string          `json:"kind,omitempty"`
+	ID             string          `json:"id"`
+	Resource       string          `json:"resource"`
+	ReceivedAt     time.Time       `json:"received_at"`
+	Message        *mail.Message   `json:"message,omitempty"`
+	Event          *calendar.Event `json:"event,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// Fetcher retrieves changed resources
+type Fetcher interface {
+	Message(ctx context.Context, id string) (*mail.Message, error)
+	Event(ctx context.Context, id string) (*calendar.Event, error)
+}
+
+// GraphFetcher is a Fetcher backed by Microsoft Graph
+type GraphFetcher struct {
+	Client *msgraph.GraphServiceClient
+}
+
+// Message retrieves a message
+func (g *GraphFetcher) Message(ctx context.Context, id string) (*mail.Message, error) {
+	return mail.GetMessage(ctx, g.Client, id)
+}
+
+// Event retrieves a calendar event
+func (g *GraphFetcher) Event(ctx context.Context, id string) (*calendar.Event, error) {
+	return calendar.GetEvent(ctx, g.Client, id)
+}
+
+// Receiver is an http.Handler for Graph change notifications. It answers
+// validation requests, checks each notification's client state against the
+// store, and queues accepted changes for Run, so Graph gets its response
+// quickly.
+type Receiver struct {
+	store   *Store
+	fetcher Fetcher
+	emit    func(ctx context.Context, ev Event) error
+	queue   chan Event
+
+	// Logf reports rejected notifications and failures; nil discards them
+	Logf func(format string, args ...any)
+	// Now returns the current time; nil uses time.Now
+	Now func() time.Time
+}
+
+// NewReceiver creates a Receiver. A nil fetcher emits changes without
+// fetching the resources.
+func NewReceiver(store *Store, fetcher Fetcher, emit func(ctx context.Context, ev Event) error) *Receiver {
+	return &Receiver{
+		store:   store,
+		fetcher: fetcher,
+		emit:    emit,
+		queue:   make(chan Event, queueSize),
+	}
+}
+
+// ServeHTTP handles validation requests and notification posts
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// Graph validates the URL by posting a token it expects echoed back
+	if token := req.URL.Query().Get("validationToken"); token != "" {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, token)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload struct {
+		Value []Notification `json:"value"`
+	}
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxPayload)).Decode(&payload); err != nil {
+		http.Error(w, "invalid notification payload", http.StatusBadRequest)
+		return
+	}
+
+	for _, n := range payload.Value {
+		r.accept(n)
+	}
+
+	// Anything not accepted is dropped; an error would only make Graph retry
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// accept verifies a notification and queues it
+func (r *Receiver) accept(n Notification) {
+	sub, ok := r.store.Get(n.SubscriptionID)
+	if !ok || sub.ClientState == "" {
+		r.logf("rejected notification for unknown subscription %s", n.SubscriptionID)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(sub.ClientState), []byte(n.ClientState)) != 1 {
+		r.logf("rejected notification for %s: client state mismatch", n.SubscriptionID)
+		return
+	}
+
+	if n.LifecycleEvent != "" {
+		r.logf("subscription %s: %s", n.SubscriptionID, n.LifecycleEvent)
+		return
+	}
+
+	ev := Event{
+		SubscriptionID: n.SubscriptionID,
+		ChangeType:     n.ChangeType,
+		Kind:           resourceKind(n),
+		ID:             n.ResourceData.ID,
+		Resource:       n.Resource,
+		ReceivedAt:     r.now(),
+	}
+
+	select {
+	case r.queue <- ev:
+	default:
+		r.logf("queue full, dropped %s notification for %s", ev.ChangeType, ev.ID)
+	}
+}
+
+// Run fetches and emits queued changes until ctx is cancelled
+func (r *Receiver) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-r.queue:
+			r.process(ctx, ev)
+		}
+	}
+}
+
+// process fetches the changed resource and emits the change
+func (r *Receiver) process(ctx context.Context, ev Event) {
+	if r.fetcher != nil && ev.ChangeType != "deleted" && ev.ID != "" {
+		var err error
+		switch ev.Kind {
+		case KindMessage:
+			ev.Message, err = r.fetcher.Message(ctx, ev.ID)
+		case KindEvent:
+			ev.Event, err = r.fetcher.Event(ctx, ev.ID)
+		}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+	}
+
+	if err := r.emit(ctx, ev); err != nil {
+		r.logf("emit %s: %v", ev.ID, err)
+	}
+}
+
+// resourceKind works out whether a notification is about a message or an
+// event, from the resource type or else the resource path
+func resourceKind(n Notification) string {
+	for _, s := range []string{n.ResourceData.ODataType, n.Resource} {
+		s = strings.ToLower(s)
+		switch {
+		case strings.Contains(s, "message"):
+			return KindMessage
+		case strings.Contains(s, "event"):
+			return KindEvent
+		}
+	}
+	return ""
+}
+
+func (r *Receiver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *Receiver) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+)
+
+// Renewer moves the expiry of a subscription
+type Renewer interface {
+	Renew(ctx context.Context, id string, expires time.Time) (*Subscription, error)
+}
+
+// GraphRenewer is a Renewer backed by Microsoft Graph
+type GraphRenewer struct {
+	Client *msgraph.GraphServiceClient
+}
+
+// Renew renews a subscription
+func (g *GraphRenewer) Renew(ctx context.Context, id string, expires time.Time) (*Subscription, error) {
+	return RenewSubscription(ctx, g.Client, id, expires)
+}
+
+// RenewDue renews the stored subscriptions that expire within window,
+// extending each by lifetime from now. It returns the renewed
+// subscriptions; a failure on one does not stop the others.
+func RenewDue(ctx context.Context, r Renewer, store *Store, now time.Time, window, lifetime time.Duration) ([]Subscription, error) {
+	var renewed []Subscription
+	var errs []error
+
+	for _, sub := range store.List() {
+		if sub.ExpiresAt.After(now.Add(window)) {
+			continue
+		}
+
+		updated, err := r.Renew(ctx, sub.ID, ExpiryFor(now, lifetime))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.ID, err))
+			continue
+		}
+
+		sub.ExpiresAt = updated.ExpiresAt
+		if err := store.Put(sub); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		renewed = append(renewed, sub)
+	}
+
+	return renewed, errors.Join(errs...)
+}
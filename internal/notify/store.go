@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Store keeps the subscriptions created by octl, with their client state
+// secrets, in a local file. It is safe for concurrent use, also across
+// processes: a running listener sees subscriptions another process adds,
+// and writes re-read the file under a lock so none are lost.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	subs []Subscription
+}
+
+// OpenStore loads the store at path. A missing file yields an empty store.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replaces the subscriptions with the file's; callers hold the lock
+func (s *Store) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.subs = nil
+			return nil
+		}
+		return fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	var subs []Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return fmt.Errorf("failed to parse subscriptions: %w", err)
+	}
+	s.subs = subs
+	return nil
+}
+
+// List returns a copy of the stored subscriptions, re-read from the file
+// so changes by other processes show up; if the file cannot be read, the
+// last known list is returned
+func (s *Store) List() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.load()
+	return append([]Subscription(nil), s.subs...)
+}
+
+// Get returns a stored subscription by ID. An ID that is not known yet is
+// looked up in the file again, since another process may have added it.
+func (s *Store) Get(id string) (Subscription, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, ok := s.find(id); ok {
+		return sub, true
+	}
+	if err := s.load(); err != nil {
+		return Subscription{}, false
+	}
+	return s.find(id)
+}
+
+// find looks up a subscription in memory; callers hold the lock
+func (s *Store) find(id string) (Subscription, bool) {
+	for _, sub := range s.subs {
+		if sub.ID == id {
+			return sub, true
+		}
+	}
+	return Subscription{}, false
+}
+
+// Put adds or replaces a subscription, keeping a known client state when
+// the new record has none, and saves the store
+func (s *Store) Put(sub Subscription) error {
+	return s.update(func() {
+		for i, existing := range s.subs {
+			if existing.ID == sub.ID {
+				if sub.ClientState == "" {
+					sub.ClientState = existing.ClientState
+				}
+				s.subs[i] = sub
+				return
+			}
+		}
+		s.subs = append(s.subs, sub)
+	})
+}
+
+// Remove deletes a subscription from the store and saves it
+func (s *Store) Remove(id string) error {
+	return s.update(func() {
+		s.subs = slices.DeleteFunc(s.subs, func(sub Subscription) bool { return sub.ID == id })
+	})
+}
+
+// update applies change to the latest subscriptions on disk and saves
+// them, holding the file lock throughout
+func (s *Store) update(change func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		change()
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	change()
+	return s.save()
+}
+
+// save writes the store; callers hold the lock. The file holds secrets, so
+// it is only readable by the owner.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.subs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	return nil
+}
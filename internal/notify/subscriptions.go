@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// MaxLifetime is the longest Graph lets a mail or calendar subscription
+// live before it has to be renewed
+const MaxLifetime = 10080 * time.Minute
+
+// DefaultChangeTypes are the changes subscribed to when none are given
+const DefaultChangeTypes = "created,updated,deleted"
+
+// Subscription represents a Graph change-notification subscription
+type Subscription struct {
+	ID              string    `json:"id"`
+	Resource        string    `json:"resource"`
+	ChangeType      string    `json:"change_type"`
+	NotificationURL string    `json:"notification_url"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	// ClientState is the shared secret Graph echoes in every notification.
+	// Graph never returns it, so it is only known for subscriptions
+	// created by octl.
+	ClientState string `json:"client_state,omitempty"`
+}
+
+// ResourcePath maps a resource name to a Graph resource path. It accepts
+// messages, events, or a raw path such as me/mailFolders('inbox')/messages.
+// When folderID is set, messages are limited to that folder.
+func ResourcePath(resource, folderID string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(resource)) {
+	case "messages", "mail":
+		if folderID != "" {
+			return fmt.Sprintf("me/mailFolders('%s')/messages", folderID), nil
+		}
+		return "me/messages", nil
+	case "events", "calendar":
+		if folderID != "" {
+			return "", fmt.Errorf("--folder only applies to messages")
+		}
+		return "me/events", nil
+	}
+
+	if strings.Contains(resource, "/") {
+		if folderID != "" {
+			return "", fmt.Errorf("--folder cannot be combined with a resource path")
+		}
+		return strings.TrimPrefix(resource, "/"), nil
+	}
+	return "", fmt.Errorf("unknown resource: %s (use messages, events, or a resource path)", resource)
+}
+
+// NewClientState generates a random shared secret for a subscription
+func NewClientState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate client state: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ExpiryFor returns the expiry for a subscription lifetime, capped at
+// MaxLifetime
+func ExpiryFor(now time.Time, lifetime time.Duration) time.Time {
+	if lifetime <= 0 || lifetime > MaxLifetime {
+		lifetime = MaxLifetime
+	}
+	return now.Add(lifetime).UTC().Truncate(time.Second)
+}
+
+// CreateSubscription creates a subscription. Graph validates the
+// notification URL before it returns, so the receiver must be reachable.
+func CreateSubscription(ctx context.Context, client *msgraph.GraphServiceClient, sub Subscription) (*Subscription, error) {
+	body := models.NewSubscription()
+	body.SetResource(&sub.Resource)
+	body.SetChangeType(&sub.ChangeType)
+	body.SetNotificationUrl(&sub.NotificationURL)
+	body.SetClientState(&sub.ClientState)
+	expires := sub.ExpiresAt
+	body.SetExpirationDateTime(&expires)
+
+	created, err := client.Subscriptions().Post(ctx, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	result := convertSubscription(created)
+	result.ClientState = sub.ClientState
+	return &result, nil
+}
+
+// ListSubscriptions lists the subscriptions created by this app
+func ListSubscriptions(ctx context.Context, client *msgraph.GraphServiceClient) ([]Subscription, error) {
+	result, err := client.Subscriptions().Get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	subs := make([]Subscription, 0, len(result.GetValue()))
+	for _, s := range result.GetValue() {
+		subs = append(subs, convertSubscription(s))
+	}
+	return subs, nil
+}
+
+// RenewSubscription moves a subscription's expiry
+func RenewSubscription(ctx context.Context, client *msgraph.GraphServiceClient, id string, expires time.Time) (*Subscription, error) {
+	body := models.NewSubscription()
+	body.SetExpirationDateTime(&expires)
+
+	updated, err := client.Subscriptions().BySubscriptionId(id).Patch(ctx, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew subscription: %w", err)
+	}
+
+	sub := convertSubscription(updated)
+	return &sub, nil
+}
+
+// DeleteSubscription deletes a subscription
+func DeleteSubscription(ctx context.Context, client *msgraph.GraphServiceClient, id string) error {
+	if err := client.Subscriptions().BySubscriptionId(id).Delete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	return nil
+}
+
+// convertSubscription converts a Graph subscription to our Subscription type
+func convertSubscription(s models.Subscriptionable) Subscription {
+	sub := Subscription{
+		ID:              safeString(s.GetId()),
+		Resource:        safeString(s.GetResource()),
+		ChangeType:      safeString(s.GetChangeType()),
+		NotificationURL: safeString(s.GetNotificationUrl()),
+	}
+	if t := s.GetExpirationDateTime(); t != nil {
+		sub.ExpiresAt = *t
+	}
+	return sub
+}
+
+// safeString returns the value of a string pointer or empty string
+func safeString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -175,3 +175,22 @@ func PrintTable(format string, table *Table) error {
 		return table.Render(f.writer)
 	}
 }
+
+// ColorEnabled reports whether ANSI colours should be written to w: w must
+// be a terminal and NO_COLOR must be unset
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -3,6 +3,7 @@ package output
 import (
 	"bytes"
 	"encoding/json"
+	"os"
 	"strings"
 	"testing"
 )
@@ -160,3 +161,18 @@ func TestPrintTable(t *testing.T) {
 		t.Errorf("PrintTable(table) error = %v", err)
 	}
 }
+
+func TestColorEnabled(t *testing.T) {
+	t.Run("false for buffers", func(t *testing.T) {
+		if ColorEnabled(&bytes.Buffer{}) {
+			t.Error("ColorEnabled(buffer) = true, want false")
+		}
+	})
+
+	t.Run("false with NO_COLOR", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		if ColorEnabled(os.Stdout) {
+			t.Error("ColorEnabled() = true with NO_COLOR set")
+		}
+	})
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// TerminalWidth returns the column count of the terminal w writes to, or
+// 0 when w is not a terminal. Without a portable size query it relies on
+// $COLUMNS, falling back to 80.
+func TerminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
@@ -0,0 +1,299 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a declared set of inbox rules, as read from rules.yaml:
+//
+//	rules:
+//	  - name: Newsletters
+//	    conditions:
+//	      from: [news@example.com]
+//	      subject_contains: [newsletter]
+//	    actions:
+//	      move_to: Inbox/Newsletters
+//	      mark_read: true
+type File struct {
+	Rules []FileRule `yaml:"rules"`
+}
+
+// FileRule is one declared rule. Sequence defaults to the rule's position
+// in the file and Enabled defaults to true.
+type FileRule struct {
+	Name       string         `yaml:"name"`
+	Sequence   int32          `yaml:"sequence,omitempty"`
+	Enabled    *bool          `yaml:"enabled,omitempty"`
+	Stop       bool           `yaml:"stop,omitempty"`
+	Conditions FileConditions `yaml:"conditions"`
+	Actions    FileActions    `yaml:"actions"`
+}
+
+// FileConditions are the declared conditions of a rule
+type FileConditions struct {
+	From            []string `yaml:"from,omitempty"`
+	SubjectContains []string `yaml:"subject_contains,omitempty"`
+	HasAttachments  *bool    `yaml:"has_attachments,omitempty"`
+}
+
+// FileActions are the declared actions of a rule
+type FileActions struct {
+	// MoveTo is a folder path, well-known name, or folder ID
+	MoveTo     string   `yaml:"move_to,omitempty"`
+	Categories []string `yaml:"categories,omitempty"`
+	MarkRead   bool     `yaml:"mark_read,omitempty"`
+	ForwardTo  []string `yaml:"forward_to,omitempty"`
+}
+
+// Change actions
+const (
+	ActionCreate  = "create"
+	ActionReplace = "replace"
+	ActionDelete  = "delete"
+)
+
+// Change is one step needed to make the server rules match a file
+type Change struct {
+	Action string `json:"action"`
+	// Rule is the declared rule for create and replace
+	Rule Rule `json:"rule"`
+	// Current is the server rule for replace and delete
+	Current *Rule `json:"current,omitempty"`
+	// Fields lists what differs for replace
+	Fields []string `json:"fields,omitempty"`
+}
+
+// LoadFile reads and validates a rules file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	return parseFile(data)
+}
+
+// parseFile parses and validates a rules file
+func parseFile(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range f.Rules {
+		name := strings.TrimSpace(r.Name)
+		if name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("rule %q is declared twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		c := r.Conditions
+		if len(c.From) == 0 && len(c.SubjectContains) == 0 && c.HasAttachments == nil {
+			return nil, fmt.Errorf("rule %q has no conditions", name)
+		}
+		a := r.Actions
+		if a.MoveTo == "" && len(a.Categories) == 0 && !a.MarkRead && len(a.ForwardTo) == 0 {
+			return nil, fmt.Errorf("rule %q has no actions", name)
+		}
+
+		f.Rules[i].Name = name
+	}
+
+	return &f, nil
+}
+
+// Resolve converts the declared rules to Rule values, turning folder paths
+// into IDs with resolveFolder
+func (f *File) Resolve(resolveFolder func(ref string) (string, error)) ([]Rule, error) {
+	rules := make([]Rule, 0, len(f.Rules))
+	for i, r := range f.Rules {
+		rule := Rule{
+			Name:           r.Name,
+			Sequence:       r.Sequence,
+			Enabled:        r.Enabled == nil || *r.Enabled,
+			StopProcessing: r.Stop,
+			Conditions: Conditions{
+				From:            r.Conditions.From,
+				SubjectContains: r.Conditions.SubjectContains,
+				HasAttachments:  r.Conditions.HasAttachments,
+			},
+			Actions: Actions{
+				Categories: r.Actions.Categories,
+				MarkRead:   r.Actions.MarkRead,
+				ForwardTo:  r.Actions.ForwardTo,
+			},
+		}
+		if rule.Sequence == 0 {
+			rule.Sequence = int32(i + 1)
+		}
+
+		if r.Actions.MoveTo != "" {
+			id, err := resolveFolder(r.Actions.MoveTo)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+			}
+			rule.Actions.MoveToFolder = id
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ToFileRule converts a rule back to its declared form, turning folder IDs
+// into paths with folderPath
+func ToFileRule(rule Rule, folderPath func(id string) string) FileRule {
+	r := FileRule{
+		Name:     rule.Name,
+		Sequence: rule.Sequence,
+		Stop:     rule.StopProcessing,
+		Conditions: FileConditions{
+			From:            rule.Conditions.From,
+			SubjectContains: rule.Conditions.SubjectContains,
+			HasAttachments:  rule.Conditions.HasAttachments,
+		},
+		Actions: FileActions{
+			Categories: rule.Actions.Categories,
+			MarkRead:   rule.Actions.MarkRead,
+			ForwardTo:  rule.Actions.ForwardTo,
+		},
+	}
+	if !rule.Enabled {
+		enabled := false
+		r.Enabled = &enabled
+	}
+	if rule.Actions.MoveToFolder != "" {
+		r.Actions.MoveTo = folderPath(rule.Actions.MoveToFolder)
+	}
+	return r
+}
+
+// MarshalFile renders rules in the rules file format
+func MarshalFile(rules []FileRule) ([]byte, error) {
+	return yaml.Marshal(File{Rules: rules})
+}
+
+// Plan lists the changes that make current match the declared rules.
+// Rules are matched by name, case-insensitively. Server rules missing from
+// the file are deleted unless keepExtra is set.
+func Plan(current, declared []Rule, keepExtra bool) []Change {
+	var changes []Change
+
+	names := make(map[string]bool)
+	for _, rule := range declared {
+		names[strings.ToLower(rule.Name)] = true
+
+		existing, ok := findByName(current, rule.Name)
+		if !ok {
+			changes = append(changes, Change{Action: ActionCreate, Rule: rule})
+			continue
+		}
+
+		if fields := diffRules(*existing, rule); len(fields) > 0 {
+			current := *existing
+			changes = append(changes, Change{Action: ActionReplace, Rule: rule, Current: &current, Fields: fields})
+		}
+	}
+
+	if !keepExtra {
+		for i := range current {
+			if !names[strings.ToLower(current[i].Name)] {
+				changes = append(changes, Change{Action: ActionDelete, Rule: current[i], Current: &current[i]})
+			}
+		}
+	}
+
+	return changes
+}
+
+// findByName returns the rule with the given name, ignoring case
+func findByName(rules []Rule, name string) (*Rule, bool) {
+	for i := range rules {
+		if strings.EqualFold(rules[i].Name, name) {
+			return &rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// diffRules names the fields that differ between a server rule and a
+// declared one
+func diffRules(current, declared Rule) []string {
+	var fields []string
+	check := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			fields = append(fields, name)
+		}
+	}
+
+	check("name", current.Name, declared.Name)
+	check("sequence", current.Sequence, declared.Sequence)
+	check("enabled", current.Enabled, declared.Enabled)
+	check("stop", current.StopProcessing, declared.StopProcessing)
+	check("from", normalizeList(current.Conditions.From), normalizeList(declared.Conditions.From))
+	check("subject_contains", normalizeList(current.Conditions.SubjectContains), normalizeList(declared.Conditions.SubjectContains))
+	check("has_attachments", boolValue(current.Conditions.HasAttachments), boolValue(declared.Conditions.HasAttachments))
+	check("move_to", current.Actions.MoveToFolder, declared.Actions.MoveToFolder)
+	check("categories", normalizeList(current.Actions.Categories), normalizeList(declared.Actions.Categories))
+	check("mark_read", current.Actions.MarkRead, declared.Actions.MarkRead)
+	check("forward_to", normalizeList(current.Actions.ForwardTo), normalizeList(declared.Actions.ForwardTo))
+	if len(current.Unsupported) > 0 {
+		fields = append(fields, "unsupported")
+	}
+
+	return fields
+}
+
+// normalizeList lower-cases and sorts a list for order-insensitive comparison
+func normalizeList(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, strings.ToLower(strings.TrimSpace(v)))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// boolValue renders an optional bool for comparison
+func boolValue(b *bool) string {
+	if b == nil {
+		return "unset"
+	}
+	return fmt.Sprintf("%t", *b)
+}
+
+// Apply carries out planned changes in order, stopping at the first error.
+// It returns the number of changes applied.
+func Apply(ctx context.Context, client *msgraph.GraphServiceClient, changes []Change) (int, error) {
+	for i, c := range changes {
+		var err error
+		switch c.Action {
+		case ActionCreate:
+			_, err = Create(ctx, client, c.Rule)
+		case ActionReplace:
+			_, err = Replace(ctx, client, c.Current.ID, c.Rule)
+		case ActionDelete:
+			err = Delete(ctx, client, c.Current.ID)
+		default:
+			err = fmt.Errorf("unknown action: %s", c.Action)
+		}
+		if err != nil {
+			return i, fmt.Errorf("%s %q: %w", c.Action, c.Rule.Name, err)
+		}
+	}
+
+	return len(changes), nil
+}
@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+)
+
+// Rule is a server-side inbox rule
+type Rule struct {
+	ID             string     `json:"id,omitempty"`
+	Name           string     `json:"name"`
+	Sequence       int32      `json:"sequence"`
+	Enabled        bool       `json:"enabled"`
+	StopProcessing bool       `json:"stop_processing"`
+	Conditions     Conditions `json:"conditions"`
+	Actions        Actions    `json:"actions"`
+	// Unsupported lists conditions and actions set on the server that
+	// octl does not manage; they are lost when the rule is replaced
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// Conditions selects the messages a rule applies to. All set conditions
+// must match.
+type Conditions struct {
+	From            []string `json:"from,omitempty"`
+	SubjectContains []string `json:"subject_contains,omitempty"`
+	HasAttachments  *bool    `json:"has_attachments,omitempty"`
+}
+
+// Actions are applied to matching messages
+type Actions struct {
+	// MoveToFolder is the destination folder ID
+	MoveToFolder string   `json:"move_to_folder,omitempty"`
+	Categories   []string `json:"categories,omitempty"`
+	MarkRead     bool     `json:"mark_read,omitempty"`
+	ForwardTo    []string `json:"forward_to,omitempty"`
+}
+
+// ruleFolder is the folder whose rules are managed; Graph only supports
+// rules on the inbox
+const ruleFolder = "inbox"
+
+// List retrieves the inbox rules ordered by sequence
+func List(ctx context.Context, client *msgraph.GraphServiceClient) ([]Rule, error) {
+	result, err := client.Me().MailFolders().ByMailFolderId(ruleFolder).MessageRules().Get(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	rules := make([]Rule, 0)
+	for _, r := range result.GetValue() {
+		rules = append(rules, convertRule(r))
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Sequence < rules[j].Sequence
+	})
+
+	return rules, nil
+}
+
+// Find returns the rule with the given ID or name (case-insensitive)
+func Find(rules []Rule, ref string) (*Rule, bool) {
+	for i := range rules {
+		if rules[i].ID == ref {
+			return &rules[i], true
+		}
+	}
+	for i := range rules {
+		if strings.EqualFold(rules[i].Name, ref) {
+			return &rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// Create adds a rule to the inbox
+func Create(ctx context.Context, client *msgraph.GraphServiceClient, rule Rule) (*Rule, error) {
+	created, err := client.Me().MailFolders().ByMailFolderId(ruleFolder).MessageRules().Post(ctx, toGraphRule(rule), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule: %w", err)
+	}
+
+	result := convertRule(created)
+	return &result, nil
+}
+
+// Delete removes a rule from the inbox
+func Delete(ctx context.Context, client *msgraph.GraphServiceClient, id string) error {
+	if err := client.Me().MailFolders().ByMailFolderId(ruleFolder).MessageRules().ByMessageRuleId(id).Delete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	return nil
+}
+
+// Replace swaps an existing rule for a new definition. Graph merges nested
+// conditions and actions on update, which cannot clear a condition, so the
+// new rule is created before the old one is deleted.
+func Replace(ctx context.Context, client *msgraph.GraphServiceClient, oldID string, rule Rule) (*Rule, error) {
+	created, err := Create(ctx, client, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Delete(ctx, client, oldID); err != nil {
+		return created, err
+	}
+
+	return created, nil
+}
+
+// toGraphRule converts a rule to its Graph representation
+func toGraphRule(rule Rule) models.MessageRuleable {
+	r := models.NewMessageRule()
+	r.SetDisplayName(&rule.Name)
+	r.SetSequence(&rule.Sequence)
+	r.SetIsEnabled(&rule.Enabled)
+
+	conditions := models.NewMessageRulePredicates()
+	if len(rule.Conditions.From) > 0 {
+		conditions.SetFromAddresses(recipients(rule.Conditions.From))
+	}
+	if len(rule.Conditions.SubjectContains) > 0 {
+		conditions.SetSubjectContains(rule.Conditions.SubjectContains)
+	}
+	if rule.Conditions.HasAttachments != nil {
+		conditions.SetHasAttachments(rule.Conditions.HasAttachments)
+	}
+	r.SetConditions(conditions)
+
+	actions := models.NewMessageRuleActions()
+	if rule.Actions.MoveToFolder != "" {
+		actions.SetMoveToFolder(&rule.Actions.MoveToFolder)
+	}
+	if len(rule.Actions.Categories) > 0 {
+		actions.SetAssignCategories(rule.Actions.Categories)
+	}
+	if rule.Actions.MarkRead {
+		markRead := true
+		actions.SetMarkAsRead(&markRead)
+	}
+	if len(rule.Actions.ForwardTo) > 0 {
+		actions.SetForwardTo(recipients(rule.Actions.ForwardTo))
+	}
+	if rule.StopProcessing {
+		stop := true
+		actions.SetStopProcessingRules(&stop)
+	}
+	r.SetActions(actions)
+
+	return r
+}
+
+// recipients converts addresses to Graph recipients
+func recipients(addrs []string) []models.Recipientable {
+	result := make([]models.Recipientable, len(addrs))
+	for i, addr := range addrs {
+		address := addr
+		email := models.NewEmailAddress()
+		email.SetAddress(&address)
+		r := models.NewRecipient()
+		r.SetEmailAddress(email)
+		result[i] = r
+	}
+	return result
+}
+
+// convertRule converts a Graph API rule to our Rule type
+func convertRule(r models.MessageRuleable) Rule {
+	rule := Rule{
+		ID:      safeString(r.GetId()),
+		Name:    safeString(r.GetDisplayName()),
+		Enabled: safeBool(r.GetIsEnabled()),
+	}
+	if seq := r.GetSequence(); seq != nil {
+		rule.Sequence = *seq
+	}
+
+	if c := r.GetConditions(); c != nil {
+		rule.Conditions.From = addresses(c.GetFromAddresses())
+		rule.Conditions.SubjectContains = c.GetSubjectContains()
+		rule.Conditions.HasAttachments = c.GetHasAttachments()
+
+		for _, name := range otherPredicates(c) {
+			rule.Unsupported = append(rule.Unsupported, "condition "+name)
+		}
+	}
+
+	if e := r.GetExceptions(); e != nil {
+		managed := len(e.GetFromAddresses()) > 0 || len(e.GetSubjectContains()) > 0 || e.GetHasAttachments() != nil
+		if managed || len(otherPredicates(e)) > 0 {
+			rule.Unsupported = append(rule.Unsupported, "exceptions")
+		}
+	}
+
+	if a := r.GetActions(); a != nil {
+		rule.Actions.MoveToFolder = safeString(a.GetMoveToFolder())
+		rule.Actions.Categories = a.GetAssignCategories()
+		rule.Actions.MarkRead = safeBool(a.GetMarkAsRead())
+		rule.Actions.ForwardTo = addresses(a.GetForwardTo())
+		rule.StopProcessing = safeBool(a.GetStopProcessingRules())
+
+		for name, set := range map[string]bool{
+			"copyToFolder":          safeString(a.GetCopyToFolder()) != "",
+			"delete":                safeBool(a.GetDelete()),
+			"permanentDelete":       safeBool(a.GetPermanentDelete()),
+			"redirectTo":            len(a.GetRedirectTo()) > 0,
+			"forwardAsAttachmentTo": len(a.GetForwardAsAttachmentTo()) > 0,
+			"markImportance":        a.GetMarkImportance() != nil,
+		} {
+			if set {
+				rule.Unsupported = append(rule.Unsupported, "action "+name)
+			}
+		}
+	}
+	sort.Strings(rule.Unsupported)
+
+	return rule
+}
+
+// otherPredicates names the predicates set on p that octl does not manage
+func otherPredicates(p models.MessageRulePredicatesable) []string {
+	var names []string
+	for name, set := range map[string]bool{
+		"bodyContains":          len(p.GetBodyContains()) > 0,
+		"bodyOrSubjectContains": len(p.GetBodyOrSubjectContains()) > 0,
+		"senderContains":        len(p.GetSenderContains()) > 0,
+		"recipientContains":     len(p.GetRecipientContains()) > 0,
+		"headerContains":        len(p.GetHeaderContains()) > 0,
+		"sentToAddresses":       len(p.GetSentToAddresses()) > 0,
+		"importance":            p.GetImportance() != nil,
+		"sensitivity":           p.GetSensitivity() != nil,
+		"sentToMe":              safeBool(p.GetSentToMe()),
+		"sentOnlyToMe":          safeBool(p.GetSentOnlyToMe()),
+		"sentCcMe":              safeBool(p.GetSentCcMe()),
+		"sentToOrCcMe":          safeBool(p.GetSentToOrCcMe()),
+		"notSentToMe":           safeBool(p.GetNotSentToMe()),
+		"isAutomaticReply":      safeBool(p.GetIsAutomaticReply()),
+		"isMeetingRequest":      safeBool(p.GetIsMeetingRequest()),
+		"withinSizeRange":       p.GetWithinSizeRange() != nil,
+	} {
+		if set {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addresses extracts the email addresses of recipients
+func addresses(rs []models.Recipientable) []string {
+	var result []string
+	for _, r := range rs {
+		if addr := r.GetEmailAddress(); addr != nil {
+			result = append(result, safeString(addr.GetAddress()))
+		}
+	}
+	return result
+}
+
+func safeString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func safeBool(b *bool) bool {
+	if b == nil {
+		return false
+	}
+	return *b
+}
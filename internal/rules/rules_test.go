@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleFile = `rules:
+  - name: Newsletters
+    conditions:
+      from: [news@example.com]
+      subject_contains: [newsletter]
+    actions:
+      move_to: Inbox/Newsletters
+      mark_read: true
+  - name: Invoices
+    enabled: false
+    stop: true
+    conditions:
+      has_attachments: true
+      subject_contains: [invoice]
+    actions:
+      categories: [Finance]
+      forward_to: [accounts@example.com]
+`
+
+func testResolver(ref string) (string, error) {
+	if ref == "missing" {
+		return "", fmt.Errorf("folder not found: %s", ref)
+	}
+	return "id:" + strings.ToLower(ref), nil
+}
+
+func TestParseFile(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		f, err := parseFile([]byte(sampleFile))
+		if err != nil {
+			t.Fatalf("parseFile() error = %v", err)
+		}
+		if len(f.Rules) != 2 {
+			t.Fatalf("len(Rules) = %d, want 2", len(f.Rules))
+		}
+	})
+
+	errorCases := map[string]string{
+		"missing name":  "rules:\n  - conditions: {from: [a@b.c]}\n    actions: {mark_read: true}\n",
+		"duplicate":     "rules:\n  - name: A\n    conditions: {from: [a@b.c]}\n    actions: {mark_read: true}\n  - name: a\n    conditions: {from: [a@b.c]}\n    actions: {mark_read: true}\n",
+		"no conditions": "rules:\n  - name: A\n    actions: {mark_read: true}\n",
+		"no actions":    "rules:\n  - name: A\n    conditions: {from: [a@b.c]}\n",
+		"invalid yaml":  "rules: [",
+	}
+	for name, input := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseFile([]byte(input)); err == nil {
+				t.Error("parseFile() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f, err := parseFile([]byte(sampleFile))
+	if err != nil {
+		t.Fatalf("parseFile() error = %v", err)
+	}
+
+	rules, err := f.Resolve(testResolver)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	first := rules[0]
+	if first.Sequence != 1 || !first.Enabled {
+		t.Errorf("first rule Sequence/Enabled = %d/%v, want 1/true", first.Sequence, first.Enabled)
+	}
+	if first.Actions.MoveToFolder != "id:inbox/newsletters" {
+		t.Errorf("MoveToFolder = %q, want resolved ID", first.Actions.MoveToFolder)
+	}
+
+	second := rules[1]
+	if second.Sequence != 2 || second.Enabled || !second.StopProcessing {
+		t.Errorf("second rule = %+v, want sequence 2, disabled, stop", second)
+	}
+
+	bad := &File{Rules: []FileRule{{Name: "X", Actions: FileActions{MoveTo: "missing"}}}}
+	if _, err := bad.Resolve(testResolver); err == nil {
+		t.Error("Resolve() error = nil, want folder error")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	f, _ := parseFile([]byte(sampleFile))
+	declared, _ := f.Resolve(testResolver)
+
+	t.Run("creates missing rules", func(t *testing.T) {
+		changes := Plan(nil, declared, false)
+		if len(changes) != 2 || changes[0].Action != ActionCreate || changes[1].Action != ActionCreate {
+			t.Errorf("Plan() = %+v, want two creates", changes)
+		}
+	})
+
+	t.Run("no changes when in sync", func(t *testing.T) {
+		current := make([]Rule, len(declared))
+		copy(current, declared)
+		for i := range current {
+			current[i].ID = fmt.Sprintf("r%d", i)
+		}
+		// Order and case of list values do not matter
+		current[0].Conditions.From = []string{"NEWS@example.com"}
+
+		if changes := Plan(current, declared, false); len(changes) != 0 {
+			t.Errorf("Plan() = %+v, want no changes", changes)
+		}
+	})
+
+	t.Run("replaces changed and deletes extra", func(t *testing.T) {
+		hasAttachments := false
+		current := []Rule{
+			{ID: "r1", Name: "newsletters", Sequence: 1, Enabled: true,
+				Conditions: Conditions{From: []string{"news@example.com"}, SubjectContains: []string{"newsletter"}, HasAttachments: &hasAttachments},
+				Actions:    Actions{MoveToFolder: "id:inbox/newsletters", MarkRead: true}},
+			{ID: "r2", Name: "Old rule", Sequence: 5, Enabled: true},
+		}
+
+		changes := Plan(current, declared, false)
+		var actions []string
+		for _, c := range changes {
+			actions = append(actions, c.Action+":"+c.Rule.Name)
+		}
+		want := []string{"replace:Newsletters", "create:Invoices", "delete:Old rule"}
+		if !reflect.DeepEqual(actions, want) {
+			t.Errorf("Plan() actions = %v, want %v", actions, want)
+		}
+		if !reflect.DeepEqual(changes[0].Fields, []string{"name", "has_attachments"}) {
+			t.Errorf("replace Fields = %v, want [name has_attachments]", changes[0].Fields)
+		}
+
+		for _, c := range Plan(current, declared, true) {
+			if c.Action == ActionDelete {
+				t.Errorf("Plan(keepExtra) deletes %q", c.Rule.Name)
+			}
+		}
+	})
+
+	t.Run("rules with unsupported settings are replaced", func(t *testing.T) {
+		current := make([]Rule, len(declared))
+		copy(current, declared)
+		current[0].Unsupported = []string{"condition bodyContains"}
+
+		changes := Plan(current, declared, false)
+		if len(changes) != 1 || changes[0].Action != ActionReplace {
+			t.Errorf("Plan() = %+v, want one replace", changes)
+		}
+	})
+}
+
+func TestToFileRule(t *testing.T) {
+	f, _ := parseFile([]byte(sampleFile))
+	declared, _ := f.Resolve(testResolver)
+
+	r := ToFileRule(declared[1], func(id string) string { return "path-of-" + id })
+	if r.Enabled == nil || *r.Enabled {
+		t.Errorf("Enabled = %v, want explicit false", r.Enabled)
+	}
+	if r.Actions.MoveTo != "" {
+		t.Errorf("MoveTo = %q, want empty", r.Actions.MoveTo)
+	}
+
+	r = ToFileRule(declared[0], func(id string) string { return "path-of-" + id })
+	if r.Enabled != nil {
+		t.Errorf("Enabled = %v, want nil for enabled rules", *r.Enabled)
+	}
+	if r.Actions.MoveTo != "path-of-id:inbox/newsletters" {
+		t.Errorf("MoveTo = %q, want folder path", r.Actions.MoveTo)
+	}
+
+	data, err := MarshalFile([]FileRule{r})
+	if err != nil {
+		t.Fatalf("MarshalFile() error = %v", err)
+	}
+	back, err := parseFile(data)
+	if err != nil {
+		t.Fatalf("parseFile(MarshalFile()) error = %v", err)
+	}
+	if back.Rules[0].Name != "Newsletters" {
+		t.Errorf("round trip Name = %q, want Newsletters", back.Rules[0].Name)
+	}
+}
@@ -0,0 +1,285 @@
+package triage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// Action names recorded in the log
+const (
+	ActionRun        = "run"
+	ActionCategorize = "categorize"
+	ActionMarkRead   = "mark_read"
+	ActionMove       = "move"
+	ActionDelete     = "delete"
+)
+
+// Mailbox is the mailbox the engine reads and changes. GraphMailbox is the
+// production implementation; tests use an in-memory one.
+type Mailbox interface {
+	ListMessages(ctx context.Context, folderID string, limit int) ([]mail.Message, error)
+	ResolveFolder(ctx context.Context, ref string) (string, error)
+	MarkRead(ctx context.Context, messageID string) error
+	AddCategories(ctx context.Context, messageID string, categories []string) error
+	Move(ctx context.Context, messageID, folderID string) error
+	Delete(ctx context.Context, messageID string) error
+}
+
+// CommandRunner runs a rule's shell command for a message
+type CommandRunner func(ctx context.Context, command string, msg mail.Message) error
+
+// Entry records one action taken (or, in a dry run, planned) on a message
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Rule      string    `json:"rule"`
+	MessageID string    `json:"message_id"`
+	Subject   string    `json:"subject"`
+	Action    string    `json:"action"`
+	Detail    string    `json:"detail,omitempty"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Engine applies triage rules to the messages in a folder. Actions whose
+// effect is already present are skipped, so running it again only touches
+// what changed; commands are run once per rule and message, as recorded
+// in the log.
+type Engine struct {
+	Rules   []Rule
+	Mailbox Mailbox
+	Run     CommandRunner
+	// Log records every action taken; nil disables logging and the
+	// run-once guarantee for commands
+	Log    *Log
+	DryRun bool
+	// Now returns the current time; nil means time.Now
+	Now func() time.Time
+}
+
+// Triage applies the rules to up to limit messages in a folder (0 means
+// all) and returns the actions taken. Per-message failures are recorded
+// in the entries; the error is only set when the run could not start.
+func (e *Engine) Triage(ctx context.Context, folder string, limit int) ([]Entry, error) {
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+
+	folderID, err := e.Mailbox.ResolveFolder(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve every destination up front so a typo fails before anything moves
+	targets := make(map[string]string)
+	for _, r := range e.Rules {
+		if r.Actions.Move == "" {
+			continue
+		}
+		if _, ok := targets[r.Actions.Move]; ok {
+			continue
+		}
+		id, err := e.Mailbox.ResolveFolder(ctx, r.Actions.Move)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		targets[r.Actions.Move] = id
+	}
+
+	messages, err := e.Mailbox.ListMessages(ctx, folderID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	for _, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+
+		for _, rule := range e.Rules {
+			if !rule.Match.Matches(msg, now()) {
+				continue
+			}
+
+			done, failed := e.apply(ctx, rule, &msg, folderID, targets, now, &entries)
+			if done || failed || rule.Stop {
+				break
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// apply runs one rule's actions on a message, updating the local copy so
+// later rules see the result. It reports whether the message left the
+// folder and whether an action failed.
+func (e *Engine) apply(ctx context.Context, rule Rule, msg *mail.Message, folderID string, targets map[string]string, now func() time.Time, entries *[]Entry) (removed, failed bool) {
+	a := rule.Actions
+
+	record := func(action, detail string, err error) bool {
+		entry := Entry{
+			Time:      now(),
+			Rule:      rule.Name,
+			MessageID: msg.ID,
+			Subject:   msg.Subject,
+			Action:    action,
+			Detail:    detail,
+			DryRun:    e.DryRun,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		*entries = append(*entries, entry)
+		if e.Log != nil && !e.DryRun {
+			// Logging problems must not stop triage; the entry is still returned
+			_ = e.Log.Append(entry)
+		}
+		return err != nil
+	}
+
+	do := func(fn func() error) error {
+		if e.DryRun {
+			return nil
+		}
+		return fn()
+	}
+
+	if a.Run != "" && (e.Log == nil || !e.Log.HasRun(rule.Name, msg.ID)) {
+		err := do(func() error {
+			if e.Run == nil {
+				return fmt.Errorf("running commands is not enabled")
+			}
+			return e.Run(ctx, a.Run, *msg)
+		})
+		if record(ActionRun, a.Run, err) {
+			return false, true
+		}
+	}
+
+	var missing []string
+	for _, c := range a.Categorize {
+		if !hasCategory(*msg, c) {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		err := do(func() error { return e.Mailbox.AddCategories(ctx, msg.ID, missing) })
+		if record(ActionCategorize, strings.Join(missing, ", "), err) {
+			return false, true
+		}
+		msg.Categories = append(msg.Categories, missing...)
+	}
+
+	if a.MarkRead && !msg.IsRead {
+		err := do(func() error { return e.Mailbox.MarkRead(ctx, msg.ID) })
+		if record(ActionMarkRead, "", err) {
+			return false, true
+		}
+		msg.IsRead = true
+	}
+
+	if a.Move != "" && targets[a.Move] != folderID {
+		err := do(func() error { return e.Mailbox.Move(ctx, msg.ID, targets[a.Move]) })
+		if record(ActionMove, a.Move, err) {
+			return false, true
+		}
+		return true, false
+	}
+
+	if a.Delete {
+		err := do(func() error { return e.Mailbox.Delete(ctx, msg.ID) })
+		if record(ActionDelete, "", err) {
+			return false, true
+		}
+		return true, false
+	}
+
+	return false, false
+}
+
+// Log is an append-only JSON lines record of triage actions
+type Log struct {
+	path string
+	ran  map[string]bool
+}
+
+// OpenLog opens the log at path, reading earlier entries to remember which
+// commands already ran
+func OpenLog(path string) (*Log, error) {
+	l := &Log{path: path, ran: make(map[string]bool)}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open triage log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip damaged lines rather than refusing to run
+			continue
+		}
+		if entry.Action == ActionRun && entry.Error == "" && !entry.DryRun {
+			l.ran[runKey(entry.Rule, entry.MessageID)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read triage log: %w", err)
+	}
+
+	return l, nil
+}
+
+// HasRun reports whether a rule's command already ran for a message
+func (l *Log) HasRun(rule, messageID string) bool {
+	return l.ran[runKey(rule, messageID)]
+}
+
+// Append writes an entry to the log
+func (l *Log) Append(entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open triage log: %w", err)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode log entry: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write triage log: %w", err)
+	}
+
+	if entry.Action == ActionRun && entry.Error == "" && !entry.DryRun {
+		l.ran[runKey(entry.Rule, entry.MessageID)] = true
+	}
+
+	return nil
+}
+
+// runKey identifies a command run in the log
+func runKey(rule, messageID string) string {
+	return rule + "\x00" + messageID
+}
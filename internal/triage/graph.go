@@ -0,0 +1,82 @@
+package triage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// GraphMailbox is a Mailbox backed by Microsoft Graph
+type GraphMailbox struct {
+	Client *msgraph.GraphServiceClient
+}
+
+// ListMessages lists messages with their bodies, newest first
+func (g *GraphMailbox) ListMessages(ctx context.Context, folderID string, limit int) ([]mail.Message, error) {
+	opts := mail.ListOptions{
+		Top:         50,
+		FolderID:    folderID,
+		IncludeBody: true,
+	}
+	return mail.ListAllMessages(ctx, g.Client, opts, limit)
+}
+
+// ResolveFolder resolves a folder ID, well-known name, or path
+func (g *GraphMailbox) ResolveFolder(ctx context.Context, ref string) (string, error) {
+	return mail.ResolveFolderID(ctx, g.Client, ref)
+}
+
+// MarkRead marks a message as read
+func (g *GraphMailbox) MarkRead(ctx context.Context, messageID string) error {
+	return mail.MarkAsRead(ctx, g.Client, messageID, true)
+}
+
+// AddCategories adds categories to a message
+func (g *GraphMailbox) AddCategories(ctx context.Context, messageID string, categories []string) error {
+	_, err := mail.UpdateCategories(ctx, g.Client, messageID, categories, nil)
+	return err
+}
+
+// Move moves a message to a folder
+func (g *GraphMailbox) Move(ctx context.Context, messageID, folderID string) error {
+	return mail.MoveMessage(ctx, g.Client, messageID, folderID)
+}
+
+// Delete deletes a message
+func (g *GraphMailbox) Delete(ctx context.Context, messageID string) error {
+	return mail.DeleteMessage(ctx, g.Client, messageID)
+}
+
+// ShellRunner runs commands with sh -c. The message is passed as JSON on
+// stdin and in OCTL_MESSAGE_ID, OCTL_SUBJECT, OCTL_FROM and
+// OCTL_RECEIVED_AT, never interpolated into the command itself.
+func ShellRunner(ctx context.Context, command string, msg mail.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"OCTL_MESSAGE_ID="+msg.ID,
+		"OCTL_SUBJECT="+msg.Subject,
+		"OCTL_FROM="+msg.From,
+		"OCTL_RECEIVED_AT="+msg.ReceivedAt.Format(time.RFC3339),
+	)
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
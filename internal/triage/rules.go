@@ -0,0 +1,278 @@
+package triage
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pp/octl/internal/htmltext"
+	"github.com/pp/octl/internal/mail"
+)
+
+// File is a set of local triage rules, as read from triage.yaml:
+//
+//	rules:
+//	  - name: Old newsletters
+//	    match:
+//	      from: news@example.com
+//	      older_than: 30d
+//	      is_read: true
+//	    actions:
+//	      move: Archive/Newsletters
+//	  - name: Build failures
+//	    match:
+//	      subject: /build (failed|broken)/i
+//	    actions:
+//	      categorize: [CI]
+//	      run: notify-send "$OCTL_SUBJECT"
+type File struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule maps a match expression to actions. Rules run in file order; stop
+// ends processing of a message after this rule matched.
+type Rule struct {
+	Name    string  `yaml:"name"`
+	Match   Match   `yaml:"match"`
+	Actions Actions `yaml:"actions"`
+	Stop    bool    `yaml:"stop,omitempty"`
+}
+
+// Match selects messages; every set field must match. Text fields are
+// case-insensitive substrings, or regular expressions when written as
+// /expr/ or /expr/i.
+type Match struct {
+	From           string   `yaml:"from,omitempty"`
+	To             string   `yaml:"to,omitempty"`
+	Subject        string   `yaml:"subject,omitempty"`
+	Body           string   `yaml:"body,omitempty"`
+	OlderThan      string   `yaml:"older_than,omitempty"`
+	NewerThan      string   `yaml:"newer_than,omitempty"`
+	IsRead         *bool    `yaml:"is_read,omitempty"`
+	HasAttachments *bool    `yaml:"has_attachments,omitempty"`
+	Flagged        *bool    `yaml:"flagged,omitempty"`
+	Importance     string   `yaml:"importance,omitempty"`
+	Categories     []string `yaml:"categories,omitempty"`
+	Classification string   `yaml:"classification,omitempty"`
+
+	from, to, subject, body *pattern
+}
+
+// Actions are applied to matching messages in a fixed order: run,
+// categorize, mark_read, then move or delete
+type Actions struct {
+	Move       string   `yaml:"move,omitempty"`
+	MarkRead   bool     `yaml:"mark_read,omitempty"`
+	Categorize []string `yaml:"categorize,omitempty"`
+	Delete     bool     `yaml:"delete,omitempty"`
+	// Run is a shell command; the message is passed in OCTL_* environment
+	// variables and as JSON on stdin
+	Run string `yaml:"run,omitempty"`
+}
+
+// pattern is a compiled text matcher
+type pattern struct {
+	re     *regexp.Regexp
+	substr string
+}
+
+// LoadFile reads and validates a triage rules file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read triage rules: %w", err)
+	}
+
+	return ParseFile(data)
+}
+
+// ParseFile parses and validates triage rules
+func ParseFile(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse triage rules: %w", err)
+	}
+
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+
+	return &f, nil
+}
+
+// compile validates a rule and prepares its patterns
+func (r *Rule) compile() error {
+	m := &r.Match
+
+	var err error
+	for _, p := range []struct {
+		field  string
+		source string
+		target **pattern
+	}{
+		{"from", m.From, &m.from},
+		{"to", m.To, &m.to},
+		{"subject", m.Subject, &m.subject},
+		{"body", m.Body, &m.body},
+	} {
+		if *p.target, err = compilePattern(p.source); err != nil {
+			return fmt.Errorf("%s: %w", p.field, err)
+		}
+	}
+
+	for _, age := range []string{m.OlderThan, m.NewerThan} {
+		if _, err := mail.ParseDateArg(age, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if m.Importance != "" {
+		if m.Importance, err = mail.ParseImportance(m.Importance); err != nil {
+			return err
+		}
+	}
+
+	m.Classification = strings.ToLower(m.Classification)
+	if m.Classification != "" && m.Classification != "focused" && m.Classification != "other" {
+		return fmt.Errorf("invalid classification: %s (use focused or other)", m.Classification)
+	}
+
+	a := r.Actions
+	if a.Move != "" && a.Delete {
+		return fmt.Errorf("move and delete cannot be combined")
+	}
+	if a.Move == "" && !a.MarkRead && len(a.Categorize) == 0 && !a.Delete && a.Run == "" {
+		return fmt.Errorf("no actions")
+	}
+
+	return nil
+}
+
+// compilePattern compiles a text matcher; empty input yields nil
+func compilePattern(s string) (*pattern, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if len(s) > 2 && strings.HasPrefix(s, "/") {
+		expr, flags := s[1:], ""
+		if strings.HasSuffix(expr, "/i") {
+			expr, flags = strings.TrimSuffix(expr, "/i"), "(?i)"
+		} else if strings.HasSuffix(expr, "/") {
+			expr = strings.TrimSuffix(expr, "/")
+		} else {
+			return &pattern{substr: strings.ToLower(s)}, nil
+		}
+
+		re, err := regexp.Compile(flags + expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &pattern{re: re}, nil
+	}
+
+	return &pattern{substr: strings.ToLower(s)}, nil
+}
+
+// matches reports whether any of the values matches the pattern
+func (p *pattern) matches(values ...string) bool {
+	for _, v := range values {
+		if p.re != nil && p.re.MatchString(v) {
+			return true
+		}
+		if p.re == nil && strings.Contains(strings.ToLower(v), p.substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a message satisfies every set condition. Ages
+// are measured from now.
+func (m *Match) Matches(msg mail.Message, now time.Time) bool {
+	if m.from != nil && !m.from.matches(msg.From) {
+		return false
+	}
+	if m.to != nil && !m.to.matches(append(append([]string{}, msg.To...), msg.Cc...)...) {
+		return false
+	}
+	if m.subject != nil && !m.subject.matches(msg.Subject) {
+		return false
+	}
+	if m.body != nil && !m.body.matches(messageText(msg)) {
+		return false
+	}
+
+	if m.OlderThan != "" {
+		cutoff, _ := mail.ParseDateArg(m.OlderThan, now)
+		if !msg.ReceivedAt.Before(cutoff) {
+			return false
+		}
+	}
+	if m.NewerThan != "" {
+		cutoff, _ := mail.ParseDateArg(m.NewerThan, now)
+		if msg.ReceivedAt.Before(cutoff) {
+			return false
+		}
+	}
+
+	if m.IsRead != nil && msg.IsRead != *m.IsRead {
+		return false
+	}
+	if m.HasAttachments != nil && msg.HasAttachments != *m.HasAttachments {
+		return false
+	}
+	if m.Flagged != nil && isFlagged(msg) != *m.Flagged {
+		return false
+	}
+	if m.Importance != "" && !strings.EqualFold(msg.Importance, m.Importance) {
+		return false
+	}
+	if m.Classification != "" && !strings.EqualFold(msg.InferenceClassification, m.Classification) {
+		return false
+	}
+	for _, c := range m.Categories {
+		if !hasCategory(msg, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// messageText returns the message body as plain text, falling back to the
+// preview when the body was not fetched
+func messageText(msg mail.Message) string {
+	switch {
+	case msg.Body == "":
+		return msg.BodyPreview
+	case msg.BodyContentType == "html":
+		return htmltext.Render(msg.Body, htmltext.Options{})
+	}
+	return msg.Body
+}
+
+// isFlagged reports whether a message has an open follow-up flag
+func isFlagged(msg mail.Message) bool {
+	return msg.Flag != nil && msg.Flag.Status == mail.FlagFlagged
+}
+
+// hasCategory reports whether a message carries a category, ignoring case
+func hasCategory(msg mail.Message, category string) bool {
+	for _, c := range msg.Categories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
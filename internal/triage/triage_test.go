@@ -0,0 +1,330 @@
+package triage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeMailbox is an in-memory Mailbox that records the calls made
+type fakeMailbox struct {
+	folders  map[string][]mail.Message
+	calls    []string
+	failMove bool
+}
+
+func (f *fakeMailbox) ListMessages(ctx context.Context, folderID string, limit int) ([]mail.Message, error) {
+	msgs := append([]mail.Message{}, f.folders[folderID]...)
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	return msgs, nil
+}
+
+func (f *fakeMailbox) ResolveFolder(ctx context.Context, ref string) (string, error) {
+	if ref == "missing" {
+		return "", errors.New("folder not found: missing")
+	}
+	return strings.ToLower(ref), nil
+}
+
+func (f *fakeMailbox) find(id string) (string, int) {
+	for folder, msgs := range f.folders {
+		for i, m := range msgs {
+			if m.ID == id {
+				return folder, i
+			}
+		}
+	}
+	return "", -1
+}
+
+func (f *fakeMailbox) MarkRead(ctx context.Context, id string) error {
+	f.calls = append(f.calls, "mark_read "+id)
+	folder, i := f.find(id)
+	f.folders[folder][i].IsRead = true
+	return nil
+}
+
+func (f *fakeMailbox) AddCategories(ctx context.Context, id string, categories []string) error {
+	f.calls = append(f.calls, "categorize "+id+" "+strings.Join(categories, ","))
+	folder, i := f.find(id)
+	f.folders[folder][i].Categories = append(f.folders[folder][i].Categories, categories...)
+	return nil
+}
+
+func (f *fakeMailbox) Move(ctx context.Context, id, folderID string) error {
+	if f.failMove {
+		return errors.New("move refused")
+	}
+	f.calls = append(f.calls, "move "+id+" "+folderID)
+	folder, i := f.find(id)
+	msg := f.folders[folder][i]
+	f.folders[folder] = append(f.folders[folder][:i], f.folders[folder][i+1:]...)
+	f.folders[folderID] = append(f.folders[folderID], msg)
+	return nil
+}
+
+func (f *fakeMailbox) Delete(ctx context.Context, id string) error {
+	f.calls = append(f.calls, "delete "+id)
+	folder, i := f.find(id)
+	f.folders[folder] = append(f.folders[folder][:i], f.folders[folder][i+1:]...)
+	return nil
+}
+
+const testRules = `rules:
+  - name: old newsletters
+    match:
+      from: news@example.com
+      older_than: 30d
+      is_read: true
+    actions:
+      move: Archive
+  - name: build failures
+    match:
+      subject: /build (failed|broken)/i
+    actions:
+      categorize: [CI]
+      mark_read: true
+      run: echo build
+  - name: spam
+    match:
+      body: /viagra/
+    actions:
+      delete: true
+`
+
+func newTestMailbox() *fakeMailbox {
+	return &fakeMailbox{folders: map[string][]mail.Message{
+		"inbox": {
+			{ID: "m1", From: "News <news@example.com>", Subject: "Weekly", IsRead: true, ReceivedAt: testNow.AddDate(0, 0, -40)},
+			{ID: "m2", From: "News <news@example.com>", Subject: "Weekly", IsRead: true, ReceivedAt: testNow.AddDate(0, 0, -5)},
+			{ID: "m3", From: "ci@example.com", Subject: "Build FAILED on main", ReceivedAt: testNow},
+			{ID: "m4", From: "x@example.com", Subject: "Offer", Body: "cheap viagra", ReceivedAt: testNow},
+		},
+	}}
+}
+
+func newTestEngine(t *testing.T, mb Mailbox, runs *[]string) *Engine {
+	t.Helper()
+
+	f, err := ParseFile([]byte(testRules))
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	log, err := OpenLog(filepath.Join(t.TempDir(), "triage.log"))
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+
+	return &Engine{
+		Rules:   f.Rules,
+		Mailbox: mb,
+		Log:     log,
+		Now:     func() time.Time { return testNow },
+		Run: func(ctx context.Context, command string, msg mail.Message) error {
+			*runs = append(*runs, msg.ID)
+			return nil
+		},
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	errorCases := map[string]string{
+		"bad regex":          "rules:\n  - match: {subject: '/(/'}\n    actions: {mark_read: true}\n",
+		"bad age":            "rules:\n  - match: {older_than: soon}\n    actions: {mark_read: true}\n",
+		"no actions":         "rules:\n  - match: {subject: x}\n",
+		"move and delete":    "rules:\n  - match: {subject: x}\n    actions: {move: a, delete: true}\n",
+		"bad importance":     "rules:\n  - match: {importance: urgent}\n    actions: {mark_read: true}\n",
+		"bad classification": "rules:\n  - match: {classification: spam}\n    actions: {mark_read: true}\n",
+	}
+	for name, input := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseFile([]byte(input)); err == nil {
+				t.Error("ParseFile() error = nil, want error")
+			}
+		})
+	}
+
+	t.Run("names unnamed rules", func(t *testing.T) {
+		f, err := ParseFile([]byte("rules:\n  - match: {subject: x}\n    actions: {mark_read: true}\n"))
+		if err != nil {
+			t.Fatalf("ParseFile() error = %v", err)
+		}
+		if f.Rules[0].Name != "rule 1" {
+			t.Errorf("Name = %q, want %q", f.Rules[0].Name, "rule 1")
+		}
+	})
+}
+
+func TestMatch(t *testing.T) {
+	yes, no := true, false
+	msg := mail.Message{
+		From:            "Jane <jane@example.com>",
+		To:              []string{"team@example.com"},
+		Subject:         "Quarterly Report",
+		Body:            "<p>See <b>attached</b></p>",
+		BodyContentType: "html",
+		ReceivedAt:      testNow.AddDate(0, 0, -10),
+		HasAttachments:  true,
+		Importance:      "high",
+		Categories:      []string{"Finance"},
+	}
+
+	tests := []struct {
+		name  string
+		match Match
+		want  bool
+	}{
+		{"substring is case-insensitive", Match{Subject: "quarterly"}, true},
+		{"regex", Match{Subject: "/^Quarterly/"}, true},
+		{"regex is case-sensitive without i", Match{Subject: "/^quarterly/"}, false},
+		{"regex with i", Match{Subject: "/^quarterly/i"}, true},
+		{"to matches recipients", Match{To: "team@"}, true},
+		{"body uses text", Match{Body: "see attached"}, true},
+		{"older than", Match{OlderThan: "7d"}, true},
+		{"not older than", Match{OlderThan: "30d"}, false},
+		{"newer than", Match{NewerThan: "30d"}, true},
+		{"bool fields", Match{HasAttachments: &yes, IsRead: &no, Flagged: &no}, true},
+		{"importance", Match{Importance: "High"}, true},
+		{"categories", Match{Categories: []string{"finance"}}, true},
+		{"missing category", Match{Categories: []string{"Travel"}}, false},
+		{"all conditions must match", Match{Subject: "quarterly", From: "bob"}, false},
+		{"empty matches everything", Match{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Rule{Match: tt.match, Actions: Actions{MarkRead: true}}
+			if err := r.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+			if got := r.Match.Matches(msg, testNow); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriage(t *testing.T) {
+	t.Run("applies matching rules", func(t *testing.T) {
+		mb := newTestMailbox()
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+
+		entries, err := e.Triage(context.Background(), "Inbox", 0)
+		if err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+
+		wantCalls := []string{
+			"move m1 archive",
+			"categorize m3 CI",
+			"mark_read m3",
+			"delete m4",
+		}
+		if !reflect.DeepEqual(mb.calls, wantCalls) {
+			t.Errorf("calls = %v, want %v", mb.calls, wantCalls)
+		}
+		if !reflect.DeepEqual(runs, []string{"m3"}) {
+			t.Errorf("runs = %v, want [m3]", runs)
+		}
+		if len(entries) != 5 {
+			t.Errorf("len(entries) = %d, want 5", len(entries))
+		}
+	})
+
+	t.Run("second run is a no-op", func(t *testing.T) {
+		mb := newTestMailbox()
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+
+		if _, err := e.Triage(context.Background(), "inbox", 0); err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+		mb.calls = nil
+		runs = nil
+
+		entries, err := e.Triage(context.Background(), "inbox", 0)
+		if err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+		if len(entries) != 0 || len(mb.calls) != 0 || len(runs) != 0 {
+			t.Errorf("second run entries=%v calls=%v runs=%v, want none", entries, mb.calls, runs)
+		}
+	})
+
+	t.Run("commands run once across log reopen", func(t *testing.T) {
+		mb := newTestMailbox()
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+
+		if _, err := e.Triage(context.Background(), "inbox", 0); err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+
+		reopened, err := OpenLog(e.Log.path)
+		if err != nil {
+			t.Fatalf("OpenLog() error = %v", err)
+		}
+		if !reopened.HasRun("build failures", "m3") {
+			t.Error("HasRun() = false after reopening the log")
+		}
+	})
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		mb := newTestMailbox()
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+		e.DryRun = true
+
+		entries, err := e.Triage(context.Background(), "inbox", 0)
+		if err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+		if len(mb.calls) != 0 || len(runs) != 0 {
+			t.Errorf("dry run calls=%v runs=%v, want none", mb.calls, runs)
+		}
+		if len(entries) != 5 || !entries[0].DryRun {
+			t.Errorf("dry run entries = %+v, want 5 planned entries", entries)
+		}
+	})
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		mb := newTestMailbox()
+		mb.failMove = true
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+
+		entries, err := e.Triage(context.Background(), "inbox", 0)
+		if err != nil {
+			t.Fatalf("Triage() error = %v", err)
+		}
+		if entries[0].Action != ActionMove || entries[0].Error != "move refused" {
+			t.Errorf("entries[0] = %+v, want failed move", entries[0])
+		}
+	})
+
+	t.Run("unknown destination fails fast", func(t *testing.T) {
+		mb := newTestMailbox()
+		var runs []string
+		e := newTestEngine(t, mb, &runs)
+		e.Rules[0].Actions.Move = "missing"
+
+		if _, err := e.Triage(context.Background(), "inbox", 0); err == nil {
+			t.Error("Triage() error = nil, want folder error")
+		}
+		if len(mb.calls) != 0 {
+			t.Errorf("calls = %v, want none", mb.calls)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxSeen bounds the number of message IDs remembered in the state file
+const maxSeen = 500
+
+// State records how far a watch has got so a restart does not replay mail
+type State struct {
+	// FolderID is the folder the state belongs to
+	FolderID string `json:"folder_id"`
+	// DeltaLink resumes the folder's delta query
+	DeltaLink string `json:"delta_link,omitempty"`
+	// Since is when the watch started; older messages are never emitted
+	Since time.Time `json:"since"`
+	// Seen holds the IDs of the most recently emitted messages, oldest first
+	Seen []string `json:"seen,omitempty"`
+}
+
+// LoadState reads a state file. A missing file yields an empty state.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state: %w", err)
+	}
+	return &state, nil
+}
+
+// Save writes the state to path, replacing the old file atomically
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
+
+// Reset starts the state over for a folder
+func (s *State) Reset(folderID string, now time.Time) {
+	*s = State{FolderID: folderID, Since: now}
+}
+
+// HasSeen reports whether a message has already been emitted
+func (s *State) HasSeen(id string) bool {
+	for _, seen := range s.Seen {
+		if seen == id {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkSeen remembers an emitted message, forgetting the oldest when full
+func (s *State) MarkSeen(id string) {
+	s.Seen = append(s.Seen, id)
+	if len(s.Seen) > maxSeen {
+		s.Seen = s.Seen[len(s.Seen)-maxSeen:]
+	}
+}
@@ -0,0 +1,197 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	msgraph "github.com/microsoftgraph/msgraph-sdk-go"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// Source fetches message changes from a mailbox
+type Source interface {
+	Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error
+}
+
+// GraphSource is a Source backed by Microsoft Graph
+type GraphSource struct {
+	Client *msgraph.GraphServiceClient
+}
+
+// Delta runs a message delta query for a folder
+func (g *GraphSource) Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error {
+	return mail.MessageDelta(ctx, g.Client, folderID, opts, fn)
+}
+
+// Watcher polls a folder for newly arrived messages
+type Watcher struct {
+	Source   Source
+	FolderID string
+	// State is loaded from and saved to StatePath after every page
+	State     *State
+	StatePath string
+	// Interval is the time between polls; MaxBackoff caps the wait after
+	// repeated errors
+	Interval   time.Duration
+	MaxBackoff time.Duration
+	// IncludeBody fetches message bodies
+	IncludeBody bool
+	// Filter selects the messages to emit; nil emits every message
+	Filter func(mail.Message) bool
+	// Emit is called once for every new message. An error stops the poll
+	// before the message is marked seen, so it is retried.
+	Emit func(ctx context.Context, msg mail.Message) error
+	// Logf reports errors and retries; nil discards them
+	Logf func(format string, args ...any)
+	// Now returns the current time; nil uses time.Now
+	Now func() time.Time
+}
+
+// Run polls until ctx is cancelled, backing off after errors. It returns
+// nil when ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	backoff := time.Duration(0)
+	for {
+		_, err := w.Poll(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		wait := w.Interval
+		if err != nil {
+			backoff = nextBackoff(backoff, w.Interval, w.MaxBackoff)
+			wait = backoff
+			w.logf("poll failed, retrying in %s: %v", wait, err)
+		} else {
+			backoff = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Poll runs one delta query and emits the messages that arrived since the
+// last one. It returns the number of messages emitted.
+func (w *Watcher) Poll(ctx context.Context) (int, error) {
+	if w.State.FolderID != w.FolderID {
+		w.State.Reset(w.FolderID, w.now())
+	}
+	if w.State.Since.IsZero() {
+		w.State.Since = w.now()
+	}
+
+	emitted, err := w.poll(ctx)
+	if errors.Is(err, mail.ErrDeltaExpired) && w.State.DeltaLink != "" {
+		// Start a new query; Since and Seen keep old mail from replaying
+		w.logf("delta link expired, starting over")
+		w.State.DeltaLink = ""
+		var n int
+		n, err = w.poll(ctx)
+		emitted += n
+	}
+	if err != nil {
+		// Save what was emitted so a retry does not repeat it
+		if saveErr := w.State.Save(w.StatePath); saveErr != nil {
+			w.logf("%v", saveErr)
+		}
+		return emitted, err
+	}
+
+	return emitted, nil
+}
+
+// poll runs one delta query from the stored delta link
+func (w *Watcher) poll(ctx context.Context) (int, error) {
+	opts := mail.DeltaOptions{
+		DeltaLink:   w.State.DeltaLink,
+		IncludeBody: w.IncludeBody,
+		Since:       w.State.Since,
+	}
+
+	emitted := 0
+	err := w.Source.Delta(ctx, w.FolderID, opts, func(page *mail.DeltaPage) error {
+		for _, msg := range page.Messages {
+			// Delta reports updates too, and old mail moved into the folder
+			if w.State.HasSeen(msg.ID) || msg.ReceivedAt.Before(w.State.Since) {
+				continue
+			}
+			if w.Filter == nil || w.Filter(msg) {
+				if err := w.Emit(ctx, msg); err != nil {
+					return err
+				}
+				emitted++
+			}
+			w.State.MarkSeen(msg.ID)
+		}
+
+		if page.DeltaLink != "" {
+			w.State.DeltaLink = page.DeltaLink
+		}
+		return w.State.Save(w.StatePath)
+	})
+	return emitted, err
+}
+
+func (w *Watcher) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+func (w *Watcher) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+	}
+}
+
+// nextBackoff doubles the previous wait, starting at base and capped at max
+func nextBackoff(prev, base, max time.Duration) time.Duration {
+	next := prev * 2
+	if next < base {
+		next = base
+	}
+	if max > 0 && next > max {
+		next = max
+	}
+	return next
+}
+
+// ParseQuery compiles a --query value into a message filter. The query is
+// matched case-insensitively against the subject, sender, and preview, as
+// a substring or as a regular expression written /like this/.
+func ParseQuery(query string) (func(mail.Message) bool, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	var re *regexp.Regexp
+	if len(query) > 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		var err error
+		re, err = regexp.Compile("(?i)" + query[1:len(query)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query regex: %w", err)
+		}
+	} else {
+		re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	}
+
+	return func(msg mail.Message) bool {
+		for _, field := range []string{msg.Subject, msg.From, msg.BodyPreview} {
+			if re.MatchString(field) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
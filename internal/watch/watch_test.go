@@ -0,0 +1,285 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pp/octl/internal/mail"
+)
+
+// fakeSource replays scripted delta pages, keyed by the delta link asked for
+type fakeSource struct {
+	pages map[string][]mail.DeltaPage
+	errs  map[string]error
+	calls []mail.DeltaOptions
+}
+
+func (f *fakeSource) Delta(ctx context.Context, folderID string, opts mail.DeltaOptions, fn func(page *mail.DeltaPage) error) error {
+	f.calls = append(f.calls, opts)
+	if err := f.errs[opts.DeltaLink]; err != nil {
+		return err
+	}
+	for _, page := range f.pages[opts.DeltaLink] {
+		page := page
+		if err := fn(&page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func msgAt(id string, at time.Time) mail.Message {
+	return mail.Message{ID: id, Subject: "Subject " + id, ReceivedAt: at}
+}
+
+func newWatcher(t *testing.T, src *fakeSource, start time.Time) (*Watcher, *[]string) {
+	t.Helper()
+	var emitted []string
+	w := &Watcher{
+		Source:    src,
+		FolderID:  "inbox-id",
+		State:     &State{},
+		StatePath: filepath.Join(t.TempDir(), "watch.json"),
+		Emit: func(ctx context.Context, msg mail.Message) error {
+			emitted = append(emitted, msg.ID)
+			return nil
+		},
+		Now: func() time.Time { return start },
+	}
+	return w, &emitted
+}
+
+func TestPoll(t *testing.T) {
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("first poll starts from now and emits only new mail", func(t *testing.T) {
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{
+			"": {{Messages: []mail.Message{msgAt("old", start.Add(-time.Hour))}, DeltaLink: "d1"}},
+			"d1": {{Messages: []mail.Message{
+				msgAt("new", start.Add(time.Minute)),
+				msgAt("moved-in", start.Add(-48*time.Hour)),
+			}, DeltaLink: "d2"}},
+		}}
+		w, emitted := newWatcher(t, src, start)
+
+		n, err := w.Poll(context.Background())
+		if err != nil || n != 0 {
+			t.Fatalf("first Poll() = %d, %v, want 0, nil", n, err)
+		}
+		if !src.calls[0].Since.Equal(start) {
+			t.Errorf("first query Since = %v, want %v", src.calls[0].Since, start)
+		}
+
+		n, err = w.Poll(context.Background())
+		if err != nil || n != 1 {
+			t.Fatalf("second Poll() = %d, %v, want 1, nil", n, err)
+		}
+		if !reflect.DeepEqual(*emitted, []string{"new"}) {
+			t.Errorf("emitted = %v, want [new]", *emitted)
+		}
+		if w.State.DeltaLink != "d2" {
+			t.Errorf("DeltaLink = %q, want d2", w.State.DeltaLink)
+		}
+	})
+
+	t.Run("updates to emitted messages are not repeated", func(t *testing.T) {
+		msg := msgAt("m1", start.Add(time.Minute))
+		read := msg
+		read.IsRead = true
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{
+			"d1": {{Messages: []mail.Message{msg}, DeltaLink: "d2"}},
+			"d2": {{Messages: []mail.Message{read}, DeltaLink: "d3"}},
+		}}
+		w, emitted := newWatcher(t, src, start)
+		w.State = &State{FolderID: "inbox-id", DeltaLink: "d1", Since: start}
+
+		for i := 0; i < 2; i++ {
+			if _, err := w.Poll(context.Background()); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+		}
+		if !reflect.DeepEqual(*emitted, []string{"m1"}) {
+			t.Errorf("emitted = %v, want [m1]", *emitted)
+		}
+	})
+
+	t.Run("state survives a restart", func(t *testing.T) {
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{
+			"d1": {{Messages: []mail.Message{msgAt("m1", start.Add(time.Minute))}, DeltaLink: "d2"}},
+			"d2": {{Messages: []mail.Message{msgAt("m2", start.Add(2*time.Minute))}, DeltaLink: "d3"}},
+		}}
+		w, _ := newWatcher(t, src, start)
+		w.State = &State{FolderID: "inbox-id", DeltaLink: "d1", Since: start}
+		if _, err := w.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+
+		state, err := LoadState(w.StatePath)
+		if err != nil {
+			t.Fatalf("LoadState() error = %v", err)
+		}
+		if state.DeltaLink != "d2" || !state.HasSeen("m1") {
+			t.Errorf("saved state = %+v, want delta link d2 and m1 seen", state)
+		}
+
+		restarted, emitted := newWatcher(t, src, start.Add(time.Hour))
+		restarted.State, restarted.StatePath = state, w.StatePath
+		if _, err := restarted.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+		if !reflect.DeepEqual(*emitted, []string{"m2"}) {
+			t.Errorf("emitted after restart = %v, want [m2]", *emitted)
+		}
+	})
+
+	t.Run("state for another folder is reset", func(t *testing.T) {
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{}}
+		w, _ := newWatcher(t, src, start)
+		w.State = &State{FolderID: "other", DeltaLink: "d9", Seen: []string{"x"}}
+
+		if _, err := w.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+		if src.calls[0].DeltaLink != "" || w.State.HasSeen("x") {
+			t.Errorf("state not reset: call %+v, state %+v", src.calls[0], w.State)
+		}
+	})
+
+	t.Run("expired delta link starts over without replaying", func(t *testing.T) {
+		src := &fakeSource{
+			pages: map[string][]mail.DeltaPage{
+				"": {{Messages: []mail.Message{
+					msgAt("m1", start.Add(time.Minute)),
+					msgAt("m2", start.Add(2*time.Minute)),
+				}, DeltaLink: "fresh"}},
+			},
+			errs: map[string]error{"stale": mail.ErrDeltaExpired},
+		}
+		w, emitted := newWatcher(t, src, start)
+		w.State = &State{FolderID: "inbox-id", DeltaLink: "stale", Since: start, Seen: []string{"m1"}}
+
+		if _, err := w.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+		if !reflect.DeepEqual(*emitted, []string{"m2"}) {
+			t.Errorf("emitted = %v, want [m2]", *emitted)
+		}
+		if w.State.DeltaLink != "fresh" {
+			t.Errorf("DeltaLink = %q, want fresh", w.State.DeltaLink)
+		}
+	})
+
+	t.Run("emit failure leaves the message unseen", func(t *testing.T) {
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{
+			"d1": {{Messages: []mail.Message{msgAt("m1", start.Add(time.Minute))}, DeltaLink: "d2"}},
+		}}
+		w, _ := newWatcher(t, src, start)
+		w.State = &State{FolderID: "inbox-id", DeltaLink: "d1", Since: start}
+		w.Emit = func(ctx context.Context, msg mail.Message) error { return errors.New("broken pipe") }
+
+		if _, err := w.Poll(context.Background()); err == nil {
+			t.Fatal("Poll() error = nil, want error")
+		}
+		if w.State.HasSeen("m1") || w.State.DeltaLink != "d1" {
+			t.Errorf("state advanced after failure: %+v", w.State)
+		}
+	})
+
+	t.Run("filter skips messages", func(t *testing.T) {
+		src := &fakeSource{pages: map[string][]mail.DeltaPage{
+			"d1": {{Messages: []mail.Message{
+				msgAt("a", start.Add(time.Minute)),
+				msgAt("b", start.Add(time.Minute)),
+			}, DeltaLink: "d2"}},
+		}}
+		w, emitted := newWatcher(t, src, start)
+		w.State = &State{FolderID: "inbox-id", DeltaLink: "d1", Since: start}
+		w.Filter = func(m mail.Message) bool { return m.ID == "b" }
+
+		if _, err := w.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+		if !reflect.DeepEqual(*emitted, []string{"b"}) {
+			t.Errorf("emitted = %v, want [b]", *emitted)
+		}
+	})
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	src := &fakeSource{errs: map[string]error{"": errors.New("unavailable")}}
+	w, _ := newWatcher(t, src, time.Now())
+	w.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop after cancel")
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	base, max := 30*time.Second, 5*time.Minute
+	var got []time.Duration
+	wait := time.Duration(0)
+	for i := 0; i < 6; i++ {
+		wait = nextBackoff(wait, base, max)
+		got = append(got, wait)
+	}
+
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("backoff sequence = %v, want %v", got, want)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	msg := mail.Message{Subject: "Build #42 FAILED", From: "ci@example.com", BodyPreview: "Pipeline main"}
+
+	tests := []struct {
+		query   string
+		want    bool
+		wantErr bool
+	}{
+		{"failed", true, false},
+		{"CI@EXAMPLE", true, false},
+		{"pipeline", true, false},
+		{"/build #\\d+ failed/", true, false},
+		{"/^deploy/", false, false},
+		{"passed", false, false},
+		{"/(/", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			match, err := ParseQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := match(msg); got != tt.want {
+				t.Errorf("ParseQuery(%q) match = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty query matches everything", func(t *testing.T) {
+		match, err := ParseQuery("  ")
+		if err != nil || match != nil {
+			t.Errorf("ParseQuery(\"\") error = %v, filter nil = %t, want nil filter", err, match == nil)
+		}
+	})
+}